@@ -0,0 +1,64 @@
+package fromhl7
+
+import (
+	"fmt"
+
+	"github.com/kdar/health/ccd"
+	"github.com/kdar/health/hl7"
+	"github.com/kdar/health/hl7x"
+	hl7v2_3 "github.com/kdar/health/hl7x/2.3"
+)
+
+// ADT converts the PID, PV1, PV2, AL1 and DG1 segments of an ADT
+// message into a CCD. PV1 (and PV2, if any) becomes the encounter,
+// with each DG1 as one of its diagnoses as well as a problem. Other
+// segments are skipped.
+func ADT(segments []hl7.Segment) (*ccd.CCD, error) {
+	c := ccd.NewCCD()
+
+	var pv1 *hl7v2_3.Pv1
+	var pv2 *hl7v2_3.Pv2
+	var diagnoses []ccd.Diagnosis
+
+	for i, segment := range segments {
+		var err error
+
+		switch segmentName(segment) {
+		case "PID":
+			var pid hl7v2_3.Pid
+			if err = hl7x.Unmarshal(segment, &pid); err == nil {
+				c.Patient = Patient(&pid)
+			}
+		case "PV1":
+			pv1 = &hl7v2_3.Pv1{}
+			err = hl7x.Unmarshal(segment, pv1)
+		case "PV2":
+			pv2 = &hl7v2_3.Pv2{}
+			err = hl7x.Unmarshal(segment, pv2)
+		case "AL1":
+			var al1 hl7v2_3.Al1
+			if err = hl7x.Unmarshal(segment, &al1); err == nil {
+				c.Allergies = append(c.Allergies, Allergy(&al1))
+			}
+		case "DG1":
+			var dg1 hl7v2_3.Dg1
+			if err = hl7x.Unmarshal(segment, &dg1); err == nil {
+				problem := Problem(&dg1)
+				c.Problems = append(c.Problems, problem)
+				diagnoses = append(diagnoses, ccd.Diagnosis{Code: problem.Code, Problem: problem})
+			}
+		}
+
+		if err != nil {
+			return nil, fmt.Errorf("segment %d: %s", i+1, err)
+		}
+	}
+
+	if pv1 != nil {
+		encounter := Encounter(pv1, pv2)
+		encounter.Diagnosis = diagnoses
+		c.Encounters = append(c.Encounters, encounter)
+	}
+
+	return c, nil
+}
@@ -0,0 +1,158 @@
+package fromhl7
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kdar/health/ccd"
+	hl7v2_3 "github.com/kdar/health/hl7x/2.3"
+)
+
+func TestADT(t *testing.T) {
+	segments := unmarshal(t,
+		"MSH|^~\\&|ADT|HOSP|||20140922091808||ADT^A01|1|P|2.3",
+		"EVN|A01|20140922091808",
+		"PID|1||123^^^HOSP^MR||DOE^JOHN^Q^JR^DR^^L||19800102|M||2106-3|1 MAIN ST^APT 2^ANYTOWN^CA^90210^USA^H||^PRN^PH^^1^555^5551234~^NET^Internet^john@example.com|^WPN^CP^^^555^5559876|en^English^ISO639|M||||||2186-5",
+		"PV1|1|I|4N^401^B^HOSP||||1234^SMITH^JANE^^^DR|5678^JONES^BOB|||||||||9012^BROWN^AL"+strings.Repeat("|", 27)+"201409220800|201409251200",
+		"PV2|||CP^Chest pain^L",
+		"AL1|1|DA|70618^Penicillin^RXNORM|SV|Hives",
+		"AL1|2|FA|^^^PEANUT^Peanuts^L",
+		"DG1|1|I9|401.9^Hypertension^I9C|Essential hypertension|20140922|A",
+		"DG1|2|I9|^^^CP|Chest pain||W",
+	)
+
+	c, err := ADT(segments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	patient := ccd.Patient{
+		Name: ccd.Name{Last: "DOE", First: "JOHN", Middle: "Q", Suffix: "JR", Prefix: "DR", Type: "L"},
+		Dob:  time.Date(1980, 1, 2, 0, 0, 0, 0, time.UTC),
+		Addresses: []ccd.Address{
+			{Line1: "1 MAIN ST", Line2: "APT 2", City: "ANYTOWN", State: "CA", Zip: "90210", Country: "USA", Use: "H"},
+		},
+		Telecoms: []ccd.Telecom{
+			{Type: "phone", Use: "HP", Value: "+1(555)5551234", OriginalValue: "tel:+1(555)5551234"},
+			{Type: "email", Use: "HP", Value: "john@example.com", OriginalValue: "mailto:john@example.com"},
+			{Type: "phone", Use: "MC", Value: "(555)5559876", OriginalValue: "tel:(555)5559876"},
+		},
+		LanguageCode:  "en",
+		Gender:        ccd.Code{Code: "M", DisplayName: "Male", CodeSystem: "2.16.840.1.113883.5.1", CodeSystemName: "AdministrativeGender"},
+		MaritalStatus: ccd.Code{Code: "M", DisplayName: "Married", CodeSystem: "2.16.840.1.113883.5.2", CodeSystemName: "MaritalStatus"},
+		Race:          ccd.Code{Code: "2106-3", CodeSystem: "2.16.840.1.113883.6.238", CodeSystemName: "Race & Ethnicity - CDC"},
+		Ethnicity:     ccd.Code{Code: "2186-5", CodeSystem: "2.16.840.1.113883.6.238", CodeSystemName: "Race & Ethnicity - CDC"},
+	}
+	if !reflect.DeepEqual(c.Patient, patient) {
+		t.Fatalf("expected patient\n%#v\ngot\n%#v", patient, c.Patient)
+	}
+
+	hypertension := ccd.Problem{
+		Name:        "Hypertension",
+		Code:        ccd.Code{Code: "401.9", DisplayName: "Hypertension", CodeSystem: "2.16.840.1.113883.6.103", CodeSystemName: "ICD-9-CM"},
+		Time:        ccd.Time{Type: ccd.TIME_SINGLE, Value: time.Date(2014, 9, 22, 0, 0, 0, 0, time.UTC)},
+		ProblemType: "Admitting diagnosis",
+	}
+	chestPain := ccd.Problem{
+		Name:        "Chest pain",
+		Code:        ccd.Code{Code: "CP"},
+		Time:        ccd.Time{Type: ccd.TIME_SINGLE},
+		ProblemType: "Working diagnosis",
+	}
+	if !reflect.DeepEqual(c.Problems, []ccd.Problem{hypertension, chestPain}) {
+		t.Fatalf("expected problems\n%#v\ngot\n%#v", []ccd.Problem{hypertension, chestPain}, c.Problems)
+	}
+
+	participation := func(code, name string) ccd.Code {
+		return ccd.Code{Code: code, DisplayName: name, CodeSystem: "2.16.840.1.113883.5.90", CodeSystemName: "ParticipationType"}
+	}
+	encounters := []ccd.Encounter{
+		{
+			Code: ccd.Code{Code: "IMP", DisplayName: "Inpatient encounter", CodeSystem: "2.16.840.1.113883.5.4", CodeSystemName: "ActCode"},
+			Time: ccd.Time{
+				Type: ccd.TIME_INTERVAL,
+				Low:  time.Date(2014, 9, 22, 8, 0, 0, 0, time.UTC),
+				High: time.Date(2014, 9, 25, 12, 0, 0, 0, time.UTC),
+			},
+			Performers: []ccd.Performer{
+				{Name: ccd.Name{Last: "SMITH", First: "JANE", Prefix: "DR"}, Code: participation("ATND", "attender")},
+				{Name: ccd.Name{Last: "JONES", First: "BOB"}, Code: participation("REF", "referrer")},
+				{Name: ccd.Name{Last: "BROWN", First: "AL"}, Code: participation("ADM", "admitter")},
+			},
+			Locations: []ccd.Location{{Name: "HOSP 4N 401 B"}},
+			Indications: []ccd.Problem{
+				{Name: "Chest pain", Code: ccd.Code{Code: "CP", DisplayName: "Chest pain", CodeSystemName: "L"}},
+			},
+			Diagnosis: []ccd.Diagnosis{
+				{Code: hypertension.Code, Problem: hypertension},
+				{Code: chestPain.Code, Problem: chestPain},
+			},
+		},
+	}
+	if !reflect.DeepEqual(c.Encounters, encounters) {
+		t.Fatalf("expected encounters\n%#v\ngot\n%#v", encounters, c.Encounters)
+	}
+
+	allergies := []ccd.Allergy{
+		{
+			Name:         "Penicillin",
+			Reaction:     "Hives",
+			Substance:    ccd.Code{Code: "70618", DisplayName: "Penicillin", CodeSystem: "2.16.840.1.113883.6.88", CodeSystemName: "RxNorm"},
+			Type:         ccd.Code{Code: "416098002", DisplayName: "Drug allergy", CodeSystem: "2.16.840.1.113883.6.96", CodeSystemName: "SNOMED CT"},
+			Severity:     ccd.Code{Code: "24484000", DisplayName: "Severe", CodeSystem: "2.16.840.1.113883.6.96", CodeSystemName: "SNOMED CT"},
+			SeverityCode: "24484000",
+			SeverityText: "Severe",
+		},
+		{
+			Name:      "Peanuts",
+			Substance: ccd.Code{Code: "PEANUT", DisplayName: "Peanuts", CodeSystemName: "L"},
+			Type:      ccd.Code{Code: "414285001", DisplayName: "Food allergy", CodeSystem: "2.16.840.1.113883.6.96", CodeSystemName: "SNOMED CT"},
+		},
+	}
+	if !reflect.DeepEqual(c.Allergies, allergies) {
+		t.Fatalf("expected allergies\n%#v\ngot\n%#v", allergies, c.Allergies)
+	}
+}
+
+var telecomTests = []struct {
+	in  hl7v2_3.Xtn
+	use string
+	out ccd.Telecom
+}{
+	{
+		hl7v2_3.Xtn{A_999_999_9999X99999CAnyText: "(555)555-1234"},
+		"HP",
+		ccd.Telecom{Type: "phone", Use: "HP", Value: "(555)555-1234", OriginalValue: "tel:(555)555-1234"},
+	},
+	{
+		hl7v2_3.Xtn{TelecommunicationUseCode: "WPN", AreaCityCode: "555", PhoneNumber: "5551234", Extension: "12"},
+		"HP",
+		ccd.Telecom{Type: "phone", Use: "WP", Value: "(555)5551234x12", OriginalValue: "tel:(555)5551234x12"},
+	},
+	{
+		hl7v2_3.Xtn{TelecommunicationEquipmentTypeId: "CP", AreaCityCode: "555", PhoneNumber: "5551234"},
+		"HP",
+		ccd.Telecom{Type: "phone", Use: "MC", Value: "(555)5551234", OriginalValue: "tel:(555)5551234"},
+	},
+	{
+		hl7v2_3.Xtn{TelecommunicationUseCode: "NET", EmailAddress: "a@b.com"},
+		"WP",
+		ccd.Telecom{Type: "email", Use: "WP", Value: "a@b.com", OriginalValue: "mailto:a@b.com"},
+	},
+	{
+		hl7v2_3.Xtn{TelecommunicationUseCode: "PRN"},
+		"HP",
+		ccd.Telecom{},
+	},
+}
+
+func TestTelecom(t *testing.T) {
+	for i, tt := range telecomTests {
+		telecom := Telecom(tt.in, tt.use)
+		if !reflect.DeepEqual(telecom, tt.out) {
+			t.Fatalf("%d. expected %#v, got %#v", i, tt.out, telecom)
+		}
+	}
+}
@@ -0,0 +1,57 @@
+package fromhl7
+
+import (
+	"strings"
+
+	"github.com/kdar/health/ccd"
+	hl7v2_3 "github.com/kdar/health/hl7x/2.3"
+)
+
+var (
+	// HL7 table 0127 -> SNOMED CT
+	allergyTypes = map[string]ccd.Code{
+		"DA": {Code: "416098002", DisplayName: "Drug allergy"},
+		"FA": {Code: "414285001", DisplayName: "Food allergy"},
+		"MA": {Code: "419199007", DisplayName: "Allergy to substance"},
+		"MC": {Code: "420134006", DisplayName: "Propensity to adverse reactions"},
+	}
+
+	// HL7 table 0128 -> SNOMED CT
+	allergySeverities = map[string]ccd.Code{
+		"SV": {Code: "24484000", DisplayName: "Severe"},
+		"MO": {Code: "6736007", DisplayName: "Moderate"},
+		"MI": {Code: "255604002", DisplayName: "Mild"},
+	}
+)
+
+// Allergy converts an AL1 into a ccd.Allergy. The allergy type
+// (AL1-2) and severity (AL1-4) are converted to SNOMED CT.
+func Allergy(al1 *hl7v2_3.Al1) ccd.Allergy {
+	allergy := ccd.Allergy{
+		Substance: Code(al1.AllergyCodeMnemonicDescription),
+		Reaction:  string(al1.AllergyReaction),
+	}
+
+	allergy.Name = allergy.Substance.DisplayName
+	if allergy.Name == "" {
+		allergy.Name = allergy.Substance.Code
+	}
+
+	if code, ok := allergyTypes[strings.ToUpper(string(al1.AllergyType))]; ok {
+		allergy.Type = snomed(code)
+	}
+
+	if code, ok := allergySeverities[strings.ToUpper(string(al1.AllergySeverity))]; ok {
+		allergy.Severity = snomed(code)
+		allergy.SeverityCode = code.Code
+		allergy.SeverityText = code.DisplayName
+	}
+
+	return allergy
+}
+
+func snomed(code ccd.Code) ccd.Code {
+	code.CodeSystem = codeSystems["SCT"].oid
+	code.CodeSystemName = codeSystems["SCT"].name
+	return code
+}
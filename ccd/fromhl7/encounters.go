@@ -0,0 +1,105 @@
+package fromhl7
+
+import (
+	"strings"
+
+	"github.com/kdar/health/ccd"
+	hl7v2_3 "github.com/kdar/health/hl7x/2.3"
+)
+
+var (
+	// HL7 table 0004 -> ActEncounterCode
+	patientClasses = map[string]ccd.Code{
+		"E": {Code: "EMER", DisplayName: "Emergency"},
+		"I": {Code: "IMP", DisplayName: "Inpatient encounter"},
+		"O": {Code: "AMB", DisplayName: "Ambulatory"},
+		"P": {Code: "PRENC", DisplayName: "Pre-admission"},
+		"R": {Code: "AMB", DisplayName: "Ambulatory"},
+		"B": {Code: "IMP", DisplayName: "Inpatient encounter"},
+	}
+
+	// the ParticipationType of each doctor in PV1
+	participationTypes = map[string]ccd.Code{
+		"attending":  {Code: "ATND", DisplayName: "attender"},
+		"referring":  {Code: "REF", DisplayName: "referrer"},
+		"consulting": {Code: "CON", DisplayName: "consultant"},
+		"admitting":  {Code: "ADM", DisplayName: "admitter"},
+		"other":      {Code: "PRF", DisplayName: "performer"},
+	}
+)
+
+// Encounter converts a PV1, and optionally a PV2, into a ccd.Encounter.
+//
+// The code is the patient class (PV1-2) as an HL7 v3 ActEncounterCode,
+// and the time runs from the admit (PV1-44) to the discharge (PV1-45).
+// Each doctor in PV1 is a performer, with their role as a
+// ParticipationType in its Code. The assigned patient location (PV1-3)
+// is the location, and the admit reason (PV2-3) the indication.
+func Encounter(pv1 *hl7v2_3.Pv1, pv2 *hl7v2_3.Pv2) ccd.Encounter {
+	encounter := ccd.Encounter{
+		Time: ccd.Time{
+			Type: ccd.TIME_INTERVAL,
+			Low:  pv1.AdmitDateTime.TimeOfAnEvent.Time,
+			High: pv1.DischargeDateTime.TimeOfAnEvent.Time,
+		},
+	}
+
+	if code, ok := patientClasses[strings.ToUpper(string(pv1.PatientClass))]; ok {
+		code.CodeSystem = "2.16.840.1.113883.5.4"
+		code.CodeSystemName = "ActCode"
+		encounter.Code = code
+	}
+
+	addPerformer := func(role string, xcn hl7v2_3.Xcn) {
+		name := providerName(xcn)
+		if name.IsZero() {
+			return
+		}
+
+		code := participationTypes[role]
+		code.CodeSystem = "2.16.840.1.113883.5.90"
+		code.CodeSystemName = "ParticipationType"
+		encounter.Performers = append(encounter.Performers, ccd.Performer{Name: name, Code: code})
+	}
+
+	addPerformer("attending", pv1.AttendingDoctor)
+	addPerformer("referring", pv1.ReferringDoctor)
+	for _, xcn := range pv1.ConsultingDoctors {
+		addPerformer("consulting", xcn)
+	}
+	addPerformer("admitting", pv1.AdmittingDoctor)
+	for _, xcn := range pv1.OtherHealthcareProviders {
+		addPerformer("other", xcn)
+	}
+
+	if location := Location(pv1.AssignedPatientLocation); location.Name != "" {
+		encounter.Locations = append(encounter.Locations, location)
+	}
+
+	if pv2 != nil && (pv2.AdmitReason.Identifier != "" || pv2.AdmitReason.Text != "") {
+		code := Code(pv2.AdmitReason)
+		name := code.DisplayName
+		if name == "" {
+			name = code.Code
+		}
+		encounter.Indications = append(encounter.Indications, ccd.Problem{Name: name, Code: code})
+	}
+
+	return encounter
+}
+
+// Location converts a PL into a ccd.Location. The name is made up of the
+// facility, point of care, room and bed, e.g. "HOSP 4N 401 B".
+func Location(pl hl7v2_3.Pl) ccd.Location {
+	var parts []string
+	for _, part := range []hl7v2_3.String{pl.FacilityHd.NamespaceID, pl.PointOfCareID, pl.Room, pl.Bed} {
+		if part != "" {
+			parts = append(parts, string(part))
+		}
+	}
+
+	return ccd.Location{
+		Name: strings.Join(parts, " "),
+		Code: ccd.Code{Code: string(pl.LocationType)},
+	}
+}
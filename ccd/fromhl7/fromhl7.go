@@ -0,0 +1,76 @@
+// Package fromhl7 converts HL7 v2 messages, decoded with hl7x/2.3,
+// into ccd types so they can be handled the same way as data
+// parsed from a CCD.
+package fromhl7
+
+import (
+	"strings"
+
+	"github.com/kdar/health/ccd"
+	"github.com/kdar/health/hl7"
+	hl7v2_3 "github.com/kdar/health/hl7x/2.3"
+)
+
+type codeSystem struct {
+	oid  string
+	name string
+}
+
+// HL7 table 0396 coding system names -> CDA code systems
+var codeSystems = map[string]codeSystem{
+	"LN":     {"2.16.840.1.113883.6.1", "LOINC"},
+	"SNM":    {"2.16.840.1.113883.6.5", "SNOMED"},
+	"SCT":    {"2.16.840.1.113883.6.96", "SNOMED CT"},
+	"SNM3":   {"2.16.840.1.113883.6.51", "SNOMED International"},
+	"I9":     {"2.16.840.1.113883.6.42", "ICD-9"},
+	"I9C":    {"2.16.840.1.113883.6.103", "ICD-9-CM"},
+	"I10":    {"2.16.840.1.113883.6.3", "ICD-10"},
+	"I10C":   {"2.16.840.1.113883.6.90", "ICD-10-CM"},
+	"C4":     {"2.16.840.1.113883.6.12", "CPT-4"},
+	"CPT4":   {"2.16.840.1.113883.6.12", "CPT-4"},
+	"RXNORM": {"2.16.840.1.113883.6.88", "RxNorm"},
+	"NDC":    {"2.16.840.1.113883.6.69", "NDC"},
+	"CVX":    {"2.16.840.1.113883.12.292", "CVX"},
+	"UCUM":   {"2.16.840.1.113883.6.8", "UCUM"},
+}
+
+// Code converts a CE into a ccd.Code. The alternate identifier becomes
+// a translation, or the code itself if there's no identifier.
+func Code(ce hl7v2_3.Ce) ccd.Code {
+	code := newCode(string(ce.Identifier), string(ce.Text), string(ce.NameOfCodingSystem))
+
+	alt := newCode(string(ce.AlternateIdentifier), string(ce.AlternateText), string(ce.NameOfAlternateCodingSystem))
+	if alt.Code != "" || alt.DisplayName != "" {
+		if code.Code == "" && code.DisplayName == "" {
+			return alt
+		}
+		code.Translations = append(code.Translations, alt)
+	}
+
+	return code
+}
+
+func newCode(identifier, text, system string) ccd.Code {
+	code := ccd.Code{
+		Code:           identifier,
+		DisplayName:    text,
+		CodeSystemName: system,
+	}
+
+	if cs, ok := codeSystems[strings.ToUpper(system)]; ok {
+		code.CodeSystem = cs.oid
+		code.CodeSystemName = cs.name
+	}
+
+	return code
+}
+
+// segmentName gets the name of segment, e.g. OBX
+func segmentName(segment hl7.Segment) string {
+	if len(segment) == 0 {
+		return ""
+	}
+
+	f, _ := segment[0].(hl7.Field)
+	return string(f)
+}
@@ -0,0 +1,193 @@
+package fromhl7
+
+import (
+	"regexp"
+	"strings"
+
+	"github.com/kdar/health/ccd"
+	hl7v2_3 "github.com/kdar/health/hl7x/2.3"
+)
+
+var (
+	// CDC race and ethnicity codes look like 2106-3
+	cdcCodeRE = regexp.MustCompile(`^\d{4}-\d$`)
+
+	// HL7 table 0001 -> AdministrativeGender
+	genders = map[string]ccd.Code{
+		"M": {Code: "M", DisplayName: "Male"},
+		"F": {Code: "F", DisplayName: "Female"},
+		"O": {Code: "UN", DisplayName: "Undifferentiated"},
+		"U": {Code: "UN", DisplayName: "Undifferentiated"},
+	}
+
+	// HL7 table 0002 -> MaritalStatus
+	maritalStatuses = map[string]ccd.Code{
+		"A": {Code: "L", DisplayName: "Legally Separated"},
+		"D": {Code: "D", DisplayName: "Divorced"},
+		"M": {Code: "M", DisplayName: "Married"},
+		"S": {Code: "S", DisplayName: "Never Married"},
+		"W": {Code: "W", DisplayName: "Widowed"},
+	}
+
+	// HL7 table 0190 -> PostalAddressUse
+	addressUses = map[string]string{
+		"H": "H",
+		"P": "HP",
+		"B": "WP",
+		"O": "WP",
+		"C": "TMP",
+		"M": "PST",
+	}
+
+	// HL7 table 0201 -> TelecommunicationAddressUse
+	telecomUses = map[string]string{
+		"PRN": "HP",
+		"ORN": "H",
+		"VHN": "HV",
+		"WPN": "WP",
+		"ASN": "AS",
+		"EMR": "EC",
+		"BPN": "PG",
+	}
+)
+
+// Patient converts a PID into a ccd.Patient.
+//
+// Sex (PID-8) and marital status (PID-16) are converted to the HL7 v3
+// code systems a CCD uses. Race (PID-10) and ethnic group (PID-22) are
+// only given a code system when they're CDC codes, since the HL7 v2
+// tables for them are user-defined.
+func Patient(pid *hl7v2_3.Pid) ccd.Patient {
+	patient := ccd.Patient{
+		Name:         Name(pid.PatientName),
+		Dob:          pid.DateOfBirth.TimeOfAnEvent.Time,
+		LanguageCode: string(pid.PrimaryLanguage.Identifier),
+		Religion:     ccd.Code{Code: string(pid.Religion)},
+	}
+
+	for _, xad := range pid.PatientAddresses {
+		if address := Address(xad); !address.IsZero() {
+			patient.Addresses = append(patient.Addresses, address)
+		}
+	}
+
+	for _, xtn := range pid.PhoneNumberHomes {
+		if telecom := Telecom(xtn, "HP"); telecom.Value != "" {
+			patient.Telecoms = append(patient.Telecoms, telecom)
+		}
+	}
+	for _, xtn := range pid.PhoneNumberBusinesses {
+		if telecom := Telecom(xtn, "WP"); telecom.Value != "" {
+			patient.Telecoms = append(patient.Telecoms, telecom)
+		}
+	}
+
+	if gender, ok := genders[strings.ToUpper(string(pid.Sex))]; ok {
+		gender.CodeSystem = "2.16.840.1.113883.5.1"
+		gender.CodeSystemName = "AdministrativeGender"
+		patient.Gender = gender
+	}
+
+	if len(pid.MaritalStatuses) > 0 {
+		if status, ok := maritalStatuses[strings.ToUpper(string(pid.MaritalStatuses[0]))]; ok {
+			status.CodeSystem = "2.16.840.1.113883.5.2"
+			status.CodeSystemName = "MaritalStatus"
+			patient.MaritalStatus = status
+		}
+	}
+
+	patient.Race = raceCode(string(pid.Race))
+	patient.Ethnicity = raceCode(string(pid.EthnicGroup))
+
+	return patient
+}
+
+func raceCode(code string) ccd.Code {
+	c := ccd.Code{Code: code}
+	if cdcCodeRE.MatchString(code) {
+		c.CodeSystem = "2.16.840.1.113883.6.238"
+		c.CodeSystemName = "Race & Ethnicity - CDC"
+	}
+	return c
+}
+
+// Name converts an XPN into a ccd.Name.
+func Name(xpn hl7v2_3.Xpn) ccd.Name {
+	return ccd.Name{
+		Last:   string(xpn.FamilyName),
+		First:  string(xpn.GivenName),
+		Middle: string(xpn.MiddleInitialOrName),
+		Suffix: string(xpn.Suffix),
+		Prefix: string(xpn.Prefix),
+		Type:   string(xpn.NameTypeCode),
+	}
+}
+
+// providerName converts the name parts of an XCN into a ccd.Name.
+func providerName(xcn hl7v2_3.Xcn) ccd.Name {
+	return ccd.Name{
+		Last:   string(xcn.FamilyName),
+		First:  string(xcn.GivenName),
+		Middle: string(xcn.MiddleInitialOrName),
+		Suffix: string(xcn.Suffix),
+		Prefix: string(xcn.Prefix),
+		Type:   string(xcn.NameType),
+	}
+}
+
+// Address converts an XAD into a ccd.Address.
+func Address(xad hl7v2_3.Xad) ccd.Address {
+	use := string(xad.AddressType)
+	if u, ok := addressUses[strings.ToUpper(use)]; ok {
+		use = u
+	}
+
+	return ccd.Address{
+		Line1:   string(xad.StreetAddress),
+		Line2:   string(xad.OtherDesignation),
+		City:    string(xad.City),
+		County:  string(xad.CountyParishCode),
+		State:   string(xad.StateOrProvince),
+		Zip:     string(xad.ZipOrPostalCode),
+		Country: string(xad.Country),
+		Use:     use,
+	}
+}
+
+// Telecom converts an XTN into a ccd.Telecom. use is the use to give
+// it when XTN-2 doesn't say, e.g. HP for a home phone number.
+func Telecom(xtn hl7v2_3.Xtn, use string) ccd.Telecom {
+	if u, ok := telecomUses[strings.ToUpper(string(xtn.TelecommunicationUseCode))]; ok {
+		use = u
+	}
+	if strings.ToUpper(string(xtn.TelecommunicationEquipmentTypeId)) == "CP" {
+		use = "MC"
+	}
+
+	if xtn.EmailAddress != "" || xtn.TelecommunicationUseCode == "NET" {
+		value := string(xtn.EmailAddress)
+		if value == "" {
+			value = string(xtn.A_999_999_9999X99999CAnyText)
+		}
+		return ccd.Telecom{Type: "email", Use: use, Value: value, OriginalValue: "mailto:" + value}
+	}
+
+	value := string(xtn.A_999_999_9999X99999CAnyText)
+	if value == "" && xtn.PhoneNumber != "" {
+		value = string(xtn.PhoneNumber)
+		if xtn.AreaCityCode != "" {
+			value = "(" + string(xtn.AreaCityCode) + ")" + value
+		}
+		if xtn.CountryCode != "" {
+			value = "+" + string(xtn.CountryCode) + value
+		}
+		if xtn.Extension != "" {
+			value += "x" + string(xtn.Extension)
+		}
+	}
+
+	if value == "" {
+		return ccd.Telecom{}
+	}
+	return ccd.Telecom{Type: "phone", Use: use, Value: value, OriginalValue: "tel:" + value}
+}
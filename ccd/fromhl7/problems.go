@@ -0,0 +1,43 @@
+package fromhl7
+
+import (
+	"strings"
+
+	"github.com/kdar/health/ccd"
+	hl7v2_3 "github.com/kdar/health/hl7x/2.3"
+)
+
+// HL7 table 0052
+var diagnosisTypes = map[string]string{
+	"A": "Admitting diagnosis",
+	"W": "Working diagnosis",
+	"F": "Final diagnosis",
+}
+
+// Problem converts a DG1 into a ccd.Problem. The name is the text of the
+// diagnosis code (DG1-3), or the description (DG1-4) if there isn't any,
+// and the problem type is the diagnosis type (DG1-6).
+func Problem(dg1 *hl7v2_3.Dg1) ccd.Problem {
+	problem := ccd.Problem{
+		Code: Code(dg1.DiagnosisCode),
+		Time: ccd.Time{
+			Type:  ccd.TIME_SINGLE,
+			Value: dg1.DiagnosisDateTime.TimeOfAnEvent.Time,
+		},
+	}
+
+	problem.Name = problem.Code.DisplayName
+	if problem.Name == "" {
+		problem.Name = string(dg1.DiagnosisDescription)
+	}
+	if problem.Name == "" {
+		problem.Name = problem.Code.Code
+	}
+
+	problem.ProblemType = string(dg1.DiagnosisType)
+	if t, ok := diagnosisTypes[strings.ToUpper(problem.ProblemType)]; ok {
+		problem.ProblemType = t
+	}
+
+	return problem
+}
@@ -0,0 +1,165 @@
+package fromhl7
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/kdar/health/ccd"
+	"github.com/kdar/health/hl7"
+	"github.com/kdar/health/hl7x"
+	hl7v2_3 "github.com/kdar/health/hl7x/2.3"
+)
+
+// Results converts the OBR/OBX groups of an ORU^R01 message into ccd
+// results. Each OBR starts a new result, and the OBXs that follow it are
+// its observations. Other segments are skipped, as are OBXs that come
+// before the first OBR. An OBX value that doesn't decode as its type
+// is kept as text.
+func Results(segments []hl7.Segment) ([]ccd.Result, error) {
+	var results []ccd.Result
+
+	var obr *hl7v2_3.Obr
+	var obxs []hl7v2_3.Obx
+	flush := func() {
+		if obr != nil {
+			results = append(results, Result(obr, obxs))
+		}
+		obr, obxs = nil, nil
+	}
+
+	for i, segment := range segments {
+		switch segmentName(segment) {
+		case "OBR":
+			flush()
+			obr = &hl7v2_3.Obr{}
+			if err := hl7x.Unmarshal(segment, obr); err != nil {
+				return nil, fmt.Errorf("segment %d: %s", i+1, err)
+			}
+		case "OBX":
+			if obr == nil {
+				continue
+			}
+
+			obx, err := unmarshalObx(segment)
+			if err != nil {
+				return nil, fmt.Errorf("segment %d: %s", i+1, err)
+			}
+			obxs = append(obxs, obx)
+		}
+	}
+	flush()
+
+	return results, nil
+}
+
+// unmarshals an OBX. Labs send values that don't decode as their value
+// type, like "<0.1" as an NM, so those are taken as an ST instead.
+func unmarshalObx(segment hl7.Segment) (hl7v2_3.Obx, error) {
+	var obx hl7v2_3.Obx
+	err := hl7x.Unmarshal(segment, &obx)
+	if err == nil || len(segment) < 3 {
+		return obx, err
+	}
+
+	st := make(hl7.Segment, len(segment))
+	copy(st, segment)
+	st[2] = hl7.Field("ST")
+
+	obx = hl7v2_3.Obx{}
+	if hl7x.Unmarshal(st, &obx) != nil {
+		return obx, err
+	}
+	return obx, nil
+}
+
+// Result converts an OBR and its OBXs into a ccd.Result. The date of
+// the result is OBR-7, which is also used for observations that
+// don't have their own (OBX-14).
+func Result(obr *hl7v2_3.Obr, obxs []hl7v2_3.Obx) ccd.Result {
+	result := ccd.Result{
+		Date: obr.ObservationDateTime.TimeOfAnEvent.Time,
+		Code: Code(obr.UniversalServiceIdentifier),
+	}
+
+	for i := range obxs {
+		observation := Observation(&obxs[i])
+		if observation.Date.IsZero() {
+			observation.Date = result.Date
+		}
+		result.Observations = append(result.Observations, observation)
+	}
+
+	return result
+}
+
+// Observation converts an OBX into a ccd.ResultObservation.
+//
+// The value (OBX-5) is converted based on its type: NM becomes a PQ
+// with the units from OBX-6, CE/CWE a CD, TS/DT a TS, and anything
+// else an ST. Repetitions of an ST are joined with newlines, otherwise
+// only the first is used. The reference range (OBX-7) is parsed with
+// ccd.ResultRanges, and the abnormal flags (OBX-8) are the
+// interpretation codes.
+func Observation(obx *hl7v2_3.Obx) ccd.ResultObservation {
+	observation := ccd.ResultObservation{
+		Date:  obx.DateTimeOfTheObservation.TimeOfAnEvent.Time,
+		Code:  Code(obx.ObservationIdentifier),
+		Value: resultValue(obx),
+	}
+
+	for _, flag := range obx.AbnormalFlags {
+		if flag != "" {
+			observation.InterpretationCodes = append(observation.InterpretationCodes, string(flag))
+		}
+	}
+
+	if obx.ReferencesRange != "" {
+		var ranges ccd.ResultRanges
+		ranges.Parse(string(obx.ReferencesRange))
+		observation.Ranges = ranges
+	}
+
+	return observation
+}
+
+func resultValue(obx *hl7v2_3.Obx) ccd.ResultValue {
+	values := obx.ObservationValues.Values
+	if len(values) == 0 {
+		return ccd.ResultValue{}
+	}
+
+	unit := string(obx.Units.Identifier)
+	if unit == "" {
+		unit = string(obx.Units.Text)
+	}
+
+	switch v := values[0].(type) {
+	case hl7x.Number:
+		return ccd.ResultValue{Type: "PQ", Value: v.String(), Unit: unit}
+	case hl7v2_3.Sn:
+		// a plain number is a quantity, anything
+		// else (<5, 1:128, ...) is text
+		if (v.Comparator == "" || v.Comparator == "=") && v.SeparatorOrSuffix == "" && v.Num2.IsZero() {
+			return ccd.ResultValue{Type: "PQ", Value: v.Num1.String(), Unit: unit}
+		}
+		text := string(v.Comparator) + v.Num1.String() + string(v.SeparatorOrSuffix) + v.Num2.String()
+		return ccd.ResultValue{Type: "ST", Value: text, Unit: unit}
+	case hl7v2_3.Ce:
+		code := Code(v)
+		value := code.DisplayName
+		if value == "" {
+			value = code.Code
+		}
+		return ccd.ResultValue{Type: "CD", Value: value}
+	case hl7v2_3.Ts:
+		return ccd.ResultValue{Type: "TS", Value: v.TimeOfAnEvent.String()}
+	case hl7x.Date:
+		return ccd.ResultValue{Type: "TS", Value: v.String()}
+	}
+
+	lines := make([]string, len(values))
+	for i, value := range values {
+		lines[i] = fmt.Sprint(value)
+	}
+	return ccd.ResultValue{Type: "ST", Value: strings.Join(lines, "\n"), Unit: unit}
+}
@@ -0,0 +1,154 @@
+package fromhl7
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kdar/health/ccd"
+	"github.com/kdar/health/hl7"
+)
+
+func unmarshal(t *testing.T, segments ...string) []hl7.Segment {
+	out, err := hl7.Unmarshal([]byte(strings.Join(segments, "\r") + "\r"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func float(f float64) *float64 {
+	return &f
+}
+
+func TestResults(t *testing.T) {
+	segments := unmarshal(t,
+		"MSH|^~\\&|LAB|HOSP|||20140922091808||ORU^R01|1|P|2.3",
+		"PID|1||123||DOE^JOHN",
+		"OBX|1|NM|IGNORED",
+		"OBR|1|||24323-8^Comprehensive metabolic panel^LN|||201409220800",
+		"OBX|1|NM|2345-7^Glucose^LN||105.0|mg/dL^^UCUM|70-99|H|||F",
+		"OBX|2|CE|5195-3^HBsAg^LN||NEG^Negative^L||||||F|||201409221000",
+		"NTE|1||comment",
+		"OBR|2|||BLD^Blood culture^L",
+		"OBX|1|TX|600-7^Culture^LN||No growth~at 5 days||||||F",
+		"OBX|2|SN|TITER^Titer^L||^1^:^128||<8|A|||F",
+	)
+
+	date := time.Date(2014, 9, 22, 8, 0, 0, 0, time.UTC)
+	expected := []ccd.Result{
+		{
+			Date: date,
+			Code: ccd.Code{Code: "24323-8", DisplayName: "Comprehensive metabolic panel", CodeSystem: "2.16.840.1.113883.6.1", CodeSystemName: "LOINC"},
+			Observations: []ccd.ResultObservation{
+				{
+					Date:                date,
+					Code:                ccd.Code{Code: "2345-7", DisplayName: "Glucose", CodeSystem: "2.16.840.1.113883.6.1", CodeSystemName: "LOINC"},
+					Value:               ccd.ResultValue{Type: "PQ", Value: "105.0", Unit: "mg/dL"},
+					InterpretationCodes: []string{"H"},
+					Ranges:              []ccd.ResultRange{{Low: float(70), High: float(99), OriginalText: "70-99"}},
+				},
+				{
+					Date:  time.Date(2014, 9, 22, 10, 0, 0, 0, time.UTC),
+					Code:  ccd.Code{Code: "5195-3", DisplayName: "HBsAg", CodeSystem: "2.16.840.1.113883.6.1", CodeSystemName: "LOINC"},
+					Value: ccd.ResultValue{Type: "CD", Value: "Negative"},
+				},
+			},
+		},
+		{
+			Code: ccd.Code{Code: "BLD", DisplayName: "Blood culture", CodeSystemName: "L"},
+			Observations: []ccd.ResultObservation{
+				{
+					Code:  ccd.Code{Code: "600-7", DisplayName: "Culture", CodeSystem: "2.16.840.1.113883.6.1", CodeSystemName: "LOINC"},
+					Value: ccd.ResultValue{Type: "ST", Value: "No growth\nat 5 days"},
+				},
+				{
+					Code:                ccd.Code{Code: "TITER", DisplayName: "Titer", CodeSystemName: "L"},
+					Value:               ccd.ResultValue{Type: "ST", Value: "1:128"},
+					InterpretationCodes: []string{"A"},
+					Ranges:              []ccd.ResultRange{{High: float(8), OriginalText: "<8"}},
+				},
+			},
+		},
+	}
+
+	results, err := Results(segments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(results) != len(expected) {
+		t.Fatalf("expected %d results, got %d", len(expected), len(results))
+	}
+
+	for i := range expected {
+		if !reflect.DeepEqual(results[i], expected[i]) {
+			t.Fatalf("%d. expected\n%#v\ngot\n%#v", i, expected[i], results[i])
+		}
+	}
+}
+
+func TestResultsInvalidValue(t *testing.T) {
+	segments := unmarshal(t,
+		"MSH|^~\\&|LAB|HOSP|||20140922091808||ORU^R01|1|P|2.3",
+		"OBR|1|||24323-8^Comprehensive metabolic panel^LN",
+		"OBX|1|NM|1742-6^ALT^LN||<0.1|mg/dL|||||F",
+		"OBX|2|NM|2345-7^Glucose^LN||105|mg/dL|||||F",
+	)
+
+	results, err := Results(segments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || len(results[0].Observations) != 2 {
+		t.Fatalf("expected 1 result with 2 observations, got %#v", results)
+	}
+
+	expected := []ccd.ResultValue{
+		{Type: "ST", Value: "<0.1", Unit: "mg/dL"},
+		{Type: "PQ", Value: "105", Unit: "mg/dL"},
+	}
+	for i, observation := range results[0].Observations {
+		if !reflect.DeepEqual(observation.Value, expected[i]) {
+			t.Fatalf("%d. expected %#v, got %#v", i, expected[i], observation.Value)
+		}
+	}
+}
+
+var codeTests = []struct {
+	in  string
+	out ccd.Code
+}{
+	{
+		"OBX|1|ST|2345-7^Glucose^LN^GLU^Glucose, serum^L",
+		ccd.Code{
+			Code: "2345-7", DisplayName: "Glucose", CodeSystem: "2.16.840.1.113883.6.1", CodeSystemName: "LOINC",
+			Translations: []ccd.Code{{Code: "GLU", DisplayName: "Glucose, serum", CodeSystemName: "L"}},
+		},
+	},
+	{
+		"OBX|1|ST|^^^GLU^Glucose^L",
+		ccd.Code{Code: "GLU", DisplayName: "Glucose", CodeSystemName: "L"},
+	},
+	{
+		"OBX|1|ST|401.9^Hypertension^i9c",
+		ccd.Code{Code: "401.9", DisplayName: "Hypertension", CodeSystem: "2.16.840.1.113883.6.103", CodeSystemName: "ICD-9-CM"},
+	},
+}
+
+func TestCode(t *testing.T) {
+	for i, tt := range codeTests {
+		segments := unmarshal(t, "MSH|^~\\&|LAB|HOSP|||20140922091808||ORU^R01|1|P|2.3", "OBR|1", tt.in)
+
+		results, err := Results(segments)
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+
+		code := results[0].Observations[0].Code
+		if !reflect.DeepEqual(code, tt.out) {
+			t.Fatalf("%d. expected %#v, got %#v", i, tt.out, code)
+		}
+	}
+}
@@ -0,0 +1,166 @@
+package edifact
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// A Charset is a syntax level: the character repertoire an
+// interchange declares in the first component of its UNB (or UIB)
+// syntax identifier.
+type Charset string
+
+const (
+	UNOA Charset = "UNOA" // upper case letters, digits, space and some punctuation
+	UNOB Charset = "UNOB" // UNOA plus lower case letters
+	UNOC Charset = "UNOC" // ISO 8859-1 (Latin 1)
+	UNOY Charset = "UNOY" // ISO 10646 (all of Unicode), written as UTF-8
+)
+
+// the punctuation of level A (ISO 9735 annex A), which includes the
+// default delimiters.
+const levelAPunctuation = " .,-()/='+:?!\"%&*;<>"
+
+// Contains reports whether r is in the charset. Every rune is in
+// a charset this package doesn't know about.
+func (c Charset) Contains(r rune) bool {
+	switch c {
+	case UNOA:
+		return 'A' <= r && r <= 'Z' || '0' <= r && r <= '9' ||
+			strings.ContainsRune(levelAPunctuation, r)
+	case UNOB:
+		return 'a' <= r && r <= 'z' || UNOA.Contains(r)
+	case UNOC:
+		return 0x20 <= r && r <= 0x7e || 0xa0 <= r && r <= 0xff
+	case UNOY:
+		return r != utf8.RuneError && !unicode.IsControl(r)
+	}
+	return true
+}
+
+// SyntaxIdentifier returns the Charset declared by the first UNB or
+// UIB segment in segments, or "" if there isn't one.
+func SyntaxIdentifier(segments Values) Charset {
+	for _, value := range segments {
+		segment, ok := value.(Values)
+		if !ok {
+			continue
+		}
+
+		if name := SegmentName(segment); name == UNB || name == UIB {
+			return syntaxIdentifier(segment)
+		}
+	}
+	return ""
+}
+
+// returns the Charset of a UNB or UIB segment.
+func syntaxIdentifier(segment Values) Charset {
+	switch id := Element(segment, 1).(type) {
+	case string:
+		return Charset(id)
+	case Values:
+		if len(id) > 0 {
+			s, _ := id[0].(string)
+			return Charset(s)
+		}
+	}
+	return ""
+}
+
+// What to do with characters that aren't in the charset when
+// decoding.
+type CharsetMode int
+
+const (
+	// Accept any character, as Unmarshal does.
+	CharsetIgnore CharsetMode = iota
+	// Decode the data, but return a CharsetErrors listing the
+	// characters that aren't in the charset.
+	CharsetReport
+	// Stop at the first character that isn't in the charset.
+	CharsetEnforce
+)
+
+// CharsetErrors is returned by UnmarshalOptions, along with the
+// decoded values, in CharsetReport mode. There is one error per
+// data element (or component) with characters outside the charset.
+type CharsetErrors []*SyntaxError
+
+func (e CharsetErrors) Error() string {
+	if len(e) == 1 {
+		return e[0].Error()
+	}
+	return fmt.Sprintf("%s (and %d more errors)", e[0], len(e)-1)
+}
+
+// returns the first rune of s that isn't in the charset.
+func (c Charset) invalid(s string) (rune, bool) {
+	for _, r := range s {
+		if !c.Contains(r) {
+			return r, true
+		}
+	}
+	return 0, false
+}
+
+// converts ISO 8859-1 text to UTF-8.
+func latin1ToUTF8(b []byte) string {
+	runes := make([]rune, len(b))
+	for i, c := range b {
+		runes[i] = rune(c)
+	}
+	return string(runes)
+}
+
+// the letters and punctuation we know how to write in the
+// smaller charsets.
+var transliterations = map[rune]string{
+	'À': "A", 'Á': "A", 'Â': "A", 'Ã': "A", 'Ä': "A", 'Å': "A", 'Æ': "AE",
+	'Ç': "C", 'È': "E", 'É': "E", 'Ê': "E", 'Ë': "E",
+	'Ì': "I", 'Í': "I", 'Î': "I", 'Ï': "I", 'Ð': "D", 'Ñ': "N",
+	'Ò': "O", 'Ó': "O", 'Ô': "O", 'Õ': "O", 'Ö': "O", 'Ø': "O",
+	'Ù': "U", 'Ú': "U", 'Û': "U", 'Ü': "U", 'Ý': "Y", 'Þ': "TH", 'ß': "ss",
+	'à': "a", 'á': "a", 'â': "a", 'ã': "a", 'ä': "a", 'å': "a", 'æ': "ae",
+	'ç': "c", 'è': "e", 'é': "e", 'ê': "e", 'ë': "e",
+	'ì': "i", 'í': "i", 'î': "i", 'ï': "i", 'ð': "d", 'ñ': "n",
+	'ò': "o", 'ó': "o", 'ô': "o", 'õ': "o", 'ö': "o", 'ø': "o",
+	'ù': "u", 'ú': "u", 'û': "u", 'ü': "u", 'ý': "y", 'þ': "th", 'ÿ': "y",
+	'‘': "'", '’': "'", '“': "\"", '”': "\"", '–': "-", '—': "-", '…': "...",
+	'\t': " ", '\u00a0': " ",
+}
+
+// rewrites s to only use runes in the charset. Runes that can't be
+// written are replaced by a question mark.
+func (c Charset) transliterate(s string) string {
+	if _, ok := c.invalid(s); !ok {
+		return s
+	}
+
+	var buf strings.Builder
+	for _, r := range s {
+		if c.Contains(r) {
+			buf.WriteRune(r)
+			continue
+		}
+
+		t, ok := transliterations[r]
+		if !ok {
+			t = string(r)
+		}
+		// UNOA only has upper case letters
+		if c == UNOA {
+			t = strings.ToUpper(t)
+		}
+
+		for _, r := range t {
+			if !c.Contains(r) {
+				r = '?'
+			}
+			buf.WriteRune(r)
+		}
+	}
+	return buf.String()
+}
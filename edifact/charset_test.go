@@ -0,0 +1,150 @@
+package edifact
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kdar/health/edifact/parse"
+)
+
+var charsetContainsTests = []struct {
+	charset Charset
+	r       rune
+	out     bool
+}{
+	{UNOA, 'A', true},
+	{UNOA, '?', true},
+	{UNOA, 'a', false},
+	{UNOA, '#', false},
+	{UNOB, 'a', true},
+	{UNOB, 'é', false},
+	{UNOC, 'é', true},
+	{UNOC, '\n', false},
+	{UNOC, '€', false},
+	{UNOY, '€', true},
+	{UNOY, '\x00', false},
+	{"UNOX", '€', true},
+}
+
+func TestCharsetContains(t *testing.T) {
+	for i, tt := range charsetContainsTests {
+		if out := tt.charset.Contains(tt.r); out != tt.out {
+			t.Fatalf("%d. expected %s.Contains(%q) to be %v", i, tt.charset, tt.r, tt.out)
+		}
+	}
+}
+
+func TestSyntaxIdentifier(t *testing.T) {
+	if c := SyntaxIdentifier(CRAZY_OUT1); c != UNOA {
+		t.Fatalf("expected UNOA, got %q", c)
+	}
+
+	if c := SyntaxIdentifier(Values{Values{"UNB", "UNOC"}}); c != UNOC {
+		t.Fatalf("expected UNOC, got %q", c)
+	}
+
+	if c := SyntaxIdentifier(Values{Values{"UNH", "1"}}); c != "" {
+		t.Fatalf("expected no charset, got %q", c)
+	}
+}
+
+func TestUnmarshalOptionsCharset(t *testing.T) {
+	// UNOC is ISO 8859-1
+	out, err := UnmarshalOptions([]byte("UNB+UNOC:3+S+R'NAD+M\xfcller'"), DecodeOptions{CharsetMode: CharsetEnforce})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Values{DefaultHeader, Values{"UNB", Values{"UNOC", "3"}, "S", "R"}, Values{"NAD", "Müller"}}
+	if !reflect.DeepEqual(out, expected) {
+		t.Fatalf("mismatch\nhave: %#+v\nwant: %#+v", out, expected)
+	}
+
+	// released delimiters don't change the other bytes
+	out, err = UnmarshalOptions([]byte("UIB+UNOC:0+D1'NAD+M\xfcller?+Co'"), DecodeOptions{CharsetMode: CharsetEnforce})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected = Values{DefaultHeader, Values{"UIB", Values{"UNOC", "0"}, "D1"}, Values{"NAD", "Müller+Co"}}
+	if !reflect.DeepEqual(out, expected) {
+		t.Fatalf("mismatch\nhave: %#+v\nwant: %#+v", out, expected)
+	}
+
+	// UNOA has no lower case letters
+	_, err = UnmarshalOptions([]byte("UNB+UNOA:1+S+R'NAD+SMITH:John'"), DecodeOptions{CharsetMode: CharsetEnforce})
+	checkSyntaxError(t, 0, err, SyntaxError{
+		Err: parse.ErrInvalidCharset, Offset: 15, Line: 1, Column: 16, Segment: 2, Tag: "NAD", Element: 1, Component: 2,
+	})
+
+	// but that's just reported if asked
+	in := []byte("UNB+UNOA:1+S+R'NAD+Smith+Jones'")
+	out, err = UnmarshalOptions(in, DecodeOptions{CharsetMode: CharsetReport})
+	var errs CharsetErrors
+	if !errors.As(err, &errs) || len(errs) != 2 {
+		t.Fatalf("expected 2 CharsetErrors, got %v", err)
+	}
+	if len(out) != 3 {
+		t.Fatalf("expected the values too, got %#v", out)
+	}
+
+	// and ignored by default, or with a charset that allows it
+	if _, err := UnmarshalOptions(in, DecodeOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := UnmarshalOptions(in, DecodeOptions{Charset: UNOB, CharsetMode: CharsetEnforce}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMarshalOptionsCharset(t *testing.T) {
+	segments := Values{Values{"NAD", Values{"Müller", "José"}}}
+
+	if _, err := MarshalOptions(segments, EncodeOptions{Charset: UNOB}); err == nil {
+		t.Fatal("expected an error for characters not in UNOB")
+	}
+
+	tests := []struct {
+		opts EncodeOptions
+		out  string
+	}{
+		{EncodeOptions{Charset: UNOA, Transliterate: true}, "NAD+MULLER:JOSE'"},
+		{EncodeOptions{Charset: UNOB, Transliterate: true}, "NAD+Muller:Jose'"},
+		{EncodeOptions{Charset: UNOC}, "NAD+M\xfcller:Jos\xe9'"},
+		{EncodeOptions{Charset: UNOY}, "NAD+Müller:José'"},
+	}
+
+	for i, tt := range tests {
+		out, err := MarshalOptions(segments, tt.opts)
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+		if string(out) != tt.out {
+			t.Fatalf("%d. unexpected output: %q. want %q", i, out, tt.out)
+		}
+	}
+}
+
+func TestNewDecoderOptions(t *testing.T) {
+	in := "UIB+UNOC:0+D1'UIH+SCRIPT:008:001:RXHREQ+M1'NAD+M\xfcller'UIT+M1+3'UIZ+D1+1'"
+	d := NewDecoderOptions(strings.NewReader(in), DecodeOptions{Charset: UNOA, CharsetMode: CharsetReport})
+
+	if _, err := d.Message(); err != nil {
+		t.Fatal(err)
+	}
+
+	message, err := d.Message()
+	var errs CharsetErrors
+	if !errors.As(err, &errs) || len(errs) != 1 {
+		t.Fatalf("expected 1 CharsetError, got %v", err)
+	}
+	if len(message) != 3 {
+		t.Fatalf("expected the message too, got %#v", message)
+	}
+
+	checkSyntaxError(t, 0, errs[0], SyntaxError{
+		Err: parse.ErrInvalidCharset, Offset: 43, Line: 1, Column: 44, Segment: 3, Tag: "NAD", Element: 1,
+	})
+}
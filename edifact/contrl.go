@@ -0,0 +1,449 @@
+package edifact
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+
+	"github.com/kdar/health/edifact/parse"
+)
+
+// The CONTRL message type, the syntax and service report sent in
+// response to an interchange.
+const CONTRL = "CONTRL"
+
+// The segments of a CONTRL message.
+const (
+	UCI = "UCI" // interchange response
+	UCF = "UCF" // group response
+	UCM = "UCM" // message response
+	UCS = "UCS" // segment error
+	UCD = "UCD" // data element error
+)
+
+// An Action (data element 0083) is what was done with an interchange,
+// group or message.
+type Action string
+
+const (
+	ActionRejected     Action = "4" // rejected, along with everything in it
+	ActionAcknowledged Action = "7" // accepted, along with everything in it that isn't reported as rejected
+	ActionReceived     Action = "8" // received, but not checked
+)
+
+// Accepted reports whether the action isn't a rejection.
+func (a Action) Accepted() bool {
+	return a != ActionRejected
+}
+
+// An ErrorCode (data element 0085) is why something was rejected.
+type ErrorCode string
+
+const (
+	ErrorSyntaxVersion        ErrorCode = "2"  // syntax version or level not supported
+	ErrorInvalidValue         ErrorCode = "12" // invalid value
+	ErrorMissing              ErrorCode = "13" // missing
+	ErrorValueNotSupported    ErrorCode = "14" // value not supported in this position
+	ErrorNotSupported         ErrorCode = "15" // not supported in this position
+	ErrorTooManyConstituents  ErrorCode = "16" // too many constituents
+	ErrorUnspecified          ErrorCode = "18" // unspecified error
+	ErrorInvalidDecimal       ErrorCode = "19" // invalid decimal notation
+	ErrorInvalidCharacter     ErrorCode = "21" // invalid character(s)
+	ErrorInvalidServiceTag    ErrorCode = "22" // invalid service segment tag
+	ErrorReferenceMismatch    ErrorCode = "28" // references do not match
+	ErrorControlCount         ErrorCode = "29" // control count does not match number of instances received
+	ErrorGroupsAndMessages    ErrorCode = "30" // functional groups and messages mixed
+	ErrorTooManyRepetitions   ErrorCode = "35" // too many repetitions
+	ErrorInvalidCharacterType ErrorCode = "37" // invalid type of character(s)
+	ErrorDataElementTooLong   ErrorCode = "39" // data element too long
+	ErrorDataElementTooShort  ErrorCode = "40" // data element too short
+	ErrorTrailingSeparator    ErrorCode = "45" // trailing separator
+)
+
+// A ReportError is an error code, and where the error was.
+type ReportError struct {
+	Code      ErrorCode
+	Tag       string // the service segment tag, e.g. UNT; not used for data element errors
+	Element   int    // the position of the data element, or 0
+	Component int    // the position of the component, or 0
+}
+
+// A Report is the content of a CONTRL message: whether an interchange,
+// and the groups and messages in it, were accepted.
+type Report struct {
+	Reference string // the interchange control reference (UNB.5)
+	Sender    Values // UNB.2
+	Recipient Values // UNB.3
+	Action    Action
+	Error     *ReportError // why the interchange was rejected
+	Groups    []*GroupReport
+	Messages  []*MessageReport
+}
+
+// A GroupReport is the response to a functional group (UCF).
+type GroupReport struct {
+	Reference string // the group reference (UNG.5)
+	Sender    Values // UNG.2
+	Recipient Values // UNG.3
+	Action    Action
+	Error     *ReportError
+	Messages  []*MessageReport
+}
+
+// A MessageReport is the response to a message (UCM).
+type MessageReport struct {
+	Reference  string // the message reference (UNH.1)
+	Identifier Values // the message type, version, release and agency (UNH.2)
+	Action     Action
+	Error      *ReportError
+	Segments   []*SegmentReport
+}
+
+// A SegmentReport is an error in a segment of a message (UCS), and
+// the errors in its data elements (UCD).
+type SegmentReport struct {
+	Position int // the position of the segment in the message, starting at 1 for the UNH
+	Code     ErrorCode
+	Elements []*ReportError
+}
+
+// NewReport returns a Report for an interchange, acknowledging it
+// unless Validate found errors in its trailer segments. A message or
+// group with bad trailers is rejected on its own. Errors in the
+// segments of a message are added with MessageReport.AddError.
+func NewReport(ic *Interchange) *Report {
+	r := &Report{Action: ActionAcknowledged}
+	r.Reference, r.Sender, r.Recipient = interchangeParties(ic.Start)
+
+	for _, group := range ic.Groups {
+		gr := &GroupReport{
+			Reference: Text(Element(group.Start, 5)),
+			Sender:    composite(Element(group.Start, 2)),
+			Recipient: composite(Element(group.Start, 3)),
+			Action:    ActionAcknowledged,
+		}
+		for _, message := range group.Messages {
+			gr.Messages = append(gr.Messages, newMessageReport(message))
+		}
+		if err := reportControlErrors(group.End, group.trailer()); err != nil {
+			gr.Action, gr.Error = ActionRejected, err
+		}
+		r.Groups = append(r.Groups, gr)
+	}
+
+	for _, message := range ic.Messages {
+		r.Messages = append(r.Messages, newMessageReport(message))
+	}
+
+	if err := reportControlErrors(ic.End, ic.trailer()); err != nil {
+		r.Action, r.Error = ActionRejected, err
+	}
+
+	return r
+}
+
+// AddError reports err in the segment at position in the message,
+// starting at 1 for the UNH, and rejects the message. It's for the
+// errors found decoding or validating the message, e.g. the ones
+// directory.Validate returns, with the code from their Code method. An
+// err without an Element is an error in the segment itself (UCS),
+// otherwise it's in one of its data elements (UCD). The segments are
+// reported in order.
+func (mr *MessageReport) AddError(position int, err *ReportError) {
+	mr.Action = ActionRejected
+
+	i := 0
+	for i < len(mr.Segments) && mr.Segments[i].Position < position {
+		i++
+	}
+	if i == len(mr.Segments) || mr.Segments[i].Position != position {
+		mr.Segments = append(mr.Segments, nil)
+		copy(mr.Segments[i+1:], mr.Segments[i:])
+		mr.Segments[i] = &SegmentReport{Position: position}
+	}
+	segment := mr.Segments[i]
+
+	if err.Element == 0 {
+		segment.Code = err.Code
+		return
+	}
+	segment.Elements = append(segment.Elements, &ReportError{
+		Code:      err.Code,
+		Element:   err.Element,
+		Component: err.Component,
+	})
+}
+
+// NewRejectReport returns a Report rejecting a whole interchange
+// because of err, e.g. the *SyntaxError from decoding it. start is
+// the UNB segment, if it could be decoded.
+func NewRejectReport(start Values, err error) *Report {
+	r := &Report{Action: ActionRejected}
+	r.Reference, r.Sender, r.Recipient = interchangeParties(start)
+	r.Error = &ReportError{Code: ErrorUnspecified}
+
+	var serr *SyntaxError
+	if !errors.As(err, &serr) {
+		return r
+	}
+
+	switch serr.Err {
+	case parse.ErrInvalidCharset, parse.ErrInvalidCharacter:
+		r.Error.Code = ErrorInvalidCharacter
+	case parse.ErrUnexpectedEOF:
+		r.Error.Code = ErrorMissing
+	}
+
+	// only errors in service segments are reported with a position
+	if isServiceSegment(serr.Tag) {
+		r.Error.Tag = serr.Tag
+		r.Error.Element = serr.Element
+		r.Error.Component = serr.Component
+	}
+
+	return r
+}
+
+func newMessageReport(m *Message) *MessageReport {
+	mr := &MessageReport{Action: ActionAcknowledged}
+	if SegmentName(m.Start) == UIH {
+		mr.Reference = Text(Element(m.Start, 2))
+		mr.Identifier = composite(Element(m.Start, 1))
+	} else {
+		mr.Reference = Text(Element(m.Start, 1))
+		mr.Identifier = composite(Element(m.Start, 2))
+	}
+
+	if err := reportControlErrors(m.End, m.trailer()); err != nil {
+		mr.Action, mr.Error = ActionRejected, err
+	}
+	return mr
+}
+
+// returns the interchange reference, sender and recipient of a UNB
+// or UIB segment.
+func interchangeParties(start Values) (string, Values, Values) {
+	if SegmentName(start) == UIB {
+		return Text(Element(start, 2)), composite(Element(start, 6)), composite(Element(start, 7))
+	}
+	return Text(Element(start, 5)), composite(Element(start, 2)), composite(Element(start, 3))
+}
+
+// returns the first error in a trailer segment, if there's one.
+func reportControlErrors(actual, expected Values) *ReportError {
+	errs := compareTrailer(actual, expected)
+	if len(errs) == 0 {
+		return nil
+	}
+
+	err := errs[0]
+	if err.Element == 0 {
+		return &ReportError{Code: ErrorMissing, Tag: err.Segment}
+	}
+
+	code := ErrorReferenceMismatch
+	if isCount(err.Segment, err.Element) {
+		code = ErrorControlCount
+	}
+	return &ReportError{Code: code, Tag: err.Segment, Element: err.Element}
+}
+
+// reports whether data element n of a trailer segment is its count.
+func isCount(name string, n int) bool {
+	switch name {
+	case UIT, UIZ:
+		return n == 2
+	}
+	return n == 1
+}
+
+func isServiceSegment(name string) bool {
+	return len(name) == 3 && (name[:2] == "UN" || name[:2] == "UI")
+}
+
+// returns the data element as a composite.
+func composite(data interface{}) Values {
+	switch data := data.(type) {
+	case Values:
+		if !isRepetition(data) {
+			return data
+		}
+		return composite(data[0])
+	case string:
+		if data != "" {
+			return Values{data}
+		}
+	}
+	return nil
+}
+
+// Message returns the CONTRL message for the report, with the
+// message reference ref. Its trailer is filled in by
+// Interchange.Values.
+func (r *Report) Message(ref string) *Message {
+	m := &Message{
+		Start: Values{UNH, ref, Values{CONTRL, "D", "3", "UN"}},
+	}
+
+	m.Segments = append(m.Segments, trimValues(appendReportError(Values{
+		UCI, r.Reference, r.Sender, r.Recipient, string(r.Action),
+	}, r.Error)))
+
+	for _, group := range r.Groups {
+		m.Segments = append(m.Segments, trimValues(appendReportError(Values{
+			UCF, group.Reference, group.Sender, group.Recipient, string(group.Action),
+		}, group.Error)))
+		m.Segments = append(m.Segments, messageReportSegments(group.Messages)...)
+	}
+	m.Segments = append(m.Segments, messageReportSegments(r.Messages)...)
+
+	return m
+}
+
+func messageReportSegments(messages []*MessageReport) []Values {
+	var segments []Values
+	for _, message := range messages {
+		segments = append(segments, trimValues(appendReportError(Values{
+			UCM, message.Reference, message.Identifier, string(message.Action),
+		}, message.Error)))
+
+		for _, segment := range message.Segments {
+			segments = append(segments, trimValues(Values{
+				UCS, strconv.Itoa(segment.Position), string(segment.Code),
+			}))
+			for _, err := range segment.Elements {
+				segments = append(segments, trimValues(Values{
+					UCD, string(err.Code), positionValues(err),
+				}))
+			}
+		}
+	}
+	return segments
+}
+
+// appends the error code, service segment tag and data element
+// position of err, if there is one.
+func appendReportError(values Values, err *ReportError) Values {
+	if err == nil {
+		return values
+	}
+	return append(values, string(err.Code), err.Tag, positionValues(err))
+}
+
+// returns the S011 data element identification for err.
+func positionValues(err *ReportError) Values {
+	var position Values
+	if err.Element > 0 {
+		position = append(position, strconv.Itoa(err.Element))
+		if err.Component > 0 {
+			position = append(position, strconv.Itoa(err.Component))
+		}
+	}
+	return position
+}
+
+// ParseReport returns the Report in a CONTRL message.
+func ParseReport(m *Message) (*Report, error) {
+	identifier := Element(m.Start, 2)
+	if SegmentName(m.Start) == UIH {
+		identifier = Element(m.Start, 1)
+	}
+	if name := Text(identifier); name != CONTRL {
+		return nil, fmt.Errorf("edifact: %s message is not a %s", name, CONTRL)
+	}
+
+	var (
+		r       *Report
+		group   *GroupReport
+		message *MessageReport
+		segment *SegmentReport
+	)
+
+	for i, s := range m.Segments {
+		name := SegmentName(s)
+		if r == nil && name != UCI {
+			return nil, fmt.Errorf("edifact: segment %d: expected %s, found %s", i+2, UCI, name)
+		}
+
+		switch name {
+		case UCI:
+			if r != nil {
+				return nil, fmt.Errorf("edifact: segment %d: found a second %s", i+2, UCI)
+			}
+			r = &Report{
+				Reference: Text(Element(s, 1)),
+				Sender:    composite(Element(s, 2)),
+				Recipient: composite(Element(s, 3)),
+				Action:    Action(Text(Element(s, 4))),
+				Error:     parseReportError(s, 5),
+			}
+		case UCF:
+			group = &GroupReport{
+				Reference: Text(Element(s, 1)),
+				Sender:    composite(Element(s, 2)),
+				Recipient: composite(Element(s, 3)),
+				Action:    Action(Text(Element(s, 4))),
+				Error:     parseReportError(s, 5),
+			}
+			r.Groups = append(r.Groups, group)
+			message, segment = nil, nil
+		case UCM:
+			message = &MessageReport{
+				Reference:  Text(Element(s, 1)),
+				Identifier: composite(Element(s, 2)),
+				Action:     Action(Text(Element(s, 3))),
+				Error:      parseReportError(s, 4),
+			}
+			if group != nil {
+				group.Messages = append(group.Messages, message)
+			} else {
+				r.Messages = append(r.Messages, message)
+			}
+			segment = nil
+		case UCS:
+			if message == nil {
+				return nil, fmt.Errorf("edifact: segment %d: found %s outside of a %s", i+2, UCS, UCM)
+			}
+			position, err := strconv.Atoi(Text(Element(s, 1)))
+			if err != nil {
+				return nil, fmt.Errorf("edifact: segment %d: invalid segment position: %s", i+2, err)
+			}
+			segment = &SegmentReport{Position: position, Code: ErrorCode(Text(Element(s, 2)))}
+			message.Segments = append(message.Segments, segment)
+		case UCD:
+			if segment == nil {
+				return nil, fmt.Errorf("edifact: segment %d: found %s outside of a %s", i+2, UCD, UCS)
+			}
+			err := &ReportError{Code: ErrorCode(Text(Element(s, 1)))}
+			err.Element, err.Component = parsePosition(Element(s, 2))
+			segment.Elements = append(segment.Elements, err)
+		default:
+			return nil, fmt.Errorf("edifact: segment %d: unexpected %s segment", i+2, name)
+		}
+	}
+
+	if r == nil {
+		return nil, fmt.Errorf("edifact: missing %s segment", UCI)
+	}
+	return r, nil
+}
+
+// returns the error in data elements n (code), n+1 (service segment
+// tag) and n+2 (position) of a segment, or nil if there's no code.
+func parseReportError(segment Values, n int) *ReportError {
+	code := Text(Element(segment, n))
+	if code == "" {
+		return nil
+	}
+
+	err := &ReportError{Code: ErrorCode(code), Tag: Text(Element(segment, n+1))}
+	err.Element, err.Component = parsePosition(Element(segment, n+2))
+	return err
+}
+
+// returns the data element and component positions of S011.
+func parsePosition(data interface{}) (int, int) {
+	e, _ := strconv.Atoi(Text(Component(data, 1)))
+	c, _ := strconv.Atoi(Text(Component(data, 2)))
+	return e, c
+}
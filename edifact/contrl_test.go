@@ -0,0 +1,219 @@
+package edifact
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// puts the CONTRL message for r in an interchange and marshals it.
+func marshalReport(t *testing.T, r *Report) string {
+	ic := &Interchange{
+		Start:    Values{UNB, Values{"UNOA", "3"}, "RECEIVER", "SENDER", Values{"130113", "0600"}, "C1"},
+		Messages: []*Message{r.Message("1")},
+	}
+
+	out, err := ic.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out)
+}
+
+func TestNewReport(t *testing.T) {
+	values, err := Unmarshal([]byte(ENVELOPE_IN1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ic, err := NewInterchange(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "UNB+UNOA:3+RECEIVER+SENDER+130113:0600+C1'" +
+		"UNH+1+CONTRL:D:3:UN'" +
+		"UCI+REF1+SENDER+RECEIVER+7'" +
+		"UCF+G1+SENDER+RECEIVER+7'" +
+		"UCM+M1+ORDERS:D:96A:UN+7'" +
+		"UCM+M2+ORDERS:D:96A:UN+7'" +
+		"UNT+6+1'" +
+		"UNZ+1+C1'"
+	if out := marshalReport(t, NewReport(ic)); out != expected {
+		t.Fatalf("unexpected output: %s. want %s", out, expected)
+	}
+
+	// the trailers are wrong
+	values, err = Unmarshal([]byte(
+		"UNB+UNOA:1+S+R+130113:0516+REF1'" +
+			"UNH+M1+ORDERS'BGM+220'UNT+2+M2'" +
+			"UNH+M3+ORDERS'UNT+2+M3'" +
+			"UNH+M4+ORDERS'UNT+2+M5'" +
+			"UNZ+3+REF2'"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if ic, err = NewInterchange(values); err != nil {
+		t.Fatal(err)
+	}
+
+	expected = "UNB+UNOA:3+RECEIVER+SENDER+130113:0600+C1'" +
+		"UNH+1+CONTRL:D:3:UN'" +
+		"UCI+REF1+S+R+4+28+UNZ+2'" +
+		"UCM+M1+ORDERS+4+29+UNT+1'" +
+		"UCM+M3+ORDERS+7'" +
+		"UCM+M4+ORDERS+4+28+UNT+2'" +
+		"UNT+6+1'" +
+		"UNZ+1+C1'"
+	if out := marshalReport(t, NewReport(ic)); out != expected {
+		t.Fatalf("unexpected output: %s. want %s", out, expected)
+	}
+}
+
+func TestNewReportErrors(t *testing.T) {
+	values, err := Unmarshal([]byte(ENVELOPE_IN1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ic, err := NewInterchange(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewReport(ic)
+	m := r.Groups[0].Messages[1]
+	m.AddError(4, &ReportError{Code: ErrorDataElementTooLong, Element: 2, Component: 1})
+	m.AddError(2, &ReportError{Code: ErrorNotSupported})
+	m.AddError(4, &ReportError{Code: ErrorMissing, Element: 3})
+	m.AddError(2, &ReportError{Code: ErrorInvalidCharacterType, Element: 1})
+
+	expected := "UNB+UNOA:3+RECEIVER+SENDER+130113:0600+C1'" +
+		"UNH+1+CONTRL:D:3:UN'" +
+		"UCI+REF1+SENDER+RECEIVER+7'" +
+		"UCF+G1+SENDER+RECEIVER+7'" +
+		"UCM+M1+ORDERS:D:96A:UN+7'" +
+		"UCM+M2+ORDERS:D:96A:UN+4'" +
+		"UCS+2+15'" +
+		"UCD+37+1'" +
+		"UCS+4'" +
+		"UCD+39+2:1'" +
+		"UCD+13+3'" +
+		"UNT+11+1'" +
+		"UNZ+1+C1'"
+	out := marshalReport(t, r)
+	if out != expected {
+		t.Fatalf("unexpected output: %s. want %s", out, expected)
+	}
+
+	// and it reads back
+	values, err = Unmarshal([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if ic, err = NewInterchange(values); err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := ParseReport(ic.Messages[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(parsed.Groups[0].Messages[1], m) {
+		t.Fatalf("mismatch\nhave: %#+v\nwant: %#+v", parsed.Groups[0].Messages[1], m)
+	}
+}
+
+func TestNewRejectReport(t *testing.T) {
+	_, err := Unmarshal([]byte("UNB+UNOA:1+S+R+130113:0516+REF1'UNH+M1"))
+	if err == nil {
+		t.Fatal("expected an error")
+	}
+
+	start := Values{UNB, Values{"UNOA", "1"}, "S", "R", Values{"130113", "0516"}, "REF1"}
+	expected := &Report{
+		Reference: "REF1",
+		Sender:    Values{"S"},
+		Recipient: Values{"R"},
+		Action:    ActionRejected,
+		Error:     &ReportError{Code: ErrorMissing, Tag: UNH, Element: 1},
+	}
+
+	if r := NewRejectReport(start, err); !reflect.DeepEqual(r, expected) {
+		t.Fatalf("mismatch\nhave: %#+v\nwant: %#+v", r, expected)
+	}
+}
+
+func TestParseReport(t *testing.T) {
+	expected := &Report{
+		Reference: "REF1",
+		Sender:    Values{"SENDER", "ZZ"},
+		Recipient: Values{"RECEIVER"},
+		Action:    ActionAcknowledged,
+		Messages: []*MessageReport{
+			{
+				Reference:  "M1",
+				Identifier: Values{"ORDERS", "D", "96A", "UN"},
+				Action:     ActionRejected,
+				Segments: []*SegmentReport{
+					{
+						Position: 3,
+						Code:     ErrorInvalidValue,
+						Elements: []*ReportError{
+							{Code: ErrorMissing, Element: 2},
+							{Code: ErrorDataElementTooLong, Element: 3, Component: 1},
+						},
+					},
+				},
+			},
+			{
+				Reference:  "M2",
+				Identifier: Values{"ORDERS", "D", "96A", "UN"},
+				Action:     ActionAcknowledged,
+			},
+		},
+	}
+
+	out := marshalReport(t, expected)
+	if want := "UCM+M1+ORDERS:D:96A:UN+4'UCS+3+12'UCD+13+2'UCD+39+3:1'"; !strings.Contains(out, want) {
+		t.Fatalf("unexpected output: %s. want it to contain %s", out, want)
+	}
+
+	values, err := Unmarshal([]byte(out))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ic, err := NewInterchange(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	r, err := ParseReport(ic.Messages[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(r, expected) {
+		t.Fatalf("mismatch\nhave: %#+v\nwant: %#+v", r, expected)
+	}
+
+	if r.Action.Accepted() != true || r.Messages[0].Action.Accepted() != false {
+		t.Fatal("expected the interchange to be accepted and the first message rejected")
+	}
+}
+
+var parseReportErrorTests = []*Message{
+	{Start: Values{UNH, "1", Values{"ORDERS", "D", "96A", "UN"}}},
+	{Start: Values{UNH, "1", Values{CONTRL, "D", "3", "UN"}}},
+	{Start: Values{UNH, "1", Values{CONTRL, "D", "3", "UN"}}, Segments: []Values{{UCM, "M1", "ORDERS", "7"}}},
+	{Start: Values{UNH, "1", Values{CONTRL, "D", "3", "UN"}}, Segments: []Values{{UCI, "REF1", "S", "R", "7"}, {UCS, "3", "12"}}},
+}
+
+func TestParseReportErrors(t *testing.T) {
+	for i, m := range parseReportErrorTests {
+		if _, err := ParseReport(m); err == nil {
+			t.Fatalf("%d. expected an error", i)
+		}
+	}
+}
@@ -0,0 +1,288 @@
+package edifact
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+)
+
+// JSON and XML representations of decoded values, for storing
+// interchanges and querying them with standard tools. They round trip:
+// FromJSON(ToJSON(values)) gives back values, ready for Marshal.
+//
+// The JSON is an array with an object for every segment, and one for
+// every Header (UNA segment):
+//
+//	[
+//	  {"una": ":+.? '"},
+//	  {"tag": "UNB", "elements": [["UNOA", "1"], "SENDER", "RECEIVER"]},
+//	  ...
+//	]
+//
+// Data elements are strings, composites are arrays of strings, and
+// repetitions are arrays of those.
+//
+// The XML has a segment element for every segment, with an element
+// for each data element in order, which has its text or component
+// (or repetition) children in order:
+//
+//	<edifact>
+//	  <una value=":+.? &#39;"></una>
+//	  <segment tag="UNB">
+//	    <element><component>UNOA</component><component>1</component></element>
+//	    <element>SENDER</element>
+//	    <element>RECEIVER</element>
+//	  </segment>
+//	  ...
+//	</edifact>
+
+type jsonSegment struct {
+	UNA      string        `json:"una,omitempty"`
+	Tag      string        `json:"tag,omitempty"`
+	Elements []interface{} `json:"elements,omitempty"`
+}
+
+// ToJSON returns the JSON representation of values.
+func ToJSON(values Values) ([]byte, error) {
+	segments := make([]jsonSegment, 0, len(values))
+	for i, v := range values {
+		switch v := v.(type) {
+		case Header:
+			segments = append(segments, jsonSegment{UNA: v[1]})
+		case Values:
+			segment := jsonSegment{Tag: SegmentName(v)}
+			for _, e := range v[1:] {
+				e, err := jsonValue(e)
+				if err != nil {
+					return nil, fmt.Errorf("edifact: segment %d: %s", i+1, err)
+				}
+				segment.Elements = append(segment.Elements, e)
+			}
+			segments = append(segments, segment)
+		default:
+			return nil, fmt.Errorf("edifact: unknown data type %T", v)
+		}
+	}
+
+	return json.Marshal(segments)
+}
+
+// returns a data element, composite or repetition as it's
+// represented in JSON.
+func jsonValue(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case []byte:
+		return string(v), nil
+	case Values:
+		out := make([]interface{}, len(v))
+		for i, v := range v {
+			var err error
+			if out[i], err = jsonValue(v); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unknown data type %T", v)
+}
+
+// FromJSON returns the values of the JSON representation.
+func FromJSON(data []byte) (Values, error) {
+	var segments []jsonSegment
+	if err := json.Unmarshal(data, &segments); err != nil {
+		return nil, err
+	}
+
+	values := make(Values, 0, len(segments))
+	for i, s := range segments {
+		if s.UNA != "" {
+			hdr := Header{"UNA", s.UNA}
+			if err := hdr.validate(); err != nil {
+				return nil, err
+			}
+			values = append(values, hdr)
+			continue
+		}
+
+		if s.Tag == "" {
+			return nil, fmt.Errorf("edifact: segment %d has no tag", i+1)
+		}
+
+		segment := Values{s.Tag}
+		for _, e := range s.Elements {
+			e, err := valueFromJSON(e)
+			if err != nil {
+				return nil, fmt.Errorf("edifact: segment %d: %s", i+1, err)
+			}
+			segment = append(segment, e)
+		}
+		values = append(values, segment)
+	}
+
+	return values, nil
+}
+
+func valueFromJSON(v interface{}) (interface{}, error) {
+	switch v := v.(type) {
+	case string:
+		return v, nil
+	case []interface{}:
+		out := make(Values, len(v))
+		for i, v := range v {
+			var err error
+			if out[i], err = valueFromJSON(v); err != nil {
+				return nil, err
+			}
+		}
+		return out, nil
+	}
+	return nil, fmt.Errorf("unexpected JSON value %v", v)
+}
+
+type xmlInterchange struct {
+	XMLName  xml.Name     `xml:"edifact"`
+	Segments []xmlSegment `xml:",any"`
+}
+
+// a segment, or a Header if its name is una.
+type xmlSegment struct {
+	XMLName  xml.Name
+	UNA      string       `xml:"value,attr,omitempty"`
+	Tag      string       `xml:"tag,attr,omitempty"`
+	Elements []xmlElement `xml:"element"`
+}
+
+// a data element. It's a composite if it has components, a
+// repetition if it has repetitions, and its text otherwise.
+type xmlElement struct {
+	Text        string          `xml:",chardata"`
+	Components  []string        `xml:"component"`
+	Repetitions []xmlRepetition `xml:"repetition"`
+}
+
+type xmlRepetition struct {
+	Text       string   `xml:",chardata"`
+	Components []string `xml:"component"`
+}
+
+// ToXML returns the XML representation of values.
+func ToXML(values Values) ([]byte, error) {
+	x := xmlInterchange{}
+	for i, v := range values {
+		switch v := v.(type) {
+		case Header:
+			x.Segments = append(x.Segments, xmlSegment{XMLName: xml.Name{Local: "una"}, UNA: v[1]})
+		case Values:
+			segment := xmlSegment{XMLName: xml.Name{Local: "segment"}, Tag: SegmentName(v)}
+			for _, e := range v[1:] {
+				e, err := xmlValue(e)
+				if err != nil {
+					return nil, fmt.Errorf("edifact: segment %d: %s", i+1, err)
+				}
+				segment.Elements = append(segment.Elements, e)
+			}
+			x.Segments = append(x.Segments, segment)
+		default:
+			return nil, fmt.Errorf("edifact: unknown data type %T", v)
+		}
+	}
+
+	return xml.MarshalIndent(x, "", "  ")
+}
+
+// returns a data element as it's represented in XML.
+func xmlValue(v interface{}) (xmlElement, error) {
+	switch v := v.(type) {
+	case string:
+		return xmlElement{Text: v}, nil
+	case []byte:
+		return xmlElement{Text: string(v)}, nil
+	case Values:
+		e := xmlElement{}
+		if isRepetition(v) {
+			for _, v := range v {
+				r, err := xmlValue(v)
+				if err != nil {
+					return e, err
+				}
+				if r.Repetitions != nil {
+					return e, fmt.Errorf("repetitions in a repetition")
+				}
+				e.Repetitions = append(e.Repetitions, xmlRepetition{Text: r.Text, Components: r.Components})
+			}
+			return e, nil
+		}
+
+		for _, v := range v {
+			c, ok := v.(string)
+			if b, isBytes := v.([]byte); isBytes {
+				c, ok = string(b), true
+			}
+			if !ok {
+				return e, fmt.Errorf("unknown component type %T", v)
+			}
+			e.Components = append(e.Components, c)
+		}
+		return e, nil
+	}
+	return xmlElement{}, fmt.Errorf("unknown data type %T", v)
+}
+
+// FromXML returns the values of the XML representation.
+func FromXML(data []byte) (Values, error) {
+	x := xmlInterchange{}
+	if err := xml.Unmarshal(data, &x); err != nil {
+		return nil, err
+	}
+
+	values := make(Values, 0, len(x.Segments))
+	for i, s := range x.Segments {
+		switch s.XMLName.Local {
+		case "una":
+			hdr := Header{"UNA", s.UNA}
+			if err := hdr.validate(); err != nil {
+				return nil, err
+			}
+			values = append(values, hdr)
+		case "segment":
+			if s.Tag == "" {
+				return nil, fmt.Errorf("edifact: segment %d has no tag", i+1)
+			}
+
+			segment := Values{s.Tag}
+			for _, e := range s.Elements {
+				switch {
+				case len(e.Repetitions) > 0:
+					reps := Values{}
+					for _, r := range e.Repetitions {
+						if len(r.Components) > 0 {
+							reps = append(reps, components(r.Components))
+						} else {
+							reps = append(reps, r.Text)
+						}
+					}
+					segment = append(segment, reps)
+				case len(e.Components) > 0:
+					segment = append(segment, components(e.Components))
+				default:
+					segment = append(segment, e.Text)
+				}
+			}
+			values = append(values, segment)
+		default:
+			return nil, fmt.Errorf("edifact: unknown XML element %s", s.XMLName.Local)
+		}
+	}
+
+	return values, nil
+}
+
+func components(c []string) Values {
+	out := make(Values, len(c))
+	for i, s := range c {
+		out[i] = s
+	}
+	return out
+}
@@ -0,0 +1,94 @@
+package edifact
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var convertTests = []Values{
+	M_IN1,
+	M_IN2,
+	M_IN3,
+	CRAZY_OUT1,
+	{Values{"TES", "", Values{"a", ""}}, Header{"UNA", "~|.?^!"}, Values{"FTX", Values{"x", "y"}}},
+	{Values{"X", Values{"a", Values{"b", "c"}}}},
+}
+
+func TestJSON(t *testing.T) {
+	for i, tt := range convertTests {
+		data, err := ToJSON(tt)
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+
+		out, err := FromJSON(data)
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+		if !reflect.DeepEqual(out, tt) {
+			t.Fatalf("%d. mismatch\nhave: %#+v\nwant: %#+v", i, out, tt)
+		}
+	}
+
+	data, err := ToJSON(M_IN3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `[{"una":":+./*'"},{"tag":"TES","elements":["hey","there","guy"]}]`
+	if string(data) != expected {
+		t.Fatalf("unexpected output: %s. want %s", data, expected)
+	}
+
+	if _, err := FromJSON([]byte(`[{"tag":"TES","elements":[1]}]`)); err == nil {
+		t.Fatal("expected an error for a number")
+	}
+}
+
+func TestXML(t *testing.T) {
+	for i, tt := range convertTests {
+		data, err := ToXML(tt)
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+
+		out, err := FromXML(data)
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+		if !reflect.DeepEqual(out, tt) {
+			t.Fatalf("%d. mismatch\nhave: %#+v\nwant: %#+v", i, out, tt)
+		}
+	}
+
+	data, err := ToXML(Values{Values{"COO", "A", Values{"B", "C"}, Values{Values{"07", "1"}, Values{"36", "2"}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `<edifact>
+  <segment tag="COO">
+    <element>A</element>
+    <element>
+      <component>B</component>
+      <component>C</component>
+    </element>
+    <element>
+      <repetition>
+        <component>07</component>
+        <component>1</component>
+      </repetition>
+      <repetition>
+        <component>36</component>
+        <component>2</component>
+      </repetition>
+    </element>
+  </segment>
+</edifact>`
+	if string(data) != expected {
+		t.Fatalf("unexpected output: %s. want %s", data, expected)
+	}
+
+	if _, err := FromXML([]byte(`<edifact><segment/></edifact>`)); err == nil || !strings.Contains(err.Error(), "no tag") {
+		t.Fatalf("expected an error for a segment without a tag, got %v", err)
+	}
+}
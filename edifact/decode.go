@@ -1,26 +1,130 @@
 package edifact
 
 import (
-	"errors"
+	"fmt"
+
 	"github.com/kdar/health/edifact/parse"
+	"github.com/kdar/health/edifact/token"
 )
 
-// unmarshals passed byte data
+// unmarshals passed byte data. The first value returned is always
+// the Header; DefaultHeader if the data has no UNA segment. The
+// text is returned as is, whatever the syntax level of the data;
+// see UnmarshalOptions.
 func Unmarshal(data []byte) (Values, error) {
-	root, err := parse.Parse(string(data))
+	return UnmarshalHeader(data, DefaultHeader)
+}
+
+// unmarshals passed byte data, using the delimiters in hdr
+// if the data has no UNA segment. Errors in the data are
+// returned as a *SyntaxError.
+func UnmarshalHeader(data []byte, hdr Header) (Values, error) {
+	return unmarshal(data, hdr, QuirksRelayHealth, &state{})
+}
+
+// Options for UnmarshalOptions.
+type DecodeOptions struct {
+	// The delimiters to use if the data has no UNA segment.
+	// DefaultHeader if empty.
+	Header Header
+	// The syntax level of the data. If empty, it's taken from the
+	// UNB (or UIB) segment, and the segments before it aren't
+	// checked.
+	Charset Charset
+	// What to do with characters that aren't in the Charset.
+	CharsetMode CharsetMode
+	// The deviations from the standard to put up with, e.g.
+	// &QuirksStrict. QuirksRelayHealth if nil.
+	Quirks *Quirks
+}
+
+// returns the Quirks in opts.
+func (opts DecodeOptions) quirks() Quirks {
+	if opts.Quirks == nil {
+		return QuirksRelayHealth
+	}
+	return *opts.Quirks
+}
+
+// unmarshals passed byte data like UnmarshalHeader, using the passed
+// options. UNOC data is converted from ISO 8859-1 to UTF-8, whatever
+// the CharsetMode. Characters outside of the charset are returned as
+// a *SyntaxError with an Err of parse.ErrInvalidCharset, or as
+// CharsetErrors in CharsetReport mode.
+func UnmarshalOptions(data []byte, opts DecodeOptions) (Values, error) {
+	hdr := opts.Header
+	if hdr == (Header{}) {
+		hdr = DefaultHeader
+	}
+
+	s := &state{
+		charset:      opts.Charset,
+		detect:       opts.Charset == "",
+		checkCharset: opts.CharsetMode != CharsetIgnore,
+	}
+	values, err := unmarshal(data, hdr, opts.quirks(), s)
+	if err != nil {
+		return values, err
+	}
+
+	if len(s.invalid) > 0 {
+		if opts.CharsetMode == CharsetEnforce {
+			return nil, s.invalid[0]
+		}
+		return values, CharsetErrors(s.invalid)
+	}
+
+	return values, nil
+}
+
+func unmarshal(data []byte, hdr Header, quirks Quirks, s *state) (Values, error) {
+	if err := hdr.validate(); err != nil {
+		return nil, err
+	}
+
+	text := string(data)
+	root, err := parse.ParseOptions(text, hdr[1], quirks)
 	if err != nil {
 		return nil, err
 	}
 
-	s := &state{}
+	s.text = text
 	values := s.walk(root)
 
+	if _, ok := firstHeader(values); !ok {
+		values = append(Values{hdr}, values...)
+	}
+
 	return values, s.err
 }
 
+// returns the first value if it's a Header.
+func firstHeader(values Values) (Header, bool) {
+	if len(values) == 0 {
+		return Header{}, false
+	}
+	hdr, ok := values[0].(Header)
+	return hdr, ok
+}
+
 // our state for walking the node tree
 type state struct {
 	err error
+
+	// where we are, for errors
+	text      string
+	segment   int
+	pos       token.Pos
+	tag       string
+	element   int
+	component int
+
+	// the syntax level, whether to take it from the UNB
+	// segment, and whether to check the text is in it
+	charset      Charset
+	detect       bool
+	checkCharset bool
+	invalid      []*SyntaxError
 }
 
 // walks a parsed node tree and returns values
@@ -33,20 +137,76 @@ func (s *state) walk(node parse.Node) Values {
 			ret = append(ret, s.walk(node)...)
 		}
 	case *parse.HeaderNode:
+		s.segment++
 		ret = append(ret, Header{node.SegmentName.String(), node.Text.String()})
 	case *parse.SegmentNode:
-		ret = append(ret, s.walk(node.List))
+		s.segment++
+		s.pos = node.Pos
+		s.tag = ""
+		if len(node.List.Nodes) > 0 && node.List.Nodes[0] != nil {
+			s.tag = node.List.Nodes[0].String()
+		}
+
+		var segment Values
+		for i, node := range node.List.Nodes {
+			s.element, s.component = i, 0
+			segment = append(segment, s.walk(node)...)
+		}
+		ret = append(ret, segment)
+
+		if s.detect && (s.tag == UNB || s.tag == UIB) {
+			s.charset = syntaxIdentifier(segment)
+			s.detect = false
+		}
 	case *parse.DataNode:
 		ret = append(ret, s.walk(node.Node)...)
 	case *parse.ComponentNode:
-		ret = append(ret, s.walk(node.List))
+		var component Values
+		for i, node := range node.List.Nodes {
+			s.component = i + 1
+			component = append(component, s.walk(node)...)
+		}
+		ret = append(ret, component)
 	case *parse.RepetitionNode:
 		ret = append(ret, s.walk(node.List))
 	case *parse.TextNode:
-		ret = append(ret, string(node.Text))
+		ret = append(ret, s.decodeText(node.Text))
 	default:
-		s.err = errors.New("unknown node")
+		if s.err == nil {
+			s.err = s.errorf(parse.ErrInvalidNode, "unknown node: %T", node)
+		}
 	}
 
 	return ret
 }
+
+// decodes text from the syntax level, and checks it's all in it.
+func (s *state) decodeText(b []byte) string {
+	text := string(b)
+	if s.charset == UNOC {
+		text = latin1ToUTF8(b)
+	}
+
+	if s.checkCharset {
+		if r, ok := s.charset.invalid(text); ok {
+			s.invalid = append(s.invalid, s.errorf(parse.ErrInvalidCharset, "%q is not in %s", r, s.charset))
+		}
+	}
+
+	return text
+}
+
+// returns a SyntaxError for where we are in the tree.
+func (s *state) errorf(kind error, format string, args ...interface{}) *SyntaxError {
+	err := &SyntaxError{
+		Err:       kind,
+		Msg:       fmt.Sprintf(format, args...),
+		Offset:    int(s.pos),
+		Segment:   s.segment,
+		Tag:       s.tag,
+		Element:   s.element,
+		Component: s.component,
+	}
+	err.Line, err.Column = parse.LineColumn(s.text, err.Offset)
+	return err
+}
@@ -2,8 +2,11 @@ package edifact
 
 import (
 	//"fmt"
+	"errors"
 	"reflect"
 	"testing"
+
+	"github.com/kdar/health/edifact/parse"
 )
 
 var (
@@ -70,3 +73,116 @@ func TestUnmarshal(t *testing.T) {
 		}
 	}
 }
+
+var unmarshalHeaderTests = []struct {
+	in  string
+	hdr Header
+	out Values
+}{
+	{
+		"UNB+UNOA:1+S+R'UNZ+0+1'",
+		DefaultHeader,
+		Values{DefaultHeader, Values{"UNB", Values{"UNOA", "1"}, "S", "R"}, Values{"UNZ", "0", "1"}},
+	},
+	// without a UNA, a space is just text
+	{
+		"UNB+UNOA:1+CVS PHARMACY+R'UNZ+0+1'",
+		DefaultHeader,
+		Values{DefaultHeader, Values{"UNB", Values{"UNOA", "1"}, "CVS PHARMACY", "R"}, Values{"UNZ", "0", "1"}},
+	},
+	{
+		"\nUIB|UNOA~0'",
+		Header{"UNA", "~|.?^'"},
+		Values{Header{"UNA", "~|.?^'"}, Values{"UIB", Values{"UNOA", "0"}}},
+	},
+	// the UNA segment wins over the passed header
+	{
+		"UNA:+./*'TES+hey'",
+		Header{"UNA", "~|.?^'"},
+		Values{Header{"UNA", ":+./*'"}, Values{"TES", "hey"}},
+	},
+}
+
+func TestUnmarshalHeader(t *testing.T) {
+	for i, tt := range unmarshalHeaderTests {
+		out, err := UnmarshalHeader([]byte(tt.in), tt.hdr)
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+
+		if !reflect.DeepEqual(out, tt.out) {
+			t.Fatalf("%d. mismatch\nhave: %#+v\nwant: %#+v", i, out, tt.out)
+		}
+	}
+
+	if _, err := UnmarshalHeader([]byte("TES+hey'"), Header{"UNA", ":+"}); err == nil {
+		t.Fatal("expected an error for an invalid header")
+	}
+}
+
+var syntaxErrorTests = []struct {
+	in  string
+	out SyntaxError
+}{
+	{
+		"UNA:+",
+		SyntaxError{Err: parse.ErrUnexpectedEOF, Offset: 5, Line: 1, Column: 6, Segment: 1, Tag: "UNA"},
+	},
+	{
+		"UNA:+.? 'TES+a:b",
+		SyntaxError{Err: parse.ErrUnexpectedEOF, Offset: 16, Line: 1, Column: 17, Segment: 2, Tag: "TES", Element: 1, Component: 2},
+	},
+	{
+		"UNA:+.? ~\nTES+a~\nABC+x:y+z",
+		SyntaxError{Err: parse.ErrUnexpectedEOF, Offset: 26, Line: 3, Column: 10, Segment: 3, Tag: "ABC", Element: 2},
+	},
+	{
+		"TEs+a'",
+		SyntaxError{Err: parse.ErrInvalidCharacter, Offset: 2, Line: 1, Column: 3, Segment: 1},
+	},
+}
+
+func TestUnmarshalSyntaxError(t *testing.T) {
+	for i, tt := range syntaxErrorTests {
+		_, err := Unmarshal([]byte(tt.in))
+		checkSyntaxError(t, i, err, tt.out)
+	}
+}
+
+func checkSyntaxError(t *testing.T, i int, err error, expected SyntaxError) {
+	var serr *SyntaxError
+	if !errors.As(err, &serr) {
+		t.Fatalf("%d. expected a *SyntaxError, got %#v", i, err)
+	}
+
+	if !errors.Is(err, expected.Err) {
+		t.Fatalf("%d. expected %v, got %v", i, expected.Err, serr.Err)
+	}
+
+	have := *serr
+	have.Msg = ""
+	if have != expected {
+		t.Fatalf("%d. mismatch\nhave: %#+v\nwant: %#+v", i, have, expected)
+	}
+}
+
+func TestLineColumn(t *testing.T) {
+	text := "ab\ncé\nd"
+	tests := []struct {
+		offset, line, column int
+	}{
+		{0, 1, 1},
+		{2, 1, 3},
+		{3, 2, 1},
+		{6, 2, 3},
+		{7, 3, 1},
+		{100, 3, 2},
+	}
+
+	for i, tt := range tests {
+		line, column := parse.LineColumn(text, tt.offset)
+		if line != tt.line || column != tt.column {
+			t.Fatalf("%d. expected %d:%d, got %d:%d", i, tt.line, tt.column, line, column)
+		}
+	}
+}
@@ -0,0 +1,231 @@
+// Package directory loads EDIFACT directory definitions (data elements,
+// composites, segments and messages) and validates decoded messages
+// against them.
+//
+// A directory is the part of the standard the edifact package doesn't
+// enforce: which segments a message has and in what order, which data
+// elements are mandatory, how many times they can repeat, and their
+// format (alphabetic, numeric or alphanumeric, and length).
+package directory
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+// Status says whether an entry must be sent.
+type Status string
+
+const (
+	Mandatory   Status = "M"
+	Conditional Status = "C"
+)
+
+// A Format is the representation of a data element, e.g. an..35 or n3.
+type Format struct {
+	Type string // a (alphabetic), n (numeric) or an (alphanumeric)
+	Min  int    // the minimum length, or 0
+	Max  int    // the maximum length, or 0 for no limit
+}
+
+var formatRegexp = regexp.MustCompile(`^(an|a|n)(\.\.)?(\d+)$`)
+
+// ParseFormat parses a format like an..35 (up to 35 alphanumeric
+// characters) or n3 (exactly 3 digits).
+func ParseFormat(s string) (Format, error) {
+	m := formatRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return Format{}, fmt.Errorf("invalid format %q", s)
+	}
+
+	f := Format{Type: m[1]}
+	f.Max, _ = strconv.Atoi(m[3])
+	if m[2] == "" {
+		f.Min = f.Max
+	}
+	return f, nil
+}
+
+// UnmarshalXMLAttr implements xml.UnmarshalerAttr.
+func (f *Format) UnmarshalXMLAttr(attr xml.Attr) (err error) {
+	*f, err = ParseFormat(attr.Value)
+	return err
+}
+
+func (f Format) String() string {
+	switch {
+	case f.Max == 0:
+		return f.Type
+	case f.Min == f.Max:
+		return fmt.Sprintf("%s%d", f.Type, f.Max)
+	}
+	return fmt.Sprintf("%s..%d", f.Type, f.Max)
+}
+
+// Directory is a set of EDIFACT definitions, e.g. those of D.96A.
+type Directory struct {
+	Version      string        `xml:"Version,attr"`
+	Release      string        `xml:"Release,attr"`
+	DataElements []DataElement `xml:"DataElement"`
+	Composites   []Composite   `xml:"Composite"`
+	Segments     []Segment     `xml:"Segment"`
+	Messages     []Message     `xml:"Message"`
+}
+
+// DataElement is a simple data element.
+type DataElement struct {
+	ID     string `xml:"Id,attr"`
+	Name   string `xml:"Name,attr"`
+	Format Format `xml:"Format,attr"`
+}
+
+// Composite is a composite data element.
+type Composite struct {
+	ID         string      `xml:"Id,attr"`
+	Name       string      `xml:"Name,attr"`
+	Components []Reference `xml:"Component"`
+}
+
+// Segment is a segment definition.
+type Segment struct {
+	Tag      string      `xml:"Tag,attr"`
+	Name     string      `xml:"Name,attr"`
+	Elements []Reference `xml:"Element"`
+}
+
+// Reference is a data element or composite used in a segment, or a
+// data element used in a composite, in order.
+type Reference struct {
+	ID     string `xml:"Id,attr"`
+	Status Status `xml:"Status,attr"`
+	// how many times the element can repeat; 0 is the same as 1
+	Repeat int `xml:"Repeat,attr"`
+}
+
+// Message is the structure of a message type.
+type Message struct {
+	Type string `xml:"Type,attr"`
+	Name string `xml:"Name,attr"`
+	// the segments and segment groups of the message, in order
+	Entries []Entry `xml:",any"`
+}
+
+// Entry is a Segment or a Group in a message.
+type Entry struct {
+	XMLName xml.Name
+	Tag     string `xml:"Tag,attr"`  // of a Segment
+	Name    string `xml:"Name,attr"` // of a Group, e.g. SG2
+	Status  Status `xml:"Status,attr"`
+	Repeat  int    `xml:"Repeat,attr"`
+
+	// Entries of a Group
+	Entries []Entry `xml:",any"`
+}
+
+// IsGroup reports whether the entry is a segment group.
+func (e Entry) IsGroup() bool {
+	return e.XMLName.Local == "Group"
+}
+
+// trigger returns the tag of the first segment of a group, which
+// starts every repetition of it.
+func (e Entry) trigger() string {
+	if !e.IsGroup() {
+		return e.Tag
+	}
+	if len(e.Entries) == 0 {
+		return ""
+	}
+	return e.Entries[0].trigger()
+}
+
+// Parse reads a directory.
+func Parse(r io.Reader) (*Directory, error) {
+	d := &Directory{}
+	if err := xml.NewDecoder(r).Decode(d); err != nil {
+		return nil, err
+	}
+
+	for i := range d.Messages {
+		d.Messages[i].Entries = prune(d.Messages[i].Entries)
+	}
+
+	if len(d.Segments) == 0 {
+		return nil, fmt.Errorf("directory has no segments")
+	}
+
+	return d, nil
+}
+
+// ParseFile reads a directory from the named file.
+func ParseFile(name string) (*Directory, error) {
+	fp, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	return Parse(fp)
+}
+
+// prune drops everything that isn't a Segment or Group, since the any
+// tag picks them up too.
+func prune(entries []Entry) []Entry {
+	var out []Entry
+	for _, e := range entries {
+		switch e.XMLName.Local {
+		case "Segment":
+			e.Entries = nil
+		case "Group":
+			e.Entries = prune(e.Entries)
+		default:
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
+
+// DataElement returns the simple data element with the id, or nil.
+func (d *Directory) DataElement(id string) *DataElement {
+	for i := range d.DataElements {
+		if d.DataElements[i].ID == id {
+			return &d.DataElements[i]
+		}
+	}
+	return nil
+}
+
+// Composite returns the composite with the id, or nil.
+func (d *Directory) Composite(id string) *Composite {
+	for i := range d.Composites {
+		if d.Composites[i].ID == id {
+			return &d.Composites[i]
+		}
+	}
+	return nil
+}
+
+// Segment returns the segment with the tag, or nil.
+func (d *Directory) Segment(tag string) *Segment {
+	for i := range d.Segments {
+		if d.Segments[i].Tag == tag {
+			return &d.Segments[i]
+		}
+	}
+	return nil
+}
+
+// Message returns the message with the type, e.g. ORDERS, or nil.
+func (d *Directory) Message(typ string) *Message {
+	for i := range d.Messages {
+		if d.Messages[i].Type == typ {
+			return &d.Messages[i]
+		}
+	}
+	return nil
+}
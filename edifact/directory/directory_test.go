@@ -0,0 +1,203 @@
+package directory
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kdar/health/edifact"
+)
+
+const (
+	testUNH = "UNH+M1+ORDERS:D:96A:UN"
+	testBGM = "BGM+220+PO1+9"
+	testDTM = "DTM+137:20130113:102"
+	testNAD = "NAD+BY+5412345000013::9"
+	testLIN = "LIN+1++4000862141404:EN"
+	testQTY = "QTY+21:48"
+	testUNS = "UNS+S"
+	testUNT = "UNT+8+M1"
+)
+
+var validateTests = []struct {
+	segments []string
+	errs     []string
+}{
+	{
+		[]string{testUNH, testBGM, testDTM, testNAD, testLIN, testQTY, testUNS, testUNT},
+		nil,
+	},
+	{
+		// SG2 is conditional, and groups repeat
+		[]string{testUNH, testBGM, testDTM, testDTM, testLIN, testQTY, testLIN, testUNS, testUNT},
+		nil,
+	},
+	{
+		[]string{testUNH, testDTM, testNAD, testLIN, testUNS, testUNT},
+		[]string{"segment 2 BGM: missing"},
+	},
+	{
+		[]string{testUNH, testBGM, "DTM+:20130113:102", testLIN, "QTY+21:4x", testUNS, testUNT},
+		[]string{"segment 3 DTM, element 1.1: missing: 2005", `segment 5 QTY, element 1.2: invalid format: "4x" is not n..15`},
+	},
+	{
+		[]string{testUNH, "BGM+220+" + strings.Repeat("X", 36) + "+9+AB+X", testDTM, testLIN, "UNS+SS", testUNT},
+		[]string{"segment 2 BGM, element 2: too long: 36 > 35", "segment 2 BGM, element 5: unexpected", "segment 5 UNS, element 1: too long: 2 > 1"},
+	},
+	{
+		[]string{testUNH, testBGM, "FTX+AAI", testDTM, testLIN, testUNS, testUNT},
+		[]string{"segment 3 FTX: unexpected"},
+	},
+	{
+		[]string{testUNH, testBGM, testDTM, testNAD, testUNS, testUNT},
+		[]string{"segment 5 LIN: missing: SG25"},
+	},
+	{
+		[]string{testUNH, testBGM, testDTM, testLIN, testQTY, testQTY, testQTY, testUNS, testUNS, testUNT},
+		[]string{"segment 7 QTY: too many: more than 2", "segment 9 UNS: too many: more than 1"},
+	},
+	{
+		// out of order, which is skipped
+		[]string{testUNH, testBGM, testLIN, testDTM, testUNS, testUNT},
+		[]string{"segment 3 DTM: missing", "segment 4 DTM: unexpected"},
+	},
+	{
+		// leaving a group for the one enclosing it
+		[]string{testUNH, testBGM, testDTM, testLIN, testQTY, testDTM, testUNS, testUNT},
+		[]string{"segment 6 DTM: unexpected"},
+	},
+	{
+		// repeating data elements
+		[]string{testUNH, testBGM, "ZRP+1*2*3+A:EN*B", testDTM, testLIN, testUNS, testUNT},
+		nil,
+	},
+	{
+		[]string{testUNH, testBGM, "ZRP+1*2+A", testDTM, testLIN, testUNS, testUNT},
+		nil,
+	},
+	{
+		[]string{testUNH, testBGM, "ZRP+1*2*3*4+A:EN*B*C:SA+X*Y", testDTM, testLIN, testUNS, testUNT},
+		[]string{"segment 3 ZRP, element 1: too many: 4 > 3", "segment 3 ZRP, element 2: too many: 3 > 2", "segment 3 ZRP, element 3: too many: 2 > 1"},
+	},
+	{
+		[]string{testUNH, testBGM, "ZRP+1*2:3*x", testDTM, testLIN, testUNS, testUNT},
+		[]string{"segment 3 ZRP, element 1.2: unexpected", `segment 3 ZRP, element 1: invalid format: "x" is not n..6`},
+	},
+	{
+		[]string{"UNH+M1+INVOIC:D:96A:UN", testBGM, testUNT},
+		[]string{"segment 1 UNH, element 2.1: unknown: INVOIC"},
+	},
+	{
+		[]string{"UIH+SCRIPT:008:001:NEWRX+M1", testBGM, "UIT+M1+3"},
+		[]string{"segment 1 UIH, element 1.1: unknown: SCRIPT"},
+	},
+	{
+		[]string{testBGM, testUNT},
+		[]string{"segment 1 BGM: unexpected"},
+	},
+}
+
+func parseMessage(t *testing.T, segments []string) edifact.Values {
+	// with a repetition delimiter, which the default UNA doesn't have
+	out, err := edifact.Unmarshal([]byte("UNA:+.?*'" + strings.Join(segments, "'") + "'"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the Header
+	return out[1:]
+}
+
+func errorStrings(errs []*Error) []string {
+	var out []string
+	for _, err := range errs {
+		out = append(out, err.Error())
+	}
+	return out
+}
+
+func TestValidate(t *testing.T) {
+	d, err := ParseFile("testdata/d96a_orders.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tt := range validateTests {
+		errs := d.Validate(parseMessage(t, tt.segments))
+		if s := errorStrings(errs); !reflect.DeepEqual(s, tt.errs) {
+			t.Fatalf("%d. expected %q, got %q", i, tt.errs, s)
+		}
+	}
+}
+
+func TestErrorCode(t *testing.T) {
+	tests := []struct {
+		err  Error
+		code edifact.ErrorCode
+	}{
+		{Error{Kind: Missing}, edifact.ErrorMissing},
+		{Error{Kind: Unexpected}, edifact.ErrorNotSupported},
+		{Error{Kind: Unexpected, Element: 5}, edifact.ErrorTooManyConstituents},
+		{Error{Kind: TooLong}, edifact.ErrorDataElementTooLong},
+		{Error{Kind: InvalidFormat}, edifact.ErrorInvalidCharacterType},
+	}
+
+	for i, tt := range tests {
+		if code := tt.err.Code(); code != tt.code {
+			t.Fatalf("%d. expected %s, got %s", i, tt.code, code)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	d, err := ParseFile("testdata/d96a_orders.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	m := d.Message("ORDERS")
+	if m == nil || len(m.Entries) != 8 {
+		t.Fatalf("unexpected message: %+v", m)
+	}
+
+	group := m.Entries[5]
+	if !group.IsGroup() || group.Name != "SG25" || group.Repeat != 200 || len(group.Entries) != 2 {
+		t.Fatalf("unexpected group: %+v", group)
+	}
+
+	if s := d.Segment("ZRP"); s == nil || s.Elements[0].Repeat != 3 || s.Elements[2].Repeat != 0 {
+		t.Fatalf("unexpected segment: %+v", s)
+	}
+
+	if f := d.DataElement("6060").Format; f != (Format{Type: "n", Max: 15}) {
+		t.Fatalf("unexpected format: %+v", f)
+	}
+
+	if _, err := Parse(strings.NewReader("<Directory/>")); err == nil {
+		t.Fatal("expected an error for a directory without segments")
+	}
+}
+
+var formatTests = []struct {
+	in  string
+	out Format
+}{
+	{"an..35", Format{Type: "an", Max: 35}},
+	{"n3", Format{Type: "n", Min: 3, Max: 3}},
+	{"a1", Format{Type: "a", Min: 1, Max: 1}},
+}
+
+func TestParseFormat(t *testing.T) {
+	for i, tt := range formatTests {
+		f, err := ParseFormat(tt.in)
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+		if f != tt.out || f.String() != tt.in {
+			t.Fatalf("%d. expected %+v, got %+v (%s)", i, tt.out, f, f)
+		}
+	}
+
+	if _, err := ParseFormat("x..3"); err == nil {
+		t.Fatal("expected an error for an invalid format")
+	}
+}
@@ -0,0 +1,408 @@
+package directory
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/kdar/health/edifact"
+)
+
+// Kind is what's wrong with a segment or data element.
+type Kind string
+
+const (
+	// a mandatory segment, group, data element or component isn't there
+	Missing Kind = "missing"
+	// repeats more times than the directory allows
+	TooMany Kind = "too many"
+	// a segment that isn't in the message, or is out of order, or a
+	// data element or component past the end of its definition
+	Unexpected Kind = "unexpected"
+	// longer than the format allows
+	TooLong Kind = "too long"
+	// shorter than the format allows
+	TooShort Kind = "too short"
+	// not alphabetic or numeric when the format says it should be
+	InvalidFormat Kind = "invalid format"
+	// the message type, segment or data element isn't in the directory
+	Unknown Kind = "unknown"
+)
+
+// Error is a deviation from the directory.
+type Error struct {
+	Kind      Kind
+	Segment   int    // the position of the segment in the message, starting at 1 for the UNH
+	Tag       string // the segment tag
+	Element   int    // the position of the data element, or 0 for the segment itself
+	Component int    // the position of the component, or 0
+	// Detail explains the error, e.g. "36 > 35"
+	Detail string
+}
+
+func (e *Error) Error() string {
+	s := fmt.Sprintf("segment %d %s", e.Segment, e.Tag)
+	if e.Element > 0 {
+		s += fmt.Sprintf(", element %d", e.Element)
+		if e.Component > 0 {
+			s += fmt.Sprintf(".%d", e.Component)
+		}
+	}
+	s += ": " + string(e.Kind)
+	if e.Detail != "" {
+		s += ": " + e.Detail
+	}
+	return s
+}
+
+// Code returns the CONTRL syntax error code for the error, for
+// reporting it back to the sender in an edifact.SegmentReport.
+func (e *Error) Code() edifact.ErrorCode {
+	switch e.Kind {
+	case Missing:
+		return edifact.ErrorMissing
+	case TooMany:
+		return edifact.ErrorTooManyRepetitions
+	case Unexpected:
+		if e.Element > 0 {
+			return edifact.ErrorTooManyConstituents
+		}
+		return edifact.ErrorNotSupported
+	case TooLong:
+		return edifact.ErrorDataElementTooLong
+	case TooShort:
+		return edifact.ErrorDataElementTooShort
+	case InvalidFormat:
+		return edifact.ErrorInvalidCharacterType
+	}
+	return edifact.ErrorNotSupported
+}
+
+// Validate checks a message, the segments from its UNH (or UIH) up to
+// and including its UNT (or UIT) like edifact.Decoder.Message returns,
+// against the directory. It returns an error for every deviation, in
+// message order.
+//
+// The segment table is followed the way a receiver reads a message:
+// each segment is looked up from where the one before it was, first
+// in the segment group it's in and then in the groups enclosing it,
+// and the trigger segment of a group starts another repetition of it.
+// Mandatory segments and groups passed over are Missing. A segment
+// that can't be found that way, because the message type doesn't
+// have it or it's out of order, is Unexpected and otherwise ignored.
+func (d *Directory) Validate(message edifact.Values) []*Error {
+	var segments []edifact.Values
+	for _, value := range message {
+		if segment, ok := value.(edifact.Values); ok {
+			segments = append(segments, segment)
+		}
+	}
+
+	v := &validator{d: d}
+	def, err := d.messageDef(segments)
+	if err != nil {
+		v.add(err)
+		return v.errs
+	}
+
+	v.groups = []*openGroup{{entries: def.Entries}}
+	for i, segment := range segments {
+		v.next(segment, i+1)
+	}
+	v.leave(0, len(segments)+1)
+
+	return v.errs
+}
+
+// returns the definition of the message type in the UNH or UIH
+// segment that starts the segments.
+func (d *Directory) messageDef(segments []edifact.Values) (*Message, *Error) {
+	var start edifact.Values
+	if len(segments) > 0 {
+		start = segments[0]
+	}
+
+	// the message identifier, whose first component is the type
+	err := &Error{Kind: Unknown, Segment: 1, Tag: edifact.SegmentName(start), Component: 1}
+	switch err.Tag {
+	case edifact.UNH:
+		err.Element = 2
+	case edifact.UIH:
+		err.Element = 1
+	default:
+		err.Kind, err.Component = Unexpected, 0
+		return nil, err
+	}
+
+	typ := edifact.Text(edifact.Element(start, err.Element))
+	if def := d.Message(typ); def != nil {
+		return def, nil
+	}
+	err.Detail = typ
+	return nil, err
+}
+
+// openGroup is a segment group the message is in, or the message
+// itself at the bottom. A group is open from its trigger segment
+// until a segment is found in a group enclosing it.
+type openGroup struct {
+	entries []Entry
+	trigger bool // whether entries[0] is a trigger segment
+	// the entry the last segment was found at, and how many times in
+	// a row, or 0 if none has been found yet
+	at, count int
+}
+
+type validator struct {
+	d      *Directory
+	groups []*openGroup
+	errs   []*Error
+}
+
+func (v *validator) add(err *Error) {
+	v.errs = append(v.errs, err)
+}
+
+// next matches the segment at pos against the message definition,
+// then checks its data elements.
+func (v *validator) next(segment edifact.Values, pos int) {
+	tag := edifact.SegmentName(segment)
+	depth, at, ok := v.find(tag)
+	if !ok {
+		v.add(&Error{Kind: Unexpected, Segment: pos, Tag: tag})
+		return
+	}
+
+	v.leave(depth+1, pos)
+	g := v.groups[depth]
+	if at != g.at || g.count == 0 {
+		v.skip(g, at, pos)
+		g.at, g.count = at, 0
+	}
+	g.count++
+
+	e := g.entries[at]
+	if max := maxRepeat(e.Repeat); g.count == max+1 {
+		detail := fmt.Sprintf("more than %d", max)
+		if e.IsGroup() {
+			detail = e.Name + ": " + detail
+		}
+		v.add(&Error{Kind: TooMany, Segment: pos, Tag: tag, Detail: detail})
+	}
+
+	// the trigger segment opens the group, and any group it starts
+	for ; e.IsGroup(); e = e.Entries[0] {
+		v.groups = append(v.groups, &openGroup{entries: e.Entries, trigger: true, count: 1})
+	}
+
+	v.checkSegment(segment, pos)
+}
+
+// find returns the open group, innermost first, and the entry in it
+// from its current one on that the tag is found at.
+func (v *validator) find(tag string) (depth, at int, ok bool) {
+	for depth = len(v.groups) - 1; depth >= 0; depth-- {
+		g := v.groups[depth]
+		for at = g.at; at < len(g.entries); at++ {
+			// another trigger segment is the next repetition of the
+			// group, which the enclosing one has
+			if at == 0 && g.trigger {
+				continue
+			}
+			if g.entries[at].trigger() == tag {
+				return depth, at, true
+			}
+		}
+	}
+	return 0, 0, false
+}
+
+// leave closes the groups from depth up, reporting the mandatory
+// entries they have left as Missing at pos.
+func (v *validator) leave(depth int, pos int) {
+	for len(v.groups) > depth {
+		g := v.groups[len(v.groups)-1]
+		v.skip(g, len(g.entries), pos)
+		v.groups = v.groups[:len(v.groups)-1]
+	}
+}
+
+// skip reports the mandatory entries of g that weren't found before
+// entry at as Missing at pos.
+func (v *validator) skip(g *openGroup, at int, pos int) {
+	from := g.at
+	if g.count > 0 {
+		from++
+	}
+
+	for _, e := range g.entries[from:at] {
+		if e.Status != Mandatory {
+			continue
+		}
+		err := &Error{Kind: Missing, Segment: pos, Tag: e.trigger()}
+		if e.IsGroup() {
+			err.Detail = e.Name
+		}
+		v.add(err)
+	}
+}
+
+// checkSegment checks the data elements of the segment at pos.
+func (v *validator) checkSegment(segment edifact.Values, pos int) {
+	tag := edifact.SegmentName(segment)
+	def := v.d.Segment(tag)
+	if def == nil {
+		v.add(&Error{Kind: Unknown, Segment: pos, Tag: tag})
+		return
+	}
+
+	for i, ref := range def.Elements {
+		v.element(&Error{Segment: pos, Tag: tag, Element: i + 1}, ref, edifact.Element(segment, i+1))
+	}
+
+	for i := len(def.Elements) + 1; i < len(segment); i++ {
+		if !edifact.IsEmpty(segment[i]) {
+			v.add(&Error{Kind: Unexpected, Segment: pos, Tag: tag, Element: i})
+		}
+	}
+}
+
+// element checks a data element, at the position in at.
+func (v *validator) element(at *Error, ref Reference, data interface{}) {
+	composite := v.d.Composite(ref.ID)
+
+	// the repetitions "a*b" of a simple data element decode the same
+	// as the composite "a:b"
+	reps := edifact.Repetitions(data, composite != nil)
+
+	if len(reps) == 0 {
+		if ref.Status == Mandatory {
+			v.errorf(at, Missing, "%s", ref.ID)
+		}
+		return
+	}
+
+	if len(reps) > maxRepeat(ref.Repeat) {
+		v.errorf(at, TooMany, "%d > %d", len(reps), maxRepeat(ref.Repeat))
+	}
+
+	for _, rep := range reps {
+		if composite != nil {
+			v.components(at, composite, rep)
+			continue
+		}
+
+		// a composite where a simple data element should be
+		if values, ok := rep.(edifact.Values); ok && len(values) > 1 {
+			for i := 1; i < len(values); i++ {
+				if !edifact.IsEmpty(values[i]) {
+					c := *at
+					c.Component = i + 1
+					v.errorf(&c, Unexpected, "")
+				}
+			}
+		}
+		v.value(at, ref.ID, edifact.Text(rep))
+	}
+}
+
+// components checks the components of a composite data element.
+func (v *validator) components(at *Error, def *Composite, data interface{}) {
+	for i, ref := range def.Components {
+		c := *at
+		c.Component = i + 1
+
+		value := edifact.Component(data, i+1)
+		if edifact.IsEmpty(value) {
+			if ref.Status == Mandatory {
+				v.errorf(&c, Missing, "%s", ref.ID)
+			}
+			continue
+		}
+		v.value(&c, ref.ID, edifact.Text(value))
+	}
+
+	values, _ := data.(edifact.Values)
+	for i := len(def.Components); i < len(values); i++ {
+		if !edifact.IsEmpty(values[i]) {
+			c := *at
+			c.Component = i + 1
+			v.errorf(&c, Unexpected, "")
+		}
+	}
+}
+
+// value checks the text of a simple data element against its format.
+func (v *validator) value(at *Error, id, s string) {
+	def := v.d.DataElement(id)
+	if def == nil {
+		v.errorf(at, Unknown, "%s", id)
+		return
+	}
+
+	f := def.Format
+	n := utf8.RuneCountInString(s)
+
+	switch f.Type {
+	case "n":
+		if !isNumeric(s) {
+			v.errorf(at, InvalidFormat, "%q is not %s", s, f)
+			return
+		}
+		// the sign, decimal mark and exponent don't count
+		n = countDigits(s)
+	case "a":
+		if strings.IndexFunc(s, unicode.IsDigit) >= 0 {
+			v.errorf(at, InvalidFormat, "%q is not %s", s, f)
+			return
+		}
+	}
+
+	switch {
+	case f.Max > 0 && n > f.Max:
+		v.errorf(at, TooLong, "%d > %d", n, f.Max)
+	case f.Min > 0 && n < f.Min:
+		v.errorf(at, TooShort, "%d < %d", n, f.Min)
+	}
+}
+
+func (v *validator) errorf(at *Error, kind Kind, format string, args ...interface{}) {
+	err := *at
+	err.Kind = kind
+	err.Detail = fmt.Sprintf(format, args...)
+	v.add(&err)
+}
+
+// the most times something that repeats r times can be there.
+func maxRepeat(r int) int {
+	if r < 1 {
+		return 1
+	}
+	return r
+}
+
+// isNumeric reports whether s is an EDIFACT number.
+func isNumeric(s string) bool {
+	_, err := edifact.ParseNumber(s)
+	return err == nil
+}
+
+func isNotDigit(r rune) bool {
+	return r < '0' || r > '9'
+}
+
+// counts the digits before the exponent.
+func countDigits(s string) int {
+	if i := strings.IndexAny(s, "Ee"); i >= 0 {
+		s = s[:i]
+	}
+
+	n := 0
+	for _, r := range s {
+		if !isNotDigit(r) {
+			n++
+		}
+	}
+	return n
+}
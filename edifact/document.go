@@ -0,0 +1,337 @@
+package edifact
+
+import (
+	"bytes"
+	"fmt"
+
+	"github.com/kdar/health/edifact/parse"
+)
+
+// A Document is decoded data that keeps the bytes it was decoded
+// from, so it's written back out exactly as it was sent: the same
+// escaping, the same whitespace between segments, and the same quirks.
+// Only the segments that are edited are encoded again, and only the
+// data elements that are set in them. It's meant for forwarding
+// interchanges with as few changes as possible, which Unmarshal and
+// Marshal can't do.
+type Document struct {
+	header   Header // the delimiters until a UNA segment says otherwise
+	quirks   Quirks
+	segments []*rawSegment
+	trailer  string // what's after the last segment, e.g. a newline
+}
+
+// a segment of a Document.
+type rawSegment struct {
+	// what's before the segment, as sent: any UNA segments along with
+	// the whitespace before them, then the whitespace before the
+	// segment itself.
+	prefix string
+	space  string
+	una    Header // the last UNA segment in prefix, if any
+
+	// the segment as sent, including its terminator, where its data
+	// delimiters are, and what it decodes to.
+	raw        string
+	delimiters []int
+	values     Values
+
+	// the delimiters and syntax level it's in
+	hdr     Header
+	charset Charset
+}
+
+func (s *rawSegment) String() string {
+	return s.prefix + s.space + s.raw
+}
+
+// Unmarshals the data into a Document. Errors in the data are
+// returned as a *SyntaxError.
+func UnmarshalDocument(data []byte) (*Document, error) {
+	return UnmarshalDocumentOptions(data, DecodeOptions{})
+}
+
+// Unmarshals the data into a Document like UnmarshalOptions does. In
+// CharsetReport mode, the Document is returned along with its
+// CharsetErrors.
+func UnmarshalDocumentOptions(data []byte, opts DecodeOptions) (*Document, error) {
+	hdr := opts.Header
+	if hdr == (Header{}) {
+		hdr = DefaultHeader
+	}
+	if err := hdr.validate(); err != nil {
+		return nil, err
+	}
+
+	text := string(data)
+	root, err := parse.ParseOptions(text, hdr[1], opts.quirks())
+	if err != nil {
+		return nil, err
+	}
+
+	d := &Document{header: hdr, quirks: opts.quirks()}
+	s := &state{
+		text:         text,
+		charset:      opts.Charset,
+		detect:       opts.Charset == "",
+		checkCharset: opts.CharsetMode != CharsetIgnore,
+	}
+
+	// where the last segment ended, and what's been read since
+	var end int
+	current := &rawSegment{hdr: hdr}
+
+	for _, node := range root.Nodes {
+		switch node := node.(type) {
+		case *parse.HeaderNode:
+			values := s.walk(node)
+			current.prefix += current.space + text[end:node.Pos] + text[node.Pos:node.End]
+			current.space = ""
+			current.una = values[0].(Header)
+			current.hdr = current.una
+			end = int(node.End)
+		case *parse.SegmentNode:
+			values := s.walk(node)
+			if s.err != nil {
+				return nil, s.err
+			}
+
+			current.space += text[end:node.Pos]
+			current.raw = text[node.Pos:node.End]
+			current.values = values[0].(Values)
+			current.charset = s.charset
+			for _, pos := range node.Delimiters {
+				current.delimiters = append(current.delimiters, int(pos-node.Pos))
+			}
+			d.segments = append(d.segments, current)
+
+			current = &rawSegment{hdr: current.hdr}
+			end = int(node.End)
+		}
+	}
+	d.trailer = current.prefix + current.space + text[end:]
+
+	if len(s.invalid) > 0 {
+		if opts.CharsetMode == CharsetEnforce {
+			return nil, s.invalid[0]
+		}
+		return d, CharsetErrors(s.invalid)
+	}
+
+	return d, nil
+}
+
+// Bytes returns the Document as it was decoded, with the edits made
+// to it.
+func (d *Document) Bytes() []byte {
+	var buf bytes.Buffer
+	for _, s := range d.segments {
+		buf.WriteString(s.String())
+	}
+	buf.WriteString(d.trailer)
+	return buf.Bytes()
+}
+
+// Len returns the number of segments in the Document, not counting
+// UNA segments.
+func (d *Document) Len() int {
+	return len(d.segments)
+}
+
+// Segment returns segment i, starting at 0, as Unmarshal returns it.
+// It shouldn't be changed; use SetElement or SetSegment instead.
+func (d *Document) Segment(i int) Values {
+	return d.segments[i].values
+}
+
+// Values returns the segments like Unmarshal does: the first value is
+// always the Header, and any other UNA segments are Headers in between
+// the segments.
+func (d *Document) Values() Values {
+	values := Values{d.header}
+	for i, s := range d.segments {
+		if s.una != (Header{}) {
+			if i == 0 {
+				values[0] = s.una
+			} else {
+				values = append(values, s.una)
+			}
+		}
+		values = append(values, s.values)
+	}
+	return values
+}
+
+// SetElement sets data element n (starting at 1) of segment i to
+// value, which is a string, or Values for a composite or repetitions.
+// The rest of the segment is left as it was sent. If the segment has
+// fewer than n data elements, empty ones are added.
+func (d *Document) SetElement(i, n int, value interface{}) error {
+	if err := d.check(i, d.Len()-1); err != nil {
+		return err
+	}
+	if n < 1 {
+		return fmt.Errorf("edifact: invalid data element %d", n)
+	}
+
+	s := d.segments[i]
+	text, err := s.encode(Values{"X", value})
+	if err != nil {
+		return err
+	}
+	// without the placeholder tag, the data delimiter after it
+	// and the segment terminator
+	text = text[2 : len(text)-1]
+
+	terminator := len(s.raw) - 1
+	var raw string
+	if n <= len(s.delimiters) {
+		start, end := s.delimiters[n-1]+1, terminator
+		if n < len(s.delimiters) {
+			end = s.delimiters[n]
+		}
+		raw = s.raw[:start] + text + s.raw[end:]
+	} else {
+		raw = s.raw[:terminator] + string(bytes.Repeat([]byte{s.hdr.DataDelimiter()}, n-len(s.delimiters))) +
+			text + s.raw[terminator:]
+	}
+
+	return d.replace(s, raw)
+}
+
+// SetSegment replaces segment i with segment, in the delimiters and
+// syntax level of the segment it replaces.
+func (d *Document) SetSegment(i int, segment Values) error {
+	if err := d.check(i, d.Len()-1); err != nil {
+		return err
+	}
+
+	s := d.segments[i]
+	raw, err := s.encode(segment)
+	if err != nil {
+		return err
+	}
+
+	return d.replace(s, raw)
+}
+
+// InsertSegment inserts segment before segment i, or after the last
+// segment if i is Len. It's separated from the segments around it the
+// way the Document separates its segments, e.g. with newlines.
+func (d *Document) InsertSegment(i int, segment Values) error {
+	if err := d.check(i, d.Len()); err != nil {
+		return err
+	}
+
+	s := &rawSegment{hdr: d.header, space: d.separator()}
+	if i < d.Len() {
+		next := d.segments[i]
+		s.hdr, s.charset = next.hdr, next.charset
+	} else if i > 0 {
+		prev := d.segments[i-1]
+		s.hdr, s.charset = prev.hdr, prev.charset
+	}
+
+	raw, err := s.encode(segment)
+	if err != nil {
+		return err
+	}
+	if err := d.replace(s, raw); err != nil {
+		return err
+	}
+
+	// it goes after what's before the segment it's inserted before
+	if i < d.Len() {
+		next := d.segments[i]
+		s.prefix, s.space, s.una = next.prefix, next.space, next.una
+		next.prefix, next.space, next.una = "", d.separator(), Header{}
+	}
+
+	d.segments = append(d.segments, nil)
+	copy(d.segments[i+1:], d.segments[i:])
+	d.segments[i] = s
+	return nil
+}
+
+// RemoveSegment removes segment i. Any UNA segments before it are
+// kept.
+func (d *Document) RemoveSegment(i int) error {
+	if err := d.check(i, d.Len()-1); err != nil {
+		return err
+	}
+
+	s := d.segments[i]
+	if i+1 < d.Len() {
+		next := d.segments[i+1]
+		if next.prefix == "" {
+			next.una = s.una
+		}
+		next.prefix = s.prefix + next.prefix
+	} else {
+		d.trailer = s.prefix + d.trailer
+	}
+
+	d.segments = append(d.segments[:i], d.segments[i+1:]...)
+	return nil
+}
+
+// returns an error if i isn't a segment between 0 and max.
+func (d *Document) check(i, max int) error {
+	if i < 0 || i > max {
+		return fmt.Errorf("edifact: segment %d out of range", i)
+	}
+	return nil
+}
+
+// returns what's between the segments of the Document: the
+// whitespace before the second segment.
+func (d *Document) separator() string {
+	if len(d.segments) < 2 {
+		return ""
+	}
+	return d.segments[1].space
+}
+
+// replaces the segment s with raw, decoding it again.
+func (d *Document) replace(s *rawSegment, raw string) error {
+	root, err := parse.ParseOptions(raw, s.hdr[1], d.quirks)
+	if err != nil {
+		return err
+	}
+
+	st := &state{text: raw, charset: s.charset}
+	if len(root.Nodes) != 1 {
+		return fmt.Errorf("edifact: invalid segment %q", raw)
+	}
+	node, ok := root.Nodes[0].(*parse.SegmentNode)
+	if !ok {
+		return fmt.Errorf("edifact: invalid segment %q", raw)
+	}
+
+	values := st.walk(node)
+	if st.err != nil {
+		return st.err
+	}
+
+	s.raw = raw
+	s.values = values[0].(Values)
+	s.delimiters = s.delimiters[:0]
+	for _, pos := range node.Delimiters {
+		s.delimiters = append(s.delimiters, int(pos-node.Pos))
+	}
+	return nil
+}
+
+// encodes segment in the delimiters and syntax level of s.
+func (s *rawSegment) encode(segment Values) (string, error) {
+	opts := EncodeOptions{}
+	if s.charset == UNOC {
+		opts.Charset = UNOC
+	}
+
+	out, err := MarshalOptions(Values{s.hdr, segment}, opts)
+	if err != nil {
+		return "", err
+	}
+	return string(out[len(s.hdr[0])+len(s.hdr[1]):]), nil
+}
@@ -0,0 +1,91 @@
+package edifact
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var documentTests = []string{
+	M_OUT1,
+	M_OUT2,
+	M_OUT3,
+	CRAZY_IN1,
+	ENVELOPE_IN1,
+	// escaping Marshal wouldn't write, newlines between segments
+	// and a UNA segment in the middle
+	"NAD+CVS?PHARMACY+A??B+C?:D'\r\nUNA~|.?^!\r\nFTX|a?~b~c!\r\nDTM|1!\n",
+}
+
+func TestDocumentBytes(t *testing.T) {
+	for i, in := range documentTests {
+		d, err := UnmarshalDocument([]byte(in))
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+
+		if out := string(d.Bytes()); out != in {
+			t.Fatalf("%d. mismatch\nhave: %q\nwant: %q", i, out, in)
+		}
+
+		values, err := Unmarshal([]byte(in))
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+		if !reflect.DeepEqual(d.Values(), values) {
+			t.Fatalf("%d. mismatch\nhave: %#+v\nwant: %#+v", i, d.Values(), values)
+		}
+	}
+}
+
+func TestDocumentEdit(t *testing.T) {
+	in := "UNA:+.? 'NAD+CVS?PHARMACY+A??B+C?:D'\nFTX+Missing 'Request=''\nUNZ+1+1'\n"
+	d, err := UnmarshalDocument([]byte(in))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := d.SetElement(0, 2, "A+B"); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.SetElement(2, 4, Values{"X", "Y"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.InsertSegment(0, Values{"UNB", Values{"UNOA", "1"}}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.InsertSegment(d.Len(), Values{"UNX", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := d.RemoveSegment(2); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "UNA:+.? 'UNB+UNOA:1'\nNAD+CVS?PHARMACY+A?+B+C?:D'\nUNZ+1+1++X:Y'\nUNX+1'\n"
+	if out := string(d.Bytes()); out != expected {
+		t.Fatalf("mismatch\nhave: %q\nwant: %q", out, expected)
+	}
+
+	if s := d.Segment(1); !reflect.DeepEqual(s, Values{"NAD", "CVS PHARMACY", "A+B", "C:D"}) {
+		t.Fatalf("unexpected segment: %#+v", s)
+	}
+
+	if err := d.SetSegment(2, Values{"UNZ", "2", "1"}); err != nil {
+		t.Fatal(err)
+	}
+	if s := d.Segment(2); !reflect.DeepEqual(s, Values{"UNZ", "2", "1"}) {
+		t.Fatalf("unexpected segment: %#+v", s)
+	}
+
+	// the default UNA has no repetition delimiter, so spaces are text
+	if err := d.SetElement(1, 1, "MAIN ST PHARMACY"); err != nil {
+		t.Fatal(err)
+	}
+	if out := string(d.Bytes()); !strings.Contains(out, "\nNAD+MAIN ST PHARMACY+") {
+		t.Fatalf("expected the spaces as they are, got %q", out)
+	}
+
+	if err := d.SetElement(5, 1, "x"); err == nil {
+		t.Fatal("expected an error for a segment out of range")
+	}
+}
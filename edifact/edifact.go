@@ -1,9 +1,35 @@
 package edifact
 
+import (
+	"fmt"
+
+	"github.com/kdar/health/edifact/parse"
+)
+
 type Values []interface{}
 
+// A SyntaxError is an error in the EDIFACT data, with the position
+// (offset, line and column, segment, data element and component) it
+// was found at. Its Err is one of the parse.Err* kinds.
+type SyntaxError = parse.SyntaxError
+
 type Header [2]string
 
+// The header with the default service string advice of ISO 9735,
+// used when the interchange has no UNA segment. The space where the
+// repetition delimiter goes is reserved, so there is none and a space
+// is just text.
+var DefaultHeader = Header{"UNA", ":+.? '"}
+
+// returns an error if the header can't be used to
+// encode or decode with.
+func (h Header) validate() error {
+	if h[0] != parse.UNA_SEGMENT_NAME || len(h[1]) != 6 {
+		return fmt.Errorf("edifact: invalid header %q", h[0]+h[1])
+	}
+	return nil
+}
+
 func (h Header) ComponentDelimiter() byte {
 	return h[1][0]
 }
@@ -20,10 +46,110 @@ func (h Header) ReleaseIndicator() byte {
 	return h[1][3]
 }
 
+// RepetitionDelimiter returns the repetition delimiter, or a space if
+// it's reserved, as it is before syntax version 4, and there's none.
 func (h Header) RepetitionDelimiter() byte {
 	return h[1][4]
 }
 
+// reports whether the header has a repetition delimiter.
+func (h Header) repeats() bool {
+	return h.RepetitionDelimiter() != parse.UNA_REPETITION_DELIMITER
+}
+
 func (h Header) SegmentTerminator() byte {
 	return h[1][5]
 }
+
+// SegmentName returns the tag of a segment, its first value.
+func SegmentName(segment Values) string {
+	if len(segment) == 0 {
+		return ""
+	}
+	name, _ := segment[0].(string)
+	return name
+}
+
+// Element returns data element n of a segment, or "" if there isn't
+// one. Element 0 is the tag.
+func Element(segment Values, n int) interface{} {
+	if n < len(segment) && segment[n] != nil {
+		return segment[n]
+	}
+	return ""
+}
+
+// Component returns component n of a data element, starting at 1, or
+// "" if there isn't one. The first repetition is used if there's more
+// than one. n of 0 returns the data element.
+func Component(data interface{}, n int) interface{} {
+	if n == 0 {
+		return data
+	}
+
+	values, ok := data.(Values)
+	if !ok {
+		if n == 1 {
+			return data
+		}
+		return ""
+	}
+
+	if isRepetition(values) {
+		return Component(values[0], n)
+	}
+
+	if n <= len(values) && values[n-1] != nil {
+		return values[n-1]
+	}
+	return ""
+}
+
+// Text returns the string of a simple data element, or the first
+// component of a composite.
+func Text(data interface{}) string {
+	switch data := data.(type) {
+	case string:
+		return data
+	case Values:
+		if len(data) > 0 {
+			return Text(data[0])
+		}
+	}
+	return ""
+}
+
+// Repetitions returns the repetitions of a data element. composite
+// says whether each repetition is a composite, since a single
+// composite looks just like repetitions of simple data elements.
+func Repetitions(data interface{}, composite bool) []interface{} {
+	if IsEmpty(data) {
+		return nil
+	}
+
+	values, ok := data.(Values)
+	if !ok || (composite && !isRepetition(values)) {
+		return []interface{}{data}
+	}
+
+	return values
+}
+
+// IsEmpty reports whether data is nil or holds nothing but empty
+// strings.
+func IsEmpty(data interface{}) bool {
+	switch data := data.(type) {
+	case nil:
+		return true
+	case string:
+		return data == ""
+	case Values:
+		for _, v := range data {
+			if !IsEmpty(v) {
+				return false
+			}
+		}
+		return true
+	}
+	return false
+}
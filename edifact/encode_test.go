@@ -2,7 +2,11 @@ package edifact
 
 import (
 	"bytes"
-	//"fmt"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"regexp"
 	"testing"
 )
 
@@ -125,3 +129,267 @@ func TestMarshal(t *testing.T) {
 		}
 	}
 }
+
+var marshalOptionsTests = []struct {
+	in   Values
+	opts EncodeOptions
+	out  string
+}{
+	{
+		Values{Values{"TES", "hey", "th'ere"}},
+		EncodeOptions{},
+		"TES+hey+th?'ere'",
+	},
+	{
+		Values{DefaultHeader, Values{"TES", "hey"}},
+		EncodeOptions{},
+		"UNA:+.? 'TES+hey'",
+	},
+	{
+		Values{DefaultHeader, Values{"TES", "hey"}},
+		EncodeOptions{OmitDefaultUNA: true},
+		"TES+hey'",
+	},
+	{
+		Values{Values{"PVD", "CVS PHARMACY", Values{"A B", "C"}}},
+		EncodeOptions{},
+		"PVD+CVS PHARMACY+A B:C'",
+	},
+	{
+		M_IN3,
+		EncodeOptions{OmitDefaultUNA: true},
+		M_OUT3,
+	},
+}
+
+func TestMarshalOptions(t *testing.T) {
+	for i, tt := range marshalOptionsTests {
+		out, err := MarshalOptions(tt.in, tt.opts)
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+
+		if string(out) != tt.out {
+			t.Fatalf("%d. unexpected output: %s. want %s", i, string(out), tt.out)
+		}
+	}
+
+	// the default UNA has no repetition delimiter to write them with
+	if _, err := Marshal(Values{Values{"COO", Values{Values{"07", "1"}, Values{"36"}}}}); err == nil {
+		t.Fatal("expected an error for repetitions")
+	}
+}
+
+func TestEncoder(t *testing.T) {
+	for i, tt := range marshalTests {
+		var buf bytes.Buffer
+		if err := NewEncoder(&buf).Encode(tt.in); err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+
+		if !bytes.Equal(buf.Bytes(), tt.out) {
+			t.Fatalf("%d. unexpected output: %s. want %s", i, buf.String(), string(tt.out))
+		}
+	}
+
+	if err := NewEncoder(io.Discard).Segment(Values{"TES", 1}); err == nil {
+		t.Fatal("expected an error for an int")
+	}
+}
+
+func TestEncoderAllocs(t *testing.T) {
+	segments := largeRXHRES(10)
+	e := NewEncoder(io.Discard)
+	allocs := testing.AllocsPerRun(10, func() {
+		if err := e.Encode(segments); err != nil {
+			t.Fatal(err)
+		}
+	})
+	if allocs != 0 {
+		t.Fatalf("expected no allocations, got %v", allocs)
+	}
+}
+
+func TestEncoderLargeRXHRES(t *testing.T) {
+	segments := largeRXHRES(100)
+	out, err := Marshal(segments)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected, err := regexpMarshal(segments)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(out, expected) {
+		t.Fatalf("unexpected output: %s. want %s", out, expected)
+	}
+}
+
+// returns an RXHRES with n drugs, each with its pharmacy and
+// prescriber.
+func largeRXHRES(n int) Values {
+	segments := Values{
+		Header{"UNA", "~|.?^'"},
+		Values{"UIB", Values{"UNOA", "0"}, "", "hJIAmKH0FGDSt", "", "", Values{"Sender1", "ZZZ"}, Values{"Recepient1", "ZZZ", "Recepient2"}, Values{"20130113", "121625,0"}},
+		Values{"UIH", Values{"SCRIPT", "008", "001", "RXHRES"}, "hJIAmKH0FGDSt"},
+		Values{"RES", "A"},
+		Values{"PTT", "1", "19850630", Values{"Smith", "John"}, "M", "", Values{"", "", "", "33165", "", ""}, ""},
+	}
+
+	for i := 0; i < n; i++ {
+		segments = append(segments,
+			Values{"DRU",
+				Values{"D", "XOLEGEL 2% GEL", "16110008045", "ND", "", "", "", "", "", "", "", ""},
+				Values{"ZZ", "45", "38"},
+				Values{"", "APPLY TO AFFECTED AREA TWICE A DAY AS NEEDED", ""},
+				Values{Values{"LD", "20120422", "102"}, Values{"ZDS", "30", "804"}},
+				"0", Values{"R", "0"}, "", "", "", "", ""},
+			Values{"PVD", "P2", Values{"1031232", "D3"}, "", "", "", "", "CVS PHARMACY", Values{"55596 SW 16TH ST", "ATLANTA", "GA", "12175"}, Values{Values{"3053872415", "TE"}, Values{"", "FX"}}, ""},
+			Values{"PVD", "PC", Values{"BW7412396", "DH"}, "", "", Values{"Betterton", "Jill", "", "", ""}, "", "", Values{"9900 SW 87th Ave", "Atlanta", "GA", "12173"}, Values{"", "TE"}},
+		)
+	}
+
+	return append(segments,
+		Values{"UIT", "hJIAmKH0FGDSt", fmt.Sprint(len(segments) - 1)},
+		Values{"UIZ", "", ""},
+	)
+}
+
+func BenchmarkMarshal(b *testing.B) {
+	segments := largeRXHRES(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := Marshal(segments); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkEncoder(b *testing.B) {
+	segments := largeRXHRES(1000)
+	e := NewEncoder(io.Discard)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if err := e.Encode(segments); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func BenchmarkMarshalRegexp(b *testing.B) {
+	segments := largeRXHRES(1000)
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := regexpMarshal(segments); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// how Marshal used to work, with reflection and escaping with a regexp,
+// to compare the Encoder with.
+type sliceCallback func(reflect.Value) ([]byte, error)
+
+func marshalPart(hdr Header, data reflect.Value, delimiter byte, delimiterRegexp *regexp.Regexp, sliceCallback sliceCallback) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	if data.Kind() == reflect.Interface {
+		data = data.Elem()
+	}
+
+	switch data.Kind() {
+	default:
+		return []byte(""), errors.New(fmt.Sprintf("Unknown data type: %s", data.Kind()))
+	case reflect.String:
+		escapedData := delimiterRegexp.ReplaceAllString(data.String(), string(hdr.ReleaseIndicator())+"$1")
+		buf.WriteString(escapedData)
+	case reflect.Array, reflect.Slice:
+		// Byte slices are special. We treat them just like the string case.
+		if data.Type().Elem().Kind() == reflect.Uint8 {
+			escapedData := delimiterRegexp.ReplaceAll(data.Bytes(), []byte(string(hdr.ReleaseIndicator())+"$1"))
+			buf.Write(escapedData)
+			break
+		}
+
+		for n := 0; n < data.Len(); n++ {
+			cdata := data.Index(n)
+
+			if sliceCallback != nil {
+				cbBytes, err := sliceCallback(cdata)
+				if err != nil {
+					return []byte(""), err
+				}
+				buf.Write(cbBytes)
+			}
+
+			// we don't want to write the delimiter after the last element
+			if n+1 < data.Len() {
+				buf.WriteByte(delimiter)
+			}
+		}
+	}
+
+	return buf.Bytes(), nil
+}
+
+func regexpMarshal(segments Values) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	hdr, ok := segments[0].(Header)
+	if ok {
+		buf.WriteString(hdr[0])
+		buf.WriteString(hdr[1])
+		segments = segments[1:]
+	} else {
+		hdr = DefaultHeader
+	}
+
+	dr, err := regexp.Compile(
+		fmt.Sprintf(`([%s])`,
+			regexp.QuoteMeta(fmt.Sprintf(
+				"%c%c%c%c%c",
+				hdr.ComponentDelimiter(),
+				hdr.DataDelimiter(),
+				hdr.ReleaseIndicator(),
+				hdr.RepetitionDelimiter(),
+				hdr.SegmentTerminator()),
+			)))
+	if err != nil {
+		return []byte(""), err
+	}
+
+	for _, segment := range segments {
+		segmentBytes, err := marshalPart(hdr, reflect.ValueOf(segment), hdr.DataDelimiter(), dr, func(data reflect.Value) ([]byte, error) {
+			sep := hdr.ComponentDelimiter()
+			// if we have a slice and the slice's elements are of type slice as well,
+			// then we have a repetition and we need to use the reserved(repetition) delimiter
+			if data.Elem().Kind() == reflect.Slice {
+				vOftmp := data.Elem().Index(0)
+				if vOftmp.Kind() == reflect.Interface {
+					vOftmp = vOftmp.Elem()
+				}
+				if vOftmp.Kind() == reflect.Slice {
+					sep = hdr.RepetitionDelimiter()
+				}
+			}
+
+			return marshalPart(hdr, data, sep, dr, func(data2 reflect.Value) ([]byte, error) {
+				return marshalPart(hdr, data2, hdr.ComponentDelimiter(), dr, func(data3 reflect.Value) ([]byte, error) {
+					return marshalPart(hdr, data3, hdr.RepetitionDelimiter(), dr, nil)
+				})
+			})
+		})
+		if err != nil {
+			return []byte(""), err
+		}
+
+		buf.Write(segmentBytes)
+		buf.WriteByte(hdr.SegmentTerminator())
+	}
+
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,351 @@
+package edifact
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// The service segments that make up the envelope. The UI* ones are
+// the interactive (ISO 9735-3) versions used by SCRIPT, which has no
+// functional groups.
+const (
+	UNB = "UNB"
+	UNZ = "UNZ"
+	UNG = "UNG"
+	UNE = "UNE"
+	UNH = "UNH"
+	UNT = "UNT"
+	UIB = "UIB"
+	UIZ = "UIZ"
+	UIH = "UIH"
+	UIT = "UIT"
+)
+
+// A Message is the segments between a UNH and UNT (or UIH and UIT).
+type Message struct {
+	Start    Values // UNH or UIH
+	Segments []Values
+	End      Values // UNT or UIT
+}
+
+// A Group is a functional group of messages between a UNG and UNE.
+type Group struct {
+	Start    Values // UNG
+	Messages []*Message
+	End      Values // UNE
+}
+
+// An Interchange is the segments between a UNB and UNZ (or UIB and
+// UIZ). It either has Groups or Messages, not both.
+type Interchange struct {
+	Header   Header // the UNA segment; left out if zero
+	Start    Values // UNB or UIB
+	Groups   []*Group
+	Messages []*Message
+	End      Values // UNZ or UIZ
+}
+
+// A ControlError is a control reference or count in a trailer segment
+// that doesn't match the rest of the interchange.
+type ControlError struct {
+	Segment  string // e.g. UNT
+	Element  int    // the position of the data element, e.g. 1 for UNT.1, or 0 if the segment is missing
+	Value    interface{}
+	Expected interface{}
+}
+
+func (e *ControlError) Error() string {
+	if e.Element == 0 {
+		return fmt.Sprintf("edifact: missing %s segment", e.Segment)
+	}
+	return fmt.Sprintf("edifact: %s.%d is %v, expected %v", e.Segment, e.Element, e.Value, e.Expected)
+}
+
+// Creates an Interchange from decoded segments, like the ones
+// Unmarshal returns. Only the structure is checked here, use Validate
+// to check the control references and counts.
+func NewInterchange(segments Values) (*Interchange, error) {
+	ic := &Interchange{}
+
+	if len(segments) > 0 {
+		if hdr, ok := segments[0].(Header); ok {
+			ic.Header = hdr
+			segments = segments[1:]
+		}
+	}
+
+	s := &envelopeState{segments: segments}
+
+	start, err := s.expect(UNB, UIB)
+	if err != nil {
+		return nil, err
+	}
+	ic.Start = start
+	interactive := SegmentName(start) == UIB
+
+	for s.more() {
+		switch name := s.peek(); {
+		case name == UNG && !interactive:
+			if len(ic.Messages) > 0 {
+				return nil, s.errorf("found %s after messages outside of a group", name)
+			}
+			group, err := s.group()
+			if err != nil {
+				return nil, err
+			}
+			ic.Groups = append(ic.Groups, group)
+		case name == UNH && !interactive, name == UIH && interactive:
+			if len(ic.Groups) > 0 {
+				return nil, s.errorf("found %s outside of a group", name)
+			}
+			message, err := s.message()
+			if err != nil {
+				return nil, err
+			}
+			ic.Messages = append(ic.Messages, message)
+		default:
+			end := UNZ
+			if interactive {
+				end = UIZ
+			}
+			if ic.End, err = s.expect(end); err != nil {
+				return nil, err
+			}
+			if s.more() {
+				return nil, s.errorf("found %s after %s", s.peek(), end)
+			}
+			return ic, nil
+		}
+	}
+
+	return nil, s.errorf("found eof while reading interchange")
+}
+
+// Returns the segments of the interchange, with the counts and control
+// references of all the trailer segments filled in. Trailers that are
+// missing are created.
+func (ic *Interchange) Values() Values {
+	var values Values
+	if ic.Header != (Header{}) {
+		values = append(values, ic.Header)
+	}
+
+	values = append(values, ic.Start)
+	for _, group := range ic.Groups {
+		values = append(values, group.Start)
+		for _, message := range group.Messages {
+			values = append(values, message.values()...)
+		}
+		values = append(values, group.trailer())
+	}
+	for _, message := range ic.Messages {
+		values = append(values, message.values()...)
+	}
+	values = append(values, ic.trailer())
+
+	return values
+}
+
+// Marshals the interchange. See Values.
+func (ic *Interchange) Marshal() ([]byte, error) {
+	return Marshal(ic.Values())
+}
+
+// Validate checks the counts and control references of all the
+// trailer segments, and returns an error for each one that's wrong.
+func (ic *Interchange) Validate() []*ControlError {
+	var errs []*ControlError
+
+	for _, group := range ic.Groups {
+		for _, message := range group.Messages {
+			errs = append(errs, compareTrailer(message.End, message.trailer())...)
+		}
+		errs = append(errs, compareTrailer(group.End, group.trailer())...)
+	}
+	for _, message := range ic.Messages {
+		errs = append(errs, compareTrailer(message.End, message.trailer())...)
+	}
+	errs = append(errs, compareTrailer(ic.End, ic.trailer())...)
+
+	return errs
+}
+
+func (ic *Interchange) trailer() Values {
+	if SegmentName(ic.Start) == UIB {
+		// UIZ+dialogue reference+message count
+		return setElements(ic.End, UIZ, Element(ic.Start, 2), strconv.Itoa(len(ic.Messages)))
+	}
+
+	count := len(ic.Messages)
+	if len(ic.Groups) > 0 {
+		count = len(ic.Groups)
+	}
+
+	// UNZ+message or group count+interchange control reference
+	return setElements(ic.End, UNZ, strconv.Itoa(count), Element(ic.Start, 5))
+}
+
+func (g *Group) trailer() Values {
+	// UNE+message count+group reference
+	return setElements(g.End, UNE, strconv.Itoa(len(g.Messages)), Element(g.Start, 5))
+}
+
+// Returns the segments of the message, with the trailer filled in.
+func (m *Message) values() Values {
+	values := Values{m.Start}
+	for _, segment := range m.Segments {
+		values = append(values, segment)
+	}
+	return append(values, m.trailer())
+}
+
+func (m *Message) trailer() Values {
+	// the count includes the header and trailer
+	count := strconv.Itoa(len(m.Segments) + 2)
+
+	if SegmentName(m.Start) == UIH {
+		// UIT+message reference+segment count
+		return setElements(m.End, UIT, Element(m.Start, 2), count)
+	}
+
+	// UNT+segment count+message reference
+	return setElements(m.End, UNT, count, Element(m.Start, 1))
+}
+
+// returns a copy of segment, or a new one if it's empty, with the
+// name and the first data elements set to values.
+func setElements(segment Values, name string, values ...interface{}) Values {
+	out := make(Values, len(values)+1)
+	copy(out, segment)
+	out[0] = name
+	copy(out[1:], values)
+
+	if len(segment) > len(out) {
+		out = append(out, segment[len(out):]...)
+	}
+	return out
+}
+
+// compares a trailer segment to the one it should be.
+func compareTrailer(actual, expected Values) []*ControlError {
+	name := SegmentName(expected)
+	if actual == nil {
+		return []*ControlError{{Segment: name, Expected: expected}}
+	}
+
+	var errs []*ControlError
+	for i := 1; i < len(expected); i++ {
+		if !sameElement(Element(actual, i), expected[i]) {
+			errs = append(errs, &ControlError{
+				Segment:  name,
+				Element:  i,
+				Value:    Element(actual, i),
+				Expected: expected[i],
+			})
+		}
+	}
+	return errs
+}
+
+// reports whether two data elements are the same. Counts are
+// compared as numbers so leading zeros don't matter.
+func sameElement(a, b interface{}) bool {
+	if reflect.DeepEqual(a, b) {
+		return true
+	}
+
+	as, aok := a.(string)
+	bs, bok := b.(string)
+	if aok && bok {
+		an, aerr := strconv.Atoi(as)
+		bn, berr := strconv.Atoi(bs)
+		return aerr == nil && berr == nil && an == bn
+	}
+
+	return false
+}
+
+// our state for building an Interchange
+type envelopeState struct {
+	segments Values
+	pos      int
+}
+
+func (s *envelopeState) more() bool {
+	return s.pos < len(s.segments)
+}
+
+// returns the name of the next segment
+func (s *envelopeState) peek() string {
+	segment, _ := s.segments[s.pos].(Values)
+	return SegmentName(segment)
+}
+
+func (s *envelopeState) next() Values {
+	segment, _ := s.segments[s.pos].(Values)
+	s.pos++
+	return segment
+}
+
+// returns the next segment if it's one of names
+func (s *envelopeState) expect(names ...string) (Values, error) {
+	if !s.more() {
+		return nil, s.errorf("found eof, expected %s", names[0])
+	}
+
+	name := s.peek()
+	for _, n := range names {
+		if name == n {
+			return s.next(), nil
+		}
+	}
+
+	return nil, s.errorf("found %q, expected %s", name, names[0])
+}
+
+func (s *envelopeState) group() (*Group, error) {
+	group := &Group{Start: s.next()}
+
+	for s.more() && s.peek() == UNH {
+		message, err := s.message()
+		if err != nil {
+			return nil, err
+		}
+		group.Messages = append(group.Messages, message)
+	}
+
+	end, err := s.expect(UNE)
+	if err != nil {
+		return nil, err
+	}
+	group.End = end
+
+	return group, nil
+}
+
+func (s *envelopeState) message() (*Message, error) {
+	message := &Message{Start: s.next()}
+
+	end := UNT
+	if SegmentName(message.Start) == UIH {
+		end = UIT
+	}
+
+	for s.more() {
+		switch name := s.peek(); name {
+		case end:
+			message.End = s.next()
+			return message, nil
+		case UNB, UNZ, UNG, UNE, UNH, UIB, UIZ, UIH:
+			return nil, s.errorf("found %s, expected %s", name, end)
+		}
+		message.Segments = append(message.Segments, s.next())
+	}
+
+	return nil, s.errorf("found eof, expected %s", end)
+}
+
+func (s *envelopeState) errorf(format string, args ...interface{}) error {
+	return fmt.Errorf("edifact: segment %d: %s", s.pos+1, fmt.Sprintf(format, args...))
+}
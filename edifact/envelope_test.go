@@ -0,0 +1,134 @@
+package edifact
+
+import (
+	"reflect"
+	"testing"
+)
+
+const (
+	ENVELOPE_IN1 = "UNB+UNOA:1+SENDER+RECEIVER+130113:0516+REF1'" +
+		"UNG+ORDERS+SENDER+RECEIVER+130113:0516+G1+UN+D:96A'" +
+		"UNH+M1+ORDERS:D:96A:UN'BGM+220+1'DTM+137:20130113:102'UNT+4+M1'" +
+		"UNH+M2+ORDERS:D:96A:UN'BGM+220+2'UNT+3+M2'" +
+		"UNE+2+G1'" +
+		"UNZ+1+REF1'"
+)
+
+func TestNewInterchange(t *testing.T) {
+	values, err := Unmarshal([]byte(ENVELOPE_IN1))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ic, err := NewInterchange(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ic.Groups) != 1 || len(ic.Messages) != 0 {
+		t.Fatalf("expected 1 group and no messages, got %d and %d", len(ic.Groups), len(ic.Messages))
+	}
+
+	messages := ic.Groups[0].Messages
+	if len(messages) != 2 || len(messages[0].Segments) != 2 || len(messages[1].Segments) != 1 {
+		t.Fatalf("unexpected messages: %#v", messages)
+	}
+
+	if errs := ic.Validate(); len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+
+	if !reflect.DeepEqual(ic.Values(), values) {
+		t.Fatalf("mismatch\nhave: %#+v\nwant: %#+v", ic.Values(), values)
+	}
+
+	// SCRIPT uses the interactive segments
+	ic, err = NewInterchange(CRAZY_OUT1)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if len(ic.Messages) != 1 || len(ic.Messages[0].Segments) != 1 {
+		t.Fatalf("unexpected messages: %#v", ic.Messages)
+	}
+
+	if errs := ic.Validate(); len(errs) != 0 {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+}
+
+var newInterchangeErrorTests = []string{
+	// no UNB
+	"UNH+M1+ORDERS'UNT+2+M1'",
+	// no UNZ
+	"UNB+UNOA:1+S+R+130113:0516+REF1'UNH+M1+ORDERS'UNT+2+M1'",
+	// no UNT
+	"UNB+UNOA:1+S+R+130113:0516+REF1'UNH+M1+ORDERS'BGM+220'UNZ+1+REF1'",
+	// messages inside and outside of groups
+	"UNB+UNOA:1+S+R+130113:0516+REF1'UNH+M1+ORDERS'UNT+2+M1'UNG+ORDERS+S+R+130113:0516+G1'UNE+0+G1'UNZ+1+REF1'",
+	// segments after UNZ
+	"UNB+UNOA:1+S+R+130113:0516+REF1'UNZ+0+REF1'UNH+M1+ORDERS'",
+}
+
+func TestNewInterchangeErrors(t *testing.T) {
+	for i, in := range newInterchangeErrorTests {
+		values, err := Unmarshal([]byte(in))
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+
+		if _, err := NewInterchange(values); err == nil {
+			t.Fatalf("%d. expected an error", i)
+		}
+	}
+}
+
+func TestInterchangeValidate(t *testing.T) {
+	values, err := Unmarshal([]byte(
+		"UNB+UNOA:1+S+R+130113:0516+REF1'" +
+			"UNH+M1+ORDERS'BGM+220'UNT+2+M2'" +
+			"UNH+M3+ORDERS'UNT+02+M3'" +
+			"UNZ+1+REF2'"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ic, err := NewInterchange(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []*ControlError{
+		{Segment: UNT, Element: 1, Value: "2", Expected: "3"},
+		{Segment: UNT, Element: 2, Value: "M2", Expected: "M1"},
+		{Segment: UNZ, Element: 1, Value: "1", Expected: "2"},
+		{Segment: UNZ, Element: 2, Value: "REF2", Expected: "REF1"},
+	}
+
+	errs := ic.Validate()
+	if !reflect.DeepEqual(errs, expected) {
+		t.Fatalf("mismatch\nhave: %v\nwant: %v", errs, expected)
+	}
+}
+
+func TestInterchangeMarshal(t *testing.T) {
+	ic := &Interchange{
+		Start: Values{UIB, Values{"UNOA", "0"}, "D1"},
+		Messages: []*Message{
+			{
+				Start:    Values{UIH, Values{"SCRIPT", "008", "001", "RXHREQ"}, "M1"},
+				Segments: []Values{{"PTT", "1", "19900807"}},
+			},
+		},
+	}
+
+	out, err := ic.Marshal()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "UIB+UNOA:0+D1'UIH+SCRIPT:008:001:RXHREQ+M1'PTT+1+19900807'UIT+M1+3'UIZ+D1+1'"
+	if string(out) != expected {
+		t.Fatalf("unexpected output: %s. want %s", out, expected)
+	}
+}
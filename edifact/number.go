@@ -0,0 +1,93 @@
+package edifact
+
+import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Numeric data elements
+//
+// A numeric data element is digits with an optional leading minus
+// sign (never a plus sign) and decimal mark, and in syntax version 4
+// an optional exponent: E (or e) followed by an optional minus sign
+// and digits, as in 15E-3. The decimal mark to send is the one in the
+// UNA segment, but either a comma or a point is accepted when reading
+// since EDIFACT numbers have no other separators.
+
+// ParseNumber parses a numeric data element.
+func ParseNumber(s string) (float64, error) {
+	n, err := normalizeNumber(s)
+	if err != nil {
+		return 0, err
+	}
+
+	f, err := strconv.ParseFloat(n, 64)
+	if err != nil {
+		return 0, fmt.Errorf("edifact: invalid number %q", s)
+	}
+	return f, nil
+}
+
+// FormatNumber formats f as a numeric data element with the decimal
+// mark, e.g. Header.Decimal(). It has no exponent, and no decimal mark
+// when f is a whole number. NaN and the infinities have no numeric
+// representation, so they're formatted as "".
+func FormatNumber(f float64, decimal byte) string {
+	return formatNumber(f, 64, decimal)
+}
+
+func formatNumber(f float64, bitSize int, decimal byte) string {
+	if math.IsNaN(f) || math.IsInf(f, 0) {
+		return ""
+	}
+	if f == 0 {
+		// no negative zero
+		return "0"
+	}
+
+	s := strconv.FormatFloat(f, 'f', -1, bitSize)
+	if decimal != '.' {
+		s = strings.Replace(s, ".", string(decimal), 1)
+	}
+	return s
+}
+
+// returns the numeric data element s in the syntax strconv takes.
+func normalizeNumber(s string) (string, error) {
+	mantissa, exponent := s, ""
+	if i := strings.IndexAny(s, "Ee"); i >= 0 {
+		mantissa, exponent = s[:i], s[i+1:]
+		if !isDigits(strings.TrimPrefix(exponent, "-"), false) {
+			return "", fmt.Errorf("edifact: invalid number %q", s)
+		}
+	}
+
+	if !isDigits(strings.TrimPrefix(mantissa, "-"), true) {
+		return "", fmt.Errorf("edifact: invalid number %q", s)
+	}
+
+	n := strings.Replace(mantissa, ",", ".", 1)
+	if exponent != "" {
+		n += "e" + exponent
+	}
+	return n, nil
+}
+
+// reports whether s is at least one digit, with one decimal mark
+// anywhere in it if mark is true.
+func isDigits(s string, mark bool) bool {
+	digits := 0
+	for i := 0; i < len(s); i++ {
+		switch c := s[i]; {
+		case '0' <= c && c <= '9':
+			digits++
+		case mark && (c == '.' || c == ','):
+			mark = false
+		default:
+			return false
+		}
+	}
+	return digits > 0
+}
@@ -0,0 +1,62 @@
+package edifact
+
+import (
+	"math"
+	"testing"
+)
+
+var parseNumberTests = []struct {
+	in  string
+	out float64
+}{
+	{"48", 48},
+	{"30.5", 30.5},
+	{"30,5", 30.5},
+	{"-0,25", -0.25},
+	{",5", 0.5},
+	{"15E-3", 0.015},
+	{"-1,5e2", -150},
+	{"007", 7},
+}
+
+func TestParseNumber(t *testing.T) {
+	for i, tt := range parseNumberTests {
+		out, err := ParseNumber(tt.in)
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+		if out != tt.out {
+			t.Fatalf("%d. expected %v, got %v", i, tt.out, out)
+		}
+	}
+
+	for i, in := range []string{"", "-", "+5", "1.5.2", "1,5.2", "1E", "1E+2", "1e2.5", "12a", " 12"} {
+		if _, err := ParseNumber(in); err == nil {
+			t.Fatalf("%d. expected an error for %q", i, in)
+		}
+	}
+}
+
+var formatNumberTests = []struct {
+	in      float64
+	decimal byte
+	out     string
+}{
+	{48, ',', "48"},
+	{30.5, ',', "30,5"},
+	{30.5, '.', "30.5"},
+	{-0.25, ',', "-0,25"},
+	{1e21, '.', "1000000000000000000000"},
+	{0.000001, ',', "0,000001"},
+	{math.NaN(), '.', ""},
+	{math.Inf(1), '.', ""},
+	{math.Inf(-1), ',', ""},
+}
+
+func TestFormatNumber(t *testing.T) {
+	for i, tt := range formatNumberTests {
+		if out := FormatNumber(tt.in, tt.decimal); out != tt.out {
+			t.Fatalf("%d. expected %s, got %s", i, tt.out, out)
+		}
+	}
+}
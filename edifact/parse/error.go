@@ -0,0 +1,67 @@
+package parse
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// The kinds of syntax errors. A SyntaxError's Err is one of these.
+var (
+	ErrUnexpectedEOF    = errors.New("unexpected eof")
+	ErrInvalidCharacter = errors.New("invalid character")
+	ErrInvalidHeader    = errors.New("invalid UNA header")
+	ErrInvalidNode      = errors.New("invalid node")
+	ErrInvalidCharset   = errors.New("character not in syntax level")
+	ErrInvalidRelease   = errors.New("unpaired release indicator")
+)
+
+// A SyntaxError is an error in the EDIFACT data, along with where in
+// the data it happened.
+type SyntaxError struct {
+	Err       error  // the kind of error, e.g. ErrUnexpectedEOF
+	Msg       string // a description of the error
+	Offset    int    // the byte offset in the input
+	Line      int    // the line, starting at 1
+	Column    int    // the column in runes, starting at 1
+	Segment   int    // the segment number, starting at 1 (the UNA segment counts)
+	Tag       string // the segment tag, if it was read
+	Element   int    // the data element, or 0 for the tag
+	Component int    // the component, or 0 if not in a composite
+}
+
+func (e *SyntaxError) Error() string {
+	s := fmt.Sprintf("edifact: line %d, column %d", e.Line, e.Column)
+	if e.Segment > 0 {
+		s += fmt.Sprintf(", segment %d", e.Segment)
+		if e.Tag != "" {
+			s += " " + e.Tag
+		}
+	}
+	if e.Element > 0 {
+		s += fmt.Sprintf(", element %d", e.Element)
+		if e.Component > 0 {
+			s += fmt.Sprintf(".%d", e.Component)
+		}
+	}
+	return s + ": " + e.Msg
+}
+
+// Unwrap returns the kind of error.
+func (e *SyntaxError) Unwrap() error {
+	return e.Err
+}
+
+// LineColumn returns the line and column (in runes), both starting
+// at 1, of the byte offset in text.
+func LineColumn(text string, offset int) (line, column int) {
+	if offset > len(text) {
+		offset = len(text)
+	}
+
+	line = strings.Count(text[:offset], "\n") + 1
+	start := strings.LastIndex(text[:offset], "\n") + 1
+	column = utf8.RuneCountInString(text[start:offset]) + 1
+	return line, column
+}
@@ -21,10 +21,14 @@ const (
 	UNA_DATA_DELIMITER       = '+'
 	UNA_DECIMAL              = '.'
 	UNA_RELEASE_INDICATOR    = '?'
-	UNA_REPETITION_DELIMITER = ' ' // This is really "reserved" in the spec.
+	UNA_REPETITION_DELIMITER = ' ' // reserved before syntax version 4: there's none
 	UNA_SEGMENT_TERMINATOR   = '\''
 )
 
+// the repetition delimiter of a UNA segment with the reserved space in
+// its place. it matches no rune.
+const noDelimiter = -2
+
 const (
 	COMPONENT_DELIMITER_POS = iota
 	DATA_DELIMITER_POS
@@ -53,6 +57,10 @@ type lexer struct {
 	width               token.Pos        // width of last rune read from input
 	lastPos             token.Pos        // position of most recent item returned by nextItem
 	tokens              chan token.Token // channel of scanned tokens
+	done                chan struct{}    // closed when the tokens aren't wanted anymore
+	stopped             bool             // whether done was closed while sending a token
+	err                 *SyntaxError     // the error, set before the error token is sent
+	opts                Options          // the deviations from the standard to put up with
 
 	// rare special case
 	foundQuote rune
@@ -93,10 +101,26 @@ func (l *lexer) backup() {
 
 // emit passes an item back to the client.
 func (l *lexer) emit(t token.TokenType) {
-	l.tokens <- token.Token{t, l.start, l.input[l.start:l.pos]}
+	l.send(token.Token{t, l.start, l.input[l.start:l.pos]})
 	l.start = l.pos
 }
 
+// send passes a token back to the client, unless the client stopped
+// the lexer, in which case run stops after the current state.
+func (l *lexer) send(tok token.Token) {
+	select {
+	case l.tokens <- tok:
+	case <-l.done:
+		l.stopped = true
+	}
+}
+
+// stop tells the lexer the client won't read any more tokens, so it
+// doesn't block forever sending one.
+func (l *lexer) stop() {
+	close(l.done)
+}
+
 // ignore skips over the pending input before this point.
 func (l *lexer) ignore() {
 	l.start = l.pos
@@ -120,8 +144,14 @@ func (l *lexer) acceptRun(valid string) {
 
 // errorf returns an error token and terminates the scan by passing
 // back a nil pointer that will be the next state, terminating l.nextItem.
-func (l *lexer) errorf(format string, args ...interface{}) stateFn {
-	l.tokens <- token.Token{token.ERROR, l.start, fmt.Sprintf(format, args...)}
+// The error's position is the last rune read.
+func (l *lexer) errorf(kind error, format string, args ...interface{}) stateFn {
+	l.err = &SyntaxError{
+		Err:    kind,
+		Msg:    fmt.Sprintf(format, args...),
+		Offset: int(l.pos - l.width),
+	}
+	l.send(token.Token{token.ERROR, l.start, l.err.Msg})
 	return nil
 }
 
@@ -132,25 +162,26 @@ func (l *lexer) nextToken() token.Token {
 	return tok
 }
 
-// lex creates a new scanner for the input string.
-func lex(name, input string) *lexer {
-	l := &lexer{
+// newLexer creates a new scanner for the input string. It
+// doesn't start scanning until run is called.
+func newLexer(name, input string) *lexer {
+	return &lexer{
 		name:                name,
 		input:               input,
 		componentDelimiter:  UNA_COMPONENT_DELIMITER,
 		dataDelimiter:       UNA_DATA_DELIMITER,
+		decimal:             UNA_DECIMAL,
 		releaseIndicator:    UNA_RELEASE_INDICATOR,
-		repetitionDelimiter: UNA_REPETITION_DELIMITER,
+		repetitionDelimiter: noDelimiter,
 		segmentTerminator:   UNA_SEGMENT_TERMINATOR,
 		tokens:              make(chan token.Token, 2),
+		done:                make(chan struct{}),
 	}
-	go l.run()
-	return l
 }
 
 // run runs the state machine for the lexer.
 func (l *lexer) run() {
-	for l.state = lexSegment; l.state != nil; {
+	for l.state = lexSegment; l.state != nil && !l.stopped; {
 		l.state = l.state(l)
 	}
 }
@@ -166,16 +197,22 @@ func lexSegment(l *lexer) stateFn {
 
 	r := l.peek()
 
-	if r == '\n' {
+	switch {
+	case r == eof:
+		l.emit(token.EOF)
+		return nil
+	case l.opts.TrailingWhitespace && isTrailingSpace(l.input[l.pos:]):
+		l.pos = token.Pos(len(l.input))
+		l.emit(token.EOF)
+		return nil
+	case l.opts.Newlines && isEndOfLine(r):
 		return lexBeginningNewlines
-	}
-
-	if isUpper(r) {
+	case isUpper(r):
 		return lexSegmentName
 	}
 
-	l.emit(token.EOF)
-	return nil
+	l.next()
+	return l.errorf(ErrInvalidCharacter, "unknown character found between segments: %q", r)
 }
 
 // lex any amount of newlines before a segment.
@@ -183,10 +220,13 @@ func lexSegment(l *lexer) stateFn {
 func lexBeginningNewlines(l *lexer) stateFn {
 	for {
 		switch r := l.next(); {
-		case r == '\n':
+		case isEndOfLine(r):
 			// ignore
 			l.start = l.pos
-		case isAlphaNumeric(r) && isUpper(r):
+		case r == eof:
+			l.emit(token.EOF)
+			return nil
+		default:
 			l.backup()
 			return lexSegment
 		}
@@ -207,23 +247,10 @@ func lexUNASegment(l *lexer) stateFn {
 	for x := 0; x < 6; x++ {
 		r := l.next()
 		if r == eof {
-			return l.errorf("found eof while reading UNA header")
+			return l.errorf(ErrUnexpectedEOF, "found eof while reading UNA header")
 		}
 
-		switch x {
-		case COMPONENT_DELIMITER_POS:
-			l.componentDelimiter = r
-		case DATA_DELIMITER_POS:
-			l.dataDelimiter = r
-		case DECIMAL_POS:
-			l.decimal = r
-		case RELEASE_INDICATOR_POS:
-			l.releaseIndicator = r
-		case REPETITION_DELIMITER_POS:
-			l.repetitionDelimiter = r
-		case SEGMENT_TERMINATOR_POS:
-			l.segmentTerminator = r
-		}
+		l.setDelimiter(x, r)
 	}
 
 	l.emit(token.UNA_TEXT)
@@ -232,6 +259,37 @@ func lexUNASegment(l *lexer) stateFn {
 	return lexSegment
 }
 
+// sets the delimiter at position x of the UNA header.
+func (l *lexer) setDelimiter(x int, r rune) {
+	switch x {
+	case COMPONENT_DELIMITER_POS:
+		l.componentDelimiter = r
+	case DATA_DELIMITER_POS:
+		l.dataDelimiter = r
+	case DECIMAL_POS:
+		l.decimal = r
+	case RELEASE_INDICATOR_POS:
+		l.releaseIndicator = r
+	case REPETITION_DELIMITER_POS:
+		l.repetitionDelimiter = r
+		if r == UNA_REPETITION_DELIMITER {
+			l.repetitionDelimiter = noDelimiter
+		}
+	case SEGMENT_TERMINATOR_POS:
+		l.segmentTerminator = r
+	}
+}
+
+// returns the text of a UNA segment with the current delimiters.
+func (l *lexer) una() string {
+	repetitionDelimiter := l.repetitionDelimiter
+	if repetitionDelimiter == noDelimiter {
+		repetitionDelimiter = UNA_REPETITION_DELIMITER
+	}
+	return string([]rune{l.componentDelimiter, l.dataDelimiter, l.decimal,
+		l.releaseIndicator, repetitionDelimiter, l.segmentTerminator})
+}
+
 // les the segment name. usually this is just
 // three uppercase letters.
 func lexSegmentName(l *lexer) stateFn {
@@ -244,9 +302,9 @@ func lexSegmentName(l *lexer) stateFn {
 		case isAlphaNumeric(r) && isUpper(r):
 			// absorb.
 		case r == eof:
-			return l.errorf("found eof while reading segment name")
+			return l.errorf(ErrUnexpectedEOF, "found eof while reading segment name")
 		default:
-			return l.errorf("unknown character found while reading segment name: %q", r)
+			return l.errorf(ErrInvalidCharacter, "unknown character found while reading segment name: %q", r)
 		}
 	}
 
@@ -279,26 +337,8 @@ func lexData(l *lexer) stateFn {
 			// it were token.TEXT.
 			// note: this does not cover the case where if they don't
 			// escape other delimiters. but i have not seen this yet.
-			if l.foundQuote == 0 && isQuote(r) && int(l.pos) < len(l.input) {
-				isTerm := true
-				p := l.pos
-
-				// test if the next 3 runes are upper case
-				for x := 0; x < 3 && int(p) < len(l.input); x++ {
-					subr, w := l.at(p)
-					isTerm = isTerm && isUpper(subr)
-					p += token.Pos(w)
-				}
-
-				// check to see if the 4th rune is a data delimiter
-				if int(p) < len(l.input) {
-					subr, _ := l.at(p)
-					isTerm = isTerm && subr == l.dataDelimiter
-
-					if !isTerm {
-						l.foundQuote = r
-					}
-				}
+			if l.opts.UnescapedTerminator && l.foundQuote == 0 && isQuote(r) && !l.startsSegment() {
+				l.foundQuote = r
 			}
 
 			if l.foundQuote == 0 {
@@ -321,7 +361,7 @@ func lexData(l *lexer) stateFn {
 			l.emit(token.TEXT)
 			return lexRepetitionDelimiter
 		case r == eof:
-			return l.errorf("found eof while reading data")
+			return l.errorf(ErrUnexpectedEOF, "found eof while reading data")
 		default:
 			// absorb
 		}
@@ -330,6 +370,39 @@ func lexData(l *lexer) stateFn {
 	return lexSegment
 }
 
+// reports whether the input after the current position looks like
+// the start of a segment (three upper case letters and a data
+// delimiter, or a UNA segment, after newlines if they're allowed), or
+// the end of the input.
+func (l *lexer) startsSegment() bool {
+	p := l.pos
+	if l.opts.TrailingWhitespace && isTrailingSpace(l.input[p:]) {
+		return true
+	}
+	if l.opts.Newlines {
+		for int(p) < len(l.input) && isEndOfLine(rune(l.input[p])) {
+			p++
+		}
+	}
+
+	if strings.HasPrefix(l.input[p:], UNA_SEGMENT_NAME) {
+		return true
+	}
+
+	var next [4]rune
+	for x := range next {
+		r, w := l.at(p)
+		if r == eof {
+			return true
+		}
+		next[x] = r
+		p += token.Pos(w)
+	}
+
+	return isUpper(next[0]) && isUpper(next[1]) && isUpper(next[2]) &&
+		next[3] == l.dataDelimiter
+}
+
 // lex the component delimiter
 func lexComponentDelimiter(l *lexer) stateFn {
 	l.next() // we already know this is the component delimiter
@@ -361,6 +434,17 @@ func isEndOfLine(r rune) bool {
 	return r == '\r' || r == '\n'
 }
 
+// isTrailingSpace reports whether s is nothing but spaces and
+// end-of-line characters.
+func isTrailingSpace(s string) bool {
+	for _, r := range s {
+		if !isSpace(r) && !isEndOfLine(r) {
+			return false
+		}
+	}
+	return true
+}
+
 // isAlphaNumeric reports whether r is an alphabetic, digit, or underscore.
 func isAlphaNumeric(r rune) bool {
 	return r == '_' || unicode.IsLetter(r) || unicode.IsDigit(r)
@@ -5,6 +5,8 @@ package parse
 import (
 	"bytes"
 	"fmt"
+
+	"github.com/kdar/health/edifact/token"
 )
 
 // A Node is an element in the parse tree. The interface is trivial.
@@ -76,6 +78,8 @@ type HeaderNode struct {
 	NodeType
 	SegmentName Node
 	Text        Node
+	Pos         token.Pos // where the UNA segment starts in the input
+	End         token.Pos // where it ends, after its segment terminator
 }
 
 func newHeader() *HeaderNode {
@@ -92,11 +96,18 @@ func (h *HeaderNode) String() string {
 // DataNode of the list.
 type SegmentNode struct {
 	NodeType
-	List *ListNode
+	token.Pos // where the segment starts in the input
+	List      *ListNode
+
+	// where the segment ends in the input, after its segment
+	// terminator, and where its data delimiters are. The input
+	// between them is the segment exactly as it was sent.
+	End        token.Pos
+	Delimiters []token.Pos
 }
 
-func newSegment() *SegmentNode {
-	return &SegmentNode{NodeType: NodeSegment, List: newList()}
+func newSegment(pos token.Pos) *SegmentNode {
+	return &SegmentNode{NodeType: NodeSegment, Pos: pos, List: newList()}
 }
 
 func (s *SegmentNode) String() string {
@@ -0,0 +1,60 @@
+package parse
+
+import "fmt"
+
+// Release says what to do with a release indicator that isn't
+// followed by a delimiter or another release indicator. The standard
+// only allows those, but not every sender follows it.
+type Release int
+
+const (
+	// return a *SyntaxError with an Err of ErrInvalidRelease
+	ReleaseError Release = iota
+	// replace it with a space. relayhealth uses the release
+	// indicator as a space, such as "CVS?PHARMACY".
+	ReleaseSpace
+	// keep it as text
+	ReleaseKeep
+	// remove it, keeping the character after it
+	ReleaseDrop
+)
+
+func (r Release) String() string {
+	switch r {
+	case ReleaseError:
+		return "error"
+	case ReleaseSpace:
+		return "space"
+	case ReleaseKeep:
+		return "keep"
+	case ReleaseDrop:
+		return "drop"
+	}
+	return fmt.Sprintf("Release(%d)", int(r))
+}
+
+// Options are the deviations from the standard the parser puts up
+// with. The zero value is strict.
+type Options struct {
+	// How to handle a release indicator that isn't paired with a
+	// delimiter.
+	Release Release
+	// Take a segment terminator that's a quote as text unless it's
+	// followed by the start of another segment, for senders that
+	// don't release quotes in text.
+	UnescapedTerminator bool
+	// Skip carriage returns and line feeds between segments.
+	Newlines bool
+	// Ignore whitespace (spaces, tabs, carriage returns and line
+	// feeds) after the last segment.
+	TrailingWhitespace bool
+}
+
+// The options Parse and ParseWith use. These are what relayhealth
+// sends.
+var DefaultOptions = Options{
+	Release:             ReleaseSpace,
+	UnescapedTerminator: true,
+	Newlines:            true,
+	TrailingWhitespace:  true,
+}
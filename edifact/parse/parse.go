@@ -6,9 +6,10 @@
 package parse
 
 import (
-	"errors"
+	"bytes"
 	"fmt"
-	"regexp"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/kdar/health/edifact/token"
 )
@@ -27,6 +28,22 @@ type Tree struct {
 	lex       *lexer
 	token     [1]token.Token // one-token lookahead for parser.
 	peekCount int
+
+	// where we are, for errors
+	segment      int
+	segmentStart token.Pos
+	tag          string
+	element      int
+	component    int
+
+	// where the data delimiters of the segment are
+	dataDelimiters []token.Pos
+
+	// the deviations from the standard to put up with, and what
+	// the release indicator releases
+	opts             Options
+	releaseIndicator rune
+	delimiters       string
 }
 
 var (
@@ -53,27 +70,47 @@ var (
 	}
 )
 
-// Parses the text and returns a parse tree.
+// Parses the text and returns a parse tree. Errors in the
+// text are returned as a *SyntaxError.
 func Parse(text string) (listnode *ListNode, err error) {
+	return ParseWith(text, "")
+}
+
+// Parses the text like Parse, using the delimiters in una (the six
+// characters that follow "UNA" in a UNA segment) until a UNA segment
+// says otherwise. An empty una uses the defaults.
+func ParseWith(text, una string) (listnode *ListNode, err error) {
+	return ParseOptions(text, una, DefaultOptions)
+}
+
+// Parses the text like ParseWith, putting up with only the deviations
+// from the standard in opts.
+func ParseOptions(text, una string, opts Options) (listnode *ListNode, err error) {
 	tree := &Tree{
 		Root: newList(),
-		lex:  lex("", text),
+		lex:  newLexer("", text),
+		opts: opts,
 	}
+	tree.lex.opts = opts
 
-	// these are two regexps to help us in removing the
-	// release indicator from text and replacing it if
-	// necessary and appropriate
-	// --
-	// this first regex's job is to take any release indicator
-	// that is not paired with a delimiter, and replace it with
-	// a space. now this is not in the spec at all but I have seen
-	// this in the wild (relayhealth) where they will use the release
-	// indicator as a space. Such as: "CVS?PHARMACY"
-	var releaseRegex1 *regexp.Regexp
-	// this regex will simply just remove the release indicator
-	// wherever it is paired with a delimiter. Such as:
-	// "??" -> "?" and "?^_?^" -> "^_^"
-	var releaseRegex2 *regexp.Regexp
+	if una != "" {
+		if utf8.RuneCountInString(una) != 6 {
+			return nil, &SyntaxError{Err: ErrInvalidHeader, Msg: fmt.Sprintf("invalid UNA header %q", una), Line: 1, Column: 1}
+		}
+		x := 0
+		for _, r := range una {
+			tree.lex.setDelimiter(x, r)
+			x++
+		}
+	}
+
+	// the UNA segment is optional, so start off with the
+	// default delimiters the lexer uses. this has to be done
+	// before the lexer runs since it changes them.
+	tree.setDelimiters(tree.lex.una())
+	go tree.lex.run()
+	// returning early leaves tokens unread
+	defer tree.lex.stop()
 
 LOOP:
 	for {
@@ -82,50 +119,52 @@ LOOP:
 		case token.EOF:
 			break LOOP
 		case token.ERROR:
-			return nil, errors.New(tok.Val)
+			return nil, tree.error(text)
 		case token.SEGMENT_TERMINATOR:
 			// If we get a segment terminator, then append it
 			// to our root and clear the stack.
-			seg := newSegment()
+			seg := newSegment(tree.segmentStart)
 			seg.List.Nodes = append(seg.List.Nodes, tree.stack...)
+			seg.End = tok.Pos + token.Pos(len(tok.Val))
+			seg.Delimiters = tree.dataDelimiters
+			tree.dataDelimiters = nil
 			tree.Root.append(seg)
 			tree.stack.clear()
+			tree.tag, tree.element, tree.component = "", 0, 0
 		case token.UNA_SEGMENT:
+			tree.segment++
+			tree.segmentStart = tok.Pos
+			tree.tag = tok.Val
 			tree.stack.push(newText(tok.Val))
 		case token.UNA_TEXT:
 			hdr := newHeader()
 			hdr.SegmentName = tree.stack.last()
 			hdr.Text = newText(tok.Val)
+			hdr.Pos = tree.segmentStart
+			hdr.End = tok.Pos + token.Pos(len(tok.Val))
 			tree.Root.append(hdr)
 			tree.stack.clear()
+			tree.tag = ""
 
-			// at this point our lex parsed all the delimiters.
-			// so we can create our release regexps.
-
-			// the %%s will get replaced later for the regex's
-			// specific purpose.
-			// i use QuoteMeta here just in case our delimiters
-			// conflict with the regexp.
-			baseRegStr := fmt.Sprintf(`%s([%%s%s])`,
-				regexp.QuoteMeta(string(tree.lex.releaseIndicator)),
-				regexp.QuoteMeta(fmt.Sprintf("%c%c%c%c%c", tree.lex.componentDelimiter, tree.lex.dataDelimiter,
-					tree.lex.releaseIndicator, tree.lex.repetitionDelimiter,
-					tree.lex.segmentTerminator)))
-
-			releaseRegex1, err = regexp.Compile(fmt.Sprintf(baseRegStr, "^"))
-			if err != nil {
-				return nil, err
-			}
-			releaseRegex2, err = regexp.Compile(fmt.Sprintf(baseRegStr, ""))
-			if err != nil {
-				return nil, err
-			}
+			// the delimiters are the text of the UNA segment,
+			// which are the ones the lexer uses from now on.
+			tree.setDelimiters(tok.Val)
 		case token.TEXT:
-			// explanation of this is commented by the regexp declarations
-			tok.Val = releaseRegex1.ReplaceAllString(tok.Val, " $1")
-			tok.Val = releaseRegex2.ReplaceAllString(tok.Val, "$1")
+			var i int
+			if tok.Val, i = tree.unescape(tok.Val); i >= 0 {
+				return nil, tree.errorAt(text, &SyntaxError{
+					Err:    ErrInvalidRelease,
+					Msg:    fmt.Sprintf("release indicator not followed by a delimiter in %q", tok.Val),
+					Offset: int(tok.Pos) + i,
+				})
+			}
 			fallthrough
 		default:
+			tree.track(tok)
+			if tok.Typ == token.DATA_DELIMITER {
+				tree.dataDelimiters = append(tree.dataDelimiters, tok.Pos)
+			}
+
 			// if addToStack is true, then we push the text onto
 			// the stack.
 			addToStack := true
@@ -163,6 +202,115 @@ LOOP:
 	return tree.Root, nil
 }
 
+// keeps track of where we are in the segment for errors.
+func (t *Tree) track(tok token.Token) {
+	switch tok.Typ {
+	case token.SEGMENT:
+		t.segment++
+		t.segmentStart = tok.Pos
+		t.tag = tok.Val
+		t.element, t.component = 0, 0
+	case token.DATA_DELIMITER:
+		t.element++
+		t.component = 0
+	case token.COMPONENT_DELIMITER:
+		if t.component == 0 {
+			t.component = 1
+		}
+		t.component++
+	case token.REPETITION_DELIMITER:
+		t.component = 0
+	}
+}
+
+// returns the lexer's error with where we are in the segment.
+func (t *Tree) error(text string) error {
+	err := t.lex.err
+	if err == nil {
+		err = &SyntaxError{Err: ErrInvalidCharacter, Msg: t.token[0].Val, Offset: int(t.token[0].Pos)}
+	}
+	return t.errorAt(text, err)
+}
+
+// returns err with where we are in the segment.
+func (t *Tree) errorAt(text string, err *SyntaxError) error {
+	err.Line, err.Column = LineColumn(text, err.Offset)
+
+	// a segment name being read doesn't count until it's done
+	err.Segment = t.segment
+	if t.tag == "" {
+		err.Segment++
+	}
+	err.Tag = t.tag
+	err.Element = t.element
+	err.Component = t.component
+
+	return err
+}
+
+// remembers the release indicator and the delimiters it releases
+// from the text of a UNA segment.
+func (t *Tree) setDelimiters(una string) {
+	t.delimiters = ""
+	x := 0
+	for _, r := range una {
+		switch x {
+		case RELEASE_INDICATOR_POS:
+			t.releaseIndicator = r
+			t.delimiters += string(r)
+		case REPETITION_DELIMITER_POS:
+			// the reserved space isn't a delimiter
+			if r != UNA_REPETITION_DELIMITER {
+				t.delimiters += string(r)
+			}
+		case COMPONENT_DELIMITER_POS, DATA_DELIMITER_POS, SEGMENT_TERMINATOR_POS:
+			t.delimiters += string(r)
+		}
+		x++
+	}
+}
+
+// removes the release indicators from text, where they are paired
+// with a delimiter: "??" -> "?" and "?^_?^" -> "^_^". What happens
+// to the ones that aren't depends on the Release option. In
+// ReleaseError mode, it returns the offset of the first unpaired one
+// in text, otherwise -1.
+func (t *Tree) unescape(text string) (string, int) {
+	if strings.IndexRune(text, t.releaseIndicator) < 0 {
+		return text, -1
+	}
+
+	var buf bytes.Buffer
+	for i := 0; i < len(text); {
+		r, w := utf8.DecodeRuneInString(text[i:])
+		// copy the original bytes, since text needn't be UTF-8
+		if r != t.releaseIndicator {
+			buf.WriteString(text[i : i+w])
+			i += w
+			continue
+		}
+
+		next, nw := utf8.DecodeRuneInString(text[i+w:])
+		if nw > 0 && strings.IndexRune(t.delimiters, next) >= 0 {
+			buf.WriteString(text[i+w : i+w+nw])
+			i += w + nw
+			continue
+		}
+
+		switch t.opts.Release {
+		case ReleaseError:
+			return text, i
+		case ReleaseSpace:
+			buf.WriteByte(' ')
+		case ReleaseKeep:
+			buf.WriteString(text[i : i+w])
+		}
+		i += w
+	}
+
+	return buf.String(), -1
+}
+
 // reduce a text-data with a new data as text as its child
 func reduceTextData(node Node, tok token.Token) (Node, error) {
 	return newData(node), nil
@@ -0,0 +1,254 @@
+package parse
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+)
+
+// A Visitor's Visit method is called with each node Walk finds. If it
+// returns a Visitor w, Walk visits each of the node's children with w,
+// followed by a call of w.Visit(nil).
+type Visitor interface {
+	Visit(node Node) (w Visitor)
+}
+
+// Walk visits the tree rooted at node in depth-first order, starting
+// with v.Visit(node).
+func Walk(v Visitor, node Node) {
+	if v = v.Visit(node); v == nil {
+		return
+	}
+
+	for _, child := range children(node) {
+		Walk(v, child)
+	}
+	v.Visit(nil)
+}
+
+type inspector func(Node) bool
+
+func (f inspector) Visit(node Node) Visitor {
+	if f(node) {
+		return f
+	}
+	return nil
+}
+
+// Inspect visits the tree rooted at node in depth-first order, calling
+// f with each node. If f returns false, the node's children aren't
+// visited. After the children, f is called with nil.
+func Inspect(node Node, f func(Node) bool) {
+	Walk(inspector(f), node)
+}
+
+// returns the nodes under node, leaving out the nil ones that
+// reduceRepetitionRepetition leaves behind.
+func children(node Node) []Node {
+	var nodes []Node
+	switch node := node.(type) {
+	case *ListNode:
+		nodes = node.Nodes
+	case *HeaderNode:
+		nodes = []Node{node.SegmentName, node.Text}
+	case *SegmentNode:
+		nodes = []Node{node.List}
+	case *DataNode:
+		nodes = []Node{node.Node}
+	case *ComponentNode:
+		nodes = []Node{node.List}
+	case *RepetitionNode:
+		nodes = []Node{node.List}
+	}
+
+	var out []Node
+	for _, n := range nodes {
+		if n != nil {
+			out = append(out, n)
+		}
+	}
+	return out
+}
+
+// A Position is where a text is in a parse tree, or in decoded values.
+// Everything starts at 1. The text of a simple data element is its
+// first component, and a data element that doesn't repeat is its first
+// repetition.
+type Position struct {
+	Tag        string
+	Occurrence int // of the segments with the tag
+	Element    int // the data element, or 0 for the tag
+	Repetition int
+	Component  int
+}
+
+// String returns the position as a path that finds it, such as
+// "DRU[2]/1/2" or "COO[1]/9[2]/1".
+func (p Position) String() string {
+	s := fmt.Sprintf("%s[%d]", p.Tag, p.Occurrence)
+	if p.Element == 0 {
+		return s
+	}
+
+	s += "/" + strconv.Itoa(p.Element)
+	if p.Repetition > 1 {
+		s += fmt.Sprintf("[%d]", p.Repetition)
+	}
+	return s + "/" + strconv.Itoa(p.Component)
+}
+
+// A Path finds texts by their Position. Every field has to match,
+// except a zero Occurrence, which matches any. An Element of 0 finds
+// the tag, in which case Repetition and Component are 0 too; otherwise
+// they start at 1, which ParsePath uses when they aren't given.
+type Path Position
+
+var pathRegexp = regexp.MustCompile(`^([A-Z0-9]+)(?:\[(\d+)\])?(?:/(\d+)(?:\[(\d+)\])?(?:/(\d+))?)?$`)
+
+// ParsePath parses a path like "DRU[2]/1/2": the second component of
+// the first data element of the second DRU segment. Leaving out the
+// occurrence, as in "DRU/1/2", finds the text in every DRU segment.
+// The repetition of a data element goes after it, as in "COO/9[2]/1",
+// and a path with just the tag finds the tags.
+func ParsePath(s string) (Path, error) {
+	m := pathRegexp.FindStringSubmatch(s)
+	if m == nil {
+		return Path{}, fmt.Errorf("edifact: invalid path %q", s)
+	}
+
+	p := Path{Tag: m[1], Repetition: 1, Component: 1}
+	for i, n := range []*int{&p.Occurrence, &p.Element, &p.Repetition, &p.Component} {
+		if m[i+2] == "" {
+			continue
+		}
+		*n, _ = strconv.Atoi(m[i+2])
+		if *n == 0 {
+			return Path{}, fmt.Errorf("edifact: invalid path %q: positions start at 1", s)
+		}
+	}
+
+	if p.Element == 0 {
+		p.Repetition, p.Component = 0, 0
+	}
+	return p, nil
+}
+
+// Matches reports whether the text at pos is one the path finds.
+func (p Path) Matches(pos Position) bool {
+	return p.Tag == pos.Tag &&
+		(p.Occurrence == 0 || p.Occurrence == pos.Occurrence) &&
+		p.Element == pos.Element &&
+		p.Repetition == pos.Repetition &&
+		p.Component == pos.Component
+}
+
+// WalkText calls fn with every text in the tree rooted at node,
+// segment tags included, along with its Position, in order. Like the
+// decoded values, a data element only repeats if one of its
+// repetitions is a composite: "a*b" decodes the same as "a:b", so its
+// texts are the components of the first repetition.
+func WalkText(node Node, fn func(pos Position, text string)) {
+	occurrences := make(map[string]int)
+	Inspect(node, func(n Node) bool {
+		segment, ok := n.(*SegmentNode)
+		if !ok {
+			// keep looking for segments
+			return n != nil
+		}
+
+		pos := Position{Tag: segmentTag(segment)}
+		occurrences[pos.Tag]++
+		pos.Occurrence = occurrences[pos.Tag]
+
+		for i, element := range segment.List.Nodes {
+			if data, ok := element.(*DataNode); ok {
+				element = data.Node
+			}
+
+			pos.Element = i
+			pos.Repetition, pos.Component = 1, 1
+			if i == 0 {
+				pos.Repetition, pos.Component = 0, 0
+			}
+
+			repetitions := []Node{element}
+			if r, ok := element.(*RepetitionNode); ok && repeats(r) {
+				repetitions = r.List.Nodes
+			}
+			for r, repetition := range repetitions {
+				pos.Repetition = r + 1
+				if i == 0 {
+					pos.Repetition = 0
+				}
+
+				components := []Node{repetition}
+				switch c := repetition.(type) {
+				case *ComponentNode:
+					components = c.List.Nodes
+				case *RepetitionNode:
+					components = c.List.Nodes
+				}
+				for c, component := range components {
+					if text, ok := component.(*TextNode); ok {
+						if i > 0 {
+							pos.Component = c + 1
+						}
+						fn(pos, string(text.Text))
+					}
+				}
+			}
+		}
+		return false
+	})
+}
+
+// Query returns the texts the path finds in the tree rooted at node,
+// in order.
+func Query(node Node, path string) ([]string, error) {
+	p, err := ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var texts []string
+	WalkText(node, func(pos Position, text string) {
+		if p.Matches(pos) {
+			texts = append(texts, text)
+		}
+	})
+	return texts, nil
+}
+
+// Segments returns the segments with the tag in the tree rooted at
+// node, in order. Their Pos and End are where they are in the input.
+func Segments(node Node, tag string) []*SegmentNode {
+	var segments []*SegmentNode
+	Inspect(node, func(n Node) bool {
+		if segment, ok := n.(*SegmentNode); ok {
+			if segmentTag(segment) == tag {
+				segments = append(segments, segment)
+			}
+			return false
+		}
+		return n != nil
+	})
+	return segments
+}
+
+// reports whether any of the repetitions is a composite.
+func repeats(r *RepetitionNode) bool {
+	for _, n := range r.List.Nodes {
+		if _, ok := n.(*ComponentNode); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// returns the tag of a segment: the text of its first node.
+func segmentTag(segment *SegmentNode) string {
+	if len(segment.List.Nodes) == 0 || segment.List.Nodes[0] == nil {
+		return ""
+	}
+	return segment.List.Nodes[0].String()
+}
@@ -0,0 +1,86 @@
+package edifact
+
+import (
+	"github.com/kdar/health/edifact/parse"
+)
+
+// A Position is where a text is in decoded values. See parse.Position.
+type Position = parse.Position
+
+// WalkText calls fn with every text in values, segment tags included,
+// along with its Position, in order. Headers are skipped. The positions
+// are the ones parse.WalkText finds in the parse tree.
+func WalkText(values Values, fn func(pos Position, text string)) {
+	occurrences := make(map[string]int)
+	for _, v := range values {
+		segment, ok := v.(Values)
+		if !ok {
+			continue
+		}
+
+		pos := Position{Tag: SegmentName(segment)}
+		occurrences[pos.Tag]++
+		pos.Occurrence = occurrences[pos.Tag]
+		fn(pos, pos.Tag)
+
+		for i := 1; i < len(segment); i++ {
+			pos.Element = i
+
+			repetitions := Values{segment[i]}
+			if values, ok := segment[i].(Values); ok && isRepetition(values) {
+				repetitions = values
+			}
+			for r, repetition := range repetitions {
+				pos.Repetition = r + 1
+
+				components := Values{repetition}
+				if values, ok := repetition.(Values); ok {
+					components = values
+				}
+				for c, component := range components {
+					pos.Component = c + 1
+					switch text := component.(type) {
+					case string:
+						fn(pos, text)
+					case []byte:
+						fn(pos, string(text))
+					}
+				}
+			}
+		}
+	}
+}
+
+// Query returns the texts the path, such as "DRU[2]/1/2", finds in
+// values, in order. See parse.ParsePath for the syntax.
+func Query(values Values, path string) ([]string, error) {
+	p, err := parse.ParsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var texts []string
+	WalkText(values, func(pos Position, text string) {
+		if p.Matches(pos) {
+			texts = append(texts, text)
+		}
+	})
+	return texts, nil
+}
+
+// A FoundSegment is a segment FindSegments found.
+type FoundSegment struct {
+	Index   int // in the values
+	Segment Values
+}
+
+// FindSegments returns the segments with the tag in values, in order.
+func FindSegments(values Values, tag string) []FoundSegment {
+	var found []FoundSegment
+	for i, v := range values {
+		if segment, ok := v.(Values); ok && SegmentName(segment) == tag {
+			found = append(found, FoundSegment{Index: i, Segment: segment})
+		}
+	}
+	return found
+}
@@ -0,0 +1,152 @@
+package edifact
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kdar/health/edifact/parse"
+)
+
+const queryIn = "UNA:+.?*'UIH+SCRIPT:008:001:RXHRES'" +
+	"DRU+D:ONE+ZZ:45+LD:20120422:102*ZDS:30:804'" +
+	"PVD+P2'" +
+	"DRU+D:TWO?:2+ZZ:38+LD:20130101:102'"
+
+var queryTests = []struct {
+	path string
+	out  []string
+}{
+	{"DRU[2]/1/2", []string{"TWO:2"}},
+	{"DRU/1/2", []string{"ONE", "TWO:2"}},
+	{"DRU/1", []string{"D", "D"}},
+	{"DRU[1]/3[2]/1", []string{"ZDS"}},
+	{"DRU/3/2", []string{"20120422", "20130101"}},
+	{"PVD", []string{"PVD"}},
+	{"PVD/2", nil},
+	{"UIH/1/4", []string{"RXHRES"}},
+}
+
+func TestQuery(t *testing.T) {
+	values, err := Unmarshal([]byte(queryIn))
+	if err != nil {
+		t.Fatal(err)
+	}
+	root, err := parse.Parse(queryIn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tt := range queryTests {
+		out, err := Query(values, tt.path)
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+		if !reflect.DeepEqual(out, tt.out) {
+			t.Fatalf("%d. %s: expected %q, got %q", i, tt.path, tt.out, out)
+		}
+
+		out, err = parse.Query(root, tt.path)
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+		if !reflect.DeepEqual(out, tt.out) {
+			t.Fatalf("%d. %s: expected %q from the parse tree, got %q", i, tt.path, tt.out, out)
+		}
+	}
+
+	for _, path := range []string{"", "dru", "DRU/0", "DRU[1", "DRU/1/2/3"} {
+		if _, err := Query(values, path); err == nil {
+			t.Fatalf("%s: expected an error", path)
+		}
+	}
+}
+
+func TestWalkText(t *testing.T) {
+	var positions []string
+	WalkText(Values{Values{"COO", "A", Values{Values{"07", "1"}, Values{"36"}}}}, func(pos Position, text string) {
+		positions = append(positions, pos.String()+"="+text)
+	})
+
+	expected := []string{"COO[1]=COO", "COO[1]/1/1=A", "COO[1]/2/1=07", "COO[1]/2/2=1", "COO[1]/2[2]/1=36"}
+	if !reflect.DeepEqual(positions, expected) {
+		t.Fatalf("expected %q, got %q", expected, positions)
+	}
+}
+
+func TestWalkTextParseTree(t *testing.T) {
+	// the values and the parse tree agree on where every text is
+	tests := []string{
+		queryIn,
+		"UNA:+.?*'X+a*b:c'",
+		"UNA:+.?*'X+a*b'",
+		"UNA:+.?*'X+a:b*c+d'",
+		"UNA:+.?*'X+a:b*c:d*e'",
+		"UNA:+.?*'X++a*b*c:d+e'",
+	}
+
+	for i, tt := range tests {
+		values, err := Unmarshal([]byte(tt))
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+		root, err := parse.Parse(tt)
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+
+		var fromValues, fromTree []string
+		WalkText(values, func(pos Position, text string) {
+			fromValues = append(fromValues, pos.String()+"="+text)
+		})
+		parse.WalkText(root, func(pos Position, text string) {
+			fromTree = append(fromTree, pos.String()+"="+text)
+		})
+		if !reflect.DeepEqual(fromValues, fromTree) {
+			t.Fatalf("%d. mismatch\nvalues: %q\ntree:   %q", i, fromValues, fromTree)
+		}
+	}
+
+	values, err := Unmarshal([]byte("UNA:+.?*'X+a*b:c'"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out, _ := Query(values, "X/1[2]/1"); !reflect.DeepEqual(out, []string{"b"}) {
+		t.Fatalf("expected [b], got %q", out)
+	}
+}
+
+func TestFindSegments(t *testing.T) {
+	values, err := Unmarshal([]byte(queryIn))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	found := FindSegments(values, "DRU")
+	if len(found) != 2 || found[0].Index != 2 || found[1].Index != 4 || SegmentName(found[1].Segment) != "DRU" {
+		t.Fatalf("unexpected segments: %+v", found)
+	}
+
+	root, err := parse.Parse(queryIn)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	segments := parse.Segments(root, "DRU")
+	if len(segments) != 2 {
+		t.Fatalf("expected 2 segments, got %d", len(segments))
+	}
+	if s := queryIn[segments[1].Pos:segments[1].End]; s != "DRU+D:TWO?:2+ZZ:38+LD:20130101:102'" {
+		t.Fatalf("unexpected segment: %s", s)
+	}
+
+	var n int
+	parse.Inspect(root, func(node parse.Node) bool {
+		if _, ok := node.(*parse.TextNode); ok {
+			n++
+		}
+		return true
+	})
+	if n == 0 {
+		t.Fatal("expected Inspect to find text nodes")
+	}
+}
@@ -0,0 +1,57 @@
+package edifact
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/kdar/health/edifact/parse"
+)
+
+// Quirks are the deviations from the standard a trading partner's
+// data has that decoding should put up with. See parse.Options.
+type Quirks = parse.Options
+
+// Named Quirks profiles.
+var (
+	// Only what the standard allows: an unpaired release indicator,
+	// newlines between segments and anything after the last segment
+	// are all errors.
+	QuirksStrict = Quirks{Release: parse.ReleaseError}
+
+	// For senders that follow the standard, but break the
+	// interchange into lines. Unpaired release indicators are kept
+	// as text.
+	QuirksLenient = Quirks{
+		Release:            parse.ReleaseKeep,
+		Newlines:           true,
+		TrailingWhitespace: true,
+	}
+
+	// What relayhealth sends: the release indicator is used as a
+	// space, quotes in text aren't released, and there are newlines
+	// between segments. This is what decoding uses by default.
+	QuirksRelayHealth = parse.DefaultOptions
+)
+
+var quirksProfiles = map[string]*Quirks{
+	"strict":      &QuirksStrict,
+	"lenient":     &QuirksLenient,
+	"relayhealth": &QuirksRelayHealth,
+}
+
+// QuirksProfile returns the Quirks profile with the name, e.g.
+// "strict" or "relayhealth", so trading partners can be configured
+// by name.
+func QuirksProfile(name string) (Quirks, error) {
+	q, ok := quirksProfiles[strings.ToLower(name)]
+	if !ok {
+		var names []string
+		for name := range quirksProfiles {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		return Quirks{}, fmt.Errorf("edifact: unknown quirks profile %q, want one of %s", name, strings.Join(names, ", "))
+	}
+	return *q, nil
+}
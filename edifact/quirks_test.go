@@ -0,0 +1,178 @@
+package edifact
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/iotest"
+	"time"
+
+	"github.com/kdar/health/edifact/parse"
+)
+
+var quirksTests = []struct {
+	in     string
+	quirks Quirks
+	out    []Values
+	err    error
+}{
+	{
+		"NAD+CVS?PHARMACY+A??B+C?:D'",
+		QuirksRelayHealth,
+		[]Values{{"NAD", "CVS PHARMACY", "A?B", "C:D"}},
+		nil,
+	},
+	{
+		"NAD+CVS?PHARMACY+A??B+C?:D'",
+		QuirksLenient,
+		[]Values{{"NAD", "CVS?PHARMACY", "A?B", "C:D"}},
+		nil,
+	},
+	{
+		"NAD+CVS?PHARMACY'",
+		Quirks{Release: parse.ReleaseDrop},
+		[]Values{{"NAD", "CVSPHARMACY"}},
+		nil,
+	},
+	{
+		"NAD+CVS?PHARMACY'",
+		QuirksStrict,
+		nil,
+		parse.ErrInvalidRelease,
+	},
+	{
+		// a released release indicator followed by a letter
+		"NAD+??PHARMACY+A?+B'",
+		QuirksStrict,
+		[]Values{{"NAD", "?PHARMACY", "A+B"}},
+		nil,
+	},
+	{
+		"BGM+1'\r\nDTM+2'\r\n",
+		QuirksLenient,
+		[]Values{{"BGM", "1"}, {"DTM", "2"}},
+		nil,
+	},
+	{
+		"BGM+1'\r\nDTM+2'",
+		QuirksStrict,
+		nil,
+		parse.ErrInvalidCharacter,
+	},
+	{
+		"BGM+1'DTM+2' \n",
+		QuirksStrict,
+		nil,
+		parse.ErrInvalidCharacter,
+	},
+	{
+		"BGM+1'DTM+2' \n",
+		Quirks{TrailingWhitespace: true},
+		[]Values{{"BGM", "1"}, {"DTM", "2"}},
+		nil,
+	},
+	{
+		"FTX+IT'S'DTM+2'",
+		QuirksRelayHealth,
+		[]Values{{"FTX", "IT'S"}, {"DTM", "2"}},
+		nil,
+	},
+	{
+		"FTX+IT'S'DTM+2'",
+		QuirksLenient,
+		nil,
+		parse.ErrInvalidCharacter,
+	},
+}
+
+func TestUnmarshalQuirks(t *testing.T) {
+	for i, tt := range quirksTests {
+		quirks := tt.quirks
+		out, err := UnmarshalOptions([]byte(tt.in), DecodeOptions{Quirks: &quirks})
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("%d. expected %v, got %v", i, tt.err, err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+
+		var segments []Values
+		for _, v := range out[1:] {
+			segments = append(segments, v.(Values))
+		}
+		if !reflect.DeepEqual(segments, tt.out) {
+			t.Fatalf("%d. mismatch\nhave: %#+v\nwant: %#+v", i, segments, tt.out)
+		}
+	}
+}
+
+func TestDecoderQuirks(t *testing.T) {
+	for i, tt := range quirksTests {
+		quirks := tt.quirks
+		d := NewDecoderOptions(iotest.OneByteReader(strings.NewReader(tt.in)), DecodeOptions{Quirks: &quirks})
+
+		var segments []Values
+		var err error
+		for {
+			var segment Values
+			if segment, err = d.Segment(); err != nil {
+				break
+			}
+			segments = append(segments, segment)
+		}
+
+		if tt.err != nil {
+			if !errors.Is(err, tt.err) {
+				t.Fatalf("%d. expected %v, got %v", i, tt.err, err)
+			}
+			continue
+		}
+		if err != io.EOF {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+		if !reflect.DeepEqual(segments, tt.out) {
+			t.Fatalf("%d. mismatch\nhave: %#+v\nwant: %#+v", i, segments, tt.out)
+		}
+	}
+}
+
+func TestQuirksProfile(t *testing.T) {
+	q, err := QuirksProfile("RelayHealth")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if q != QuirksRelayHealth {
+		t.Fatalf("expected %+v, got %+v", QuirksRelayHealth, q)
+	}
+
+	if _, err := QuirksProfile("nope"); err == nil {
+		t.Fatal("expected an error for an unknown profile")
+	}
+}
+
+func TestUnmarshalQuirksErrorsStopLexer(t *testing.T) {
+	before := runtime.NumGoroutine()
+
+	quirks := QuirksStrict
+	for i := 0; i < 100; i++ {
+		// the error is found long before the lexer is done
+		in := "NAD+CVS?PHARMACY'" + strings.Repeat("DTM+1'", 10)
+		if _, err := UnmarshalOptions([]byte(in), DecodeOptions{Quirks: &quirks}); !errors.Is(err, parse.ErrInvalidRelease) {
+			t.Fatalf("%d. expected %v, got %v", i, parse.ErrInvalidRelease, err)
+		}
+	}
+
+	// the lexers stop on their own once they see they were abandoned
+	for wait := 0; runtime.NumGoroutine() > before && wait < 100; wait++ {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := runtime.NumGoroutine(); n > before {
+		t.Fatalf("expected %d goroutines, got %d", before, n)
+	}
+}
@@ -0,0 +1,544 @@
+package edifact
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/kdar/health/edifact/parse"
+)
+
+// segments that start and end a message. UNH/UNT are the standard
+// ones, UIH/UIT are the interactive ones SCRIPT uses.
+var (
+	messageHeaders  = map[string]bool{UNH: true, UIH: true}
+	messageTrailers = map[string]bool{UNT: true, UIT: true}
+)
+
+// A Decoder reads EDIFACT segments from an input stream. Only one
+// segment is held in memory at a time, so it can be used on
+// interchanges too large to Unmarshal.
+type Decoder struct {
+	r   *bufio.Reader
+	hdr Header
+	raw bytes.Buffer
+
+	// where we are in the stream, and where the
+	// current segment starts, for errors
+	offset, line, column                int
+	segment                             int
+	startOffset, startLine, startColumn int
+
+	// the syntax level, see DecodeOptions
+	charset     Charset
+	detect      bool
+	charsetMode CharsetMode
+
+	// the deviations from the standard to put up with
+	quirks Quirks
+}
+
+// NewDecoder returns a new decoder that reads from r.
+func NewDecoder(r io.Reader) *Decoder {
+	return NewDecoderHeader(r, DefaultHeader)
+}
+
+// NewDecoderHeader returns a new decoder that reads from r, using the
+// delimiters in hdr until a UNA segment says otherwise.
+func NewDecoderHeader(r io.Reader, hdr Header) *Decoder {
+	return &Decoder{
+		r:      bufio.NewReader(r),
+		hdr:    hdr,
+		line:   1,
+		column: 1,
+		quirks: QuirksRelayHealth,
+	}
+}
+
+// NewDecoderOptions returns a new decoder that reads from r, handling
+// the syntax level and quirks like UnmarshalOptions does. In
+// CharsetReport mode, Segment returns the segment along with its
+// CharsetErrors.
+func NewDecoderOptions(r io.Reader, opts DecodeOptions) *Decoder {
+	hdr := opts.Header
+	if hdr == (Header{}) {
+		hdr = DefaultHeader
+	}
+
+	d := NewDecoderHeader(r, hdr)
+	d.charset = opts.Charset
+	d.detect = opts.Charset == ""
+	d.charsetMode = opts.CharsetMode
+	d.quirks = opts.quirks()
+	return d
+}
+
+// Header returns the UNA header in use. Until a UNA segment is read,
+// this is the one the Decoder was created with.
+func (d *Decoder) Header() Header {
+	return d.hdr
+}
+
+// Segment returns the next segment in the stream, in the same form
+// Unmarshal returns segments. UNA segments aren't returned, they just
+// change the Header used for the segments after it. At the end of the
+// stream, it returns io.EOF. Errors in the data are returned as a
+// *SyntaxError with its position in the stream.
+func (d *Decoder) Segment() (Values, error) {
+	if err := d.hdr.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := d.readSegment(); err != nil {
+		return nil, err
+	}
+
+	d.raw.WriteByte(d.hdr.SegmentTerminator())
+	text := d.raw.String()
+
+	root, err := parse.ParseOptions(text, d.hdr[1], d.quirks)
+	if err != nil {
+		return nil, d.rebase(err)
+	}
+
+	s := &state{
+		text:         text,
+		charset:      d.charset,
+		detect:       d.detect,
+		checkCharset: d.charsetMode != CharsetIgnore,
+	}
+	values := s.walk(root)
+	if s.err != nil {
+		return nil, d.rebase(s.err)
+	}
+	d.charset, d.detect = s.charset, s.detect
+
+	if len(values) != 1 {
+		return nil, d.rebase(&SyntaxError{
+			Err:    parse.ErrInvalidCharacter,
+			Msg:    fmt.Sprintf("invalid segment %q", text),
+			Line:   1,
+			Column: 1,
+		})
+	}
+
+	if len(s.invalid) > 0 {
+		for _, err := range s.invalid {
+			d.rebase(err)
+		}
+		if d.charsetMode == CharsetEnforce {
+			return nil, s.invalid[0]
+		}
+		return values[0].(Values), CharsetErrors(s.invalid)
+	}
+
+	return values[0].(Values), nil
+}
+
+// Message returns the next message in the stream: the segments from a
+// UNH (or UIH) up to and including its UNT (or UIT). Segments outside
+// of a message, such as UNB and UNZ, are returned on their own. At the
+// end of the stream, it returns io.EOF. In CharsetReport mode, the
+// CharsetErrors of all of the message's segments are returned with it.
+func (d *Decoder) Message() (Values, error) {
+	var invalid CharsetErrors
+
+	segment, err := d.nextSegment(&invalid)
+	if err != nil {
+		return nil, err
+	}
+
+	message := Values{segment}
+	if messageHeaders[SegmentName(segment)] {
+		for {
+			segment, err := d.nextSegment(&invalid)
+			if err == io.EOF {
+				return nil, d.errorf(parse.ErrUnexpectedEOF, "found eof while reading message")
+			}
+			if err != nil {
+				return nil, err
+			}
+
+			message = append(message, segment)
+			if messageTrailers[SegmentName(segment)] {
+				break
+			}
+		}
+	}
+
+	if len(invalid) > 0 {
+		return message, invalid
+	}
+	return message, nil
+}
+
+// reads the next segment, adding any CharsetErrors to invalid.
+func (d *Decoder) nextSegment(invalid *CharsetErrors) (Values, error) {
+	segment, err := d.Segment()
+	if errs, ok := err.(CharsetErrors); ok {
+		*invalid = append(*invalid, errs...)
+		err = nil
+	}
+	return segment, err
+}
+
+// readSegment reads the next segment into d.raw, without its
+// terminator. It reads any UNA segments along the way into d.hdr.
+func (d *Decoder) readSegment() error {
+	d.raw.Reset()
+
+	for {
+		// some companies put newlines between segments
+		if d.quirks.Newlines {
+			if err := d.skipNewlines(); err != nil {
+				return err
+			}
+		}
+
+		name, err := d.r.Peek(len(parse.UNA_SEGMENT_NAME))
+		if string(name) != parse.UNA_SEGMENT_NAME {
+			if err != nil && err != io.EOF {
+				return err
+			}
+			break
+		}
+
+		d.segment++
+		hdr := make([]byte, len(parse.UNA_SEGMENT_NAME)+6)
+		for i := range hdr {
+			c, err := d.readByte()
+			if err == io.EOF {
+				err := d.errorf(parse.ErrUnexpectedEOF, "found eof while reading UNA header")
+				err.Tag = parse.UNA_SEGMENT_NAME
+				return err
+			}
+			if err != nil {
+				return err
+			}
+			hdr[i] = c
+		}
+		d.hdr = Header{string(hdr[:3]), string(hdr[3:])}
+	}
+
+	d.startOffset, d.startLine, d.startColumn = d.offset, d.line, d.column
+
+	release := d.hdr.ReleaseIndicator()
+	terminator := d.hdr.SegmentTerminator()
+
+	for {
+		c, err := d.readByte()
+		if err == io.EOF {
+			if d.raw.Len() == 0 {
+				return io.EOF
+			}
+			if d.quirks.TrailingWhitespace && isTrailingSpace(d.raw.Bytes()) {
+				d.raw.Reset()
+				return io.EOF
+			}
+
+			// let parse say where in the segment it ended
+			if _, err := parse.ParseOptions(d.raw.String(), d.hdr[1], d.quirks); err != nil {
+				return d.rebase(err)
+			}
+			return d.errorf(parse.ErrUnexpectedEOF, "found eof while reading data")
+		}
+		if err != nil {
+			return err
+		}
+
+		if d.raw.Len() == 0 {
+			d.segment++
+		}
+
+		switch c {
+		case release:
+			// keep the release indicator and what it escapes as is,
+			// parse takes care of it.
+			d.raw.WriteByte(c)
+			if c, err = d.readByte(); err == nil {
+				d.raw.WriteByte(c)
+			}
+		case terminator:
+			// see lexData in edifact/parse for why we do this.
+			// the quote is escaped so it's taken as text when the
+			// segment is parsed on its own.
+			if d.quirks.UnescapedTerminator && isQuote(c) && !d.startsSegment() {
+				d.raw.WriteByte(release)
+				d.raw.WriteByte(c)
+				continue
+			}
+			return nil
+		default:
+			d.raw.WriteByte(c)
+		}
+	}
+}
+
+// startsSegment reports whether the upcoming data looks like the
+// start of a segment (three upper case letters and a data
+// delimiter, or a UNA segment, possibly after newlines), or the end
+// of the stream.
+func (d *Decoder) startsSegment() bool {
+	for n := 4; ; n++ {
+		next, _ := d.r.Peek(n)
+		if len(next) < n {
+			return true
+		}
+
+		next = next[n-4:]
+		if d.quirks.Newlines && (next[0] == '\n' || next[0] == '\r') {
+			continue
+		}
+
+		if string(next[:3]) == parse.UNA_SEGMENT_NAME {
+			return true
+		}
+
+		for _, c := range next[:3] {
+			if !unicode.IsUpper(rune(c)) {
+				return false
+			}
+		}
+
+		return next[3] == d.hdr.DataDelimiter()
+	}
+}
+
+func (d *Decoder) skipNewlines() error {
+	for {
+		next, err := d.r.Peek(1)
+		if err != nil {
+			if err == io.EOF {
+				return nil
+			}
+			return err
+		}
+
+		if next[0] != '\n' && next[0] != '\r' {
+			return nil
+		}
+		d.readByte()
+	}
+}
+
+// reports whether b is nothing but spaces and end-of-line characters.
+func isTrailingSpace(b []byte) bool {
+	return len(bytes.TrimLeft(b, " \t\r\n")) == 0
+}
+
+// reads a byte, keeping track of where we are.
+func (d *Decoder) readByte() (byte, error) {
+	c, err := d.r.ReadByte()
+	if err != nil {
+		return c, err
+	}
+
+	d.offset++
+	switch {
+	case c == '\n':
+		d.line++
+		d.column = 1
+	case utf8.RuneStart(c):
+		d.column++
+	}
+
+	return c, nil
+}
+
+// moves the position of a SyntaxError from parsing the current
+// segment on its own to where it is in the stream.
+func (d *Decoder) rebase(err error) error {
+	serr, ok := err.(*SyntaxError)
+	if !ok {
+		return err
+	}
+
+	serr.Offset += d.startOffset
+	if serr.Line == 1 {
+		serr.Column += d.startColumn - 1
+	}
+	serr.Line += d.startLine - 1
+	serr.Segment = d.segment
+
+	return serr
+}
+
+// returns a SyntaxError at the current position.
+func (d *Decoder) errorf(kind error, format string, args ...interface{}) *SyntaxError {
+	return &SyntaxError{
+		Err:     kind,
+		Msg:     fmt.Sprintf(format, args...),
+		Offset:  d.offset,
+		Line:    d.line,
+		Column:  d.column,
+		Segment: d.segment,
+	}
+}
+
+func isQuote(c byte) bool {
+	return c == '\'' || c == '"'
+}
+
+// An Encoder writes EDIFACT segments to an output stream. Segments are
+// escaped as they're written, without reflection or regular
+// expressions, and the buffer a segment is written to is reused, so
+// encoding strings, []byte and Values doesn't allocate.
+type Encoder struct {
+	w    io.Writer
+	hdr  Header
+	opts EncodeOptions
+	buf  []byte
+
+	// the bytes that are released in text
+	special [256]bool
+	// the number of segments written, for errors
+	segment int
+}
+
+// NewEncoder returns a new encoder that writes to w, using the
+// DefaultHeader delimiters until Header is called.
+func NewEncoder(w io.Writer) *Encoder {
+	return NewEncoderOptions(w, EncodeOptions{})
+}
+
+// NewEncoderOptions returns a new encoder that writes to w, handling
+// the options like MarshalOptions does.
+func NewEncoderOptions(w io.Writer, opts EncodeOptions) *Encoder {
+	e := &Encoder{w: w, opts: opts}
+	e.setHeader(DefaultHeader)
+	return e
+}
+
+// Header writes hdr as a UNA segment, unless it's the DefaultHeader
+// and the OmitDefaultUNA option is set, and uses its delimiters for
+// the segments after it.
+func (e *Encoder) Header(hdr Header) error {
+	if err := hdr.validate(); err != nil {
+		return err
+	}
+
+	e.setHeader(hdr)
+	if e.opts.OmitDefaultUNA && hdr == DefaultHeader {
+		return nil
+	}
+
+	e.buf = append(e.buf[:0], hdr[0]...)
+	e.buf = append(e.buf, hdr[1]...)
+	_, err := e.w.Write(e.buf)
+	return err
+}
+
+// Segment writes a segment: its tag followed by its data elements,
+// each a string, []byte, or Values for a composite or repetitions.
+func (e *Encoder) Segment(segment Values) error {
+	e.segment++
+	e.buf = e.buf[:0]
+
+	for i, v := range segment {
+		if i > 0 {
+			e.buf = append(e.buf, e.hdr.DataDelimiter())
+		}
+
+		sep := e.hdr.ComponentDelimiter()
+		if values, ok := v.(Values); ok && isRepetition(values) {
+			if !e.hdr.repeats() {
+				return fmt.Errorf("edifact: segment %d: repetitions without a repetition delimiter", e.segment)
+			}
+			sep = e.hdr.RepetitionDelimiter()
+		}
+		if err := e.value(v, sep); err != nil {
+			return fmt.Errorf("edifact: segment %d: %s", e.segment, err)
+		}
+	}
+
+	e.buf = append(e.buf, e.hdr.SegmentTerminator())
+	_, err := e.w.Write(e.buf)
+	return err
+}
+
+// Encode writes values, which are segments and Headers, like Marshal
+// returns them.
+func (e *Encoder) Encode(values Values) error {
+	for _, v := range values {
+		var err error
+		switch v := v.(type) {
+		case Header:
+			err = e.Header(v)
+		case Values:
+			err = e.Segment(v)
+		default:
+			err = fmt.Errorf("edifact: unknown data type %T", v)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *Encoder) setHeader(hdr Header) {
+	e.hdr = hdr
+	e.special = [256]bool{}
+	for _, c := range []byte{hdr.ComponentDelimiter(), hdr.DataDelimiter(), hdr.ReleaseIndicator(),
+		hdr.SegmentTerminator()} {
+		e.special[c] = true
+	}
+	if hdr.repeats() {
+		e.special[hdr.RepetitionDelimiter()] = true
+	}
+}
+
+// appends v to the buffer. The values of Values are separated by sep,
+// and the ones in them by the component delimiter.
+func (e *Encoder) value(v interface{}, sep byte) error {
+	switch v := v.(type) {
+	case string:
+		return e.text(v)
+	case []byte:
+		return e.text(string(v))
+	case Values:
+		for i, v := range v {
+			if i > 0 {
+				e.buf = append(e.buf, sep)
+			}
+			if err := e.value(v, e.hdr.ComponentDelimiter()); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	return fmt.Errorf("unknown data type %T", v)
+}
+
+// appends the text to the buffer, in the syntax level and with the
+// delimiters in it released.
+func (e *Encoder) text(s string) error {
+	if c := e.opts.Charset; c != "" {
+		if e.opts.Transliterate {
+			s = c.transliterate(s)
+		} else if r, ok := c.invalid(s); ok {
+			return fmt.Errorf("%q is not in %s", r, c)
+		}
+	}
+
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		if c >= utf8.RuneSelf && e.opts.Charset == UNOC {
+			// ISO 8859-1, which the charset was checked to be in
+			r, w := utf8.DecodeRuneInString(s[i:])
+			e.buf = append(e.buf, byte(r))
+			i += w - 1
+			continue
+		}
+
+		if e.special[c] {
+			e.buf = append(e.buf, e.hdr.ReleaseIndicator())
+		}
+		e.buf = append(e.buf, c)
+	}
+	return nil
+}
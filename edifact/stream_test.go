@@ -0,0 +1,149 @@
+package edifact
+
+import (
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+	"testing/iotest"
+)
+
+func TestDecoderSegment(t *testing.T) {
+	for i, tt := range unmarshalTests {
+		// read a byte at a time to make sure we don't rely on
+		// having the whole input
+		d := NewDecoder(iotest.OneByteReader(strings.NewReader(string(tt.in))))
+
+		var out Values
+		for {
+			segment, err := d.Segment()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%d. received error: %s", i, err)
+			}
+
+			if len(out) == 0 {
+				out = append(out, d.Header())
+			}
+			out = append(out, segment)
+		}
+
+		if !reflect.DeepEqual(out, tt.out) {
+			t.Fatalf("%d. mismatch\nhave: %#+v\nwant: %#+v", i, out, tt.out)
+		}
+	}
+}
+
+var decoderMessageTests = []struct {
+	in  string
+	out []Values
+}{
+	{
+		"UNB+UNOA:1+S+R+130113:0516+1'UNH+1+MSG:D:96A:UN'BGM+1'UNT+3+1'UNH+2+MSG:D:96A:UN'UNT+2+2'UNZ+2+1'",
+		[]Values{
+			{Values{"UNB", Values{"UNOA", "1"}, "S", "R", Values{"130113", "0516"}, "1"}},
+			{
+				Values{"UNH", "1", Values{"MSG", "D", "96A", "UN"}},
+				Values{"BGM", "1"},
+				Values{"UNT", "3", "1"},
+			},
+			{
+				Values{"UNH", "2", Values{"MSG", "D", "96A", "UN"}},
+				Values{"UNT", "2", "2"},
+			},
+			{Values{"UNZ", "2", "1"}},
+		},
+	},
+	{
+		CRAZY_IN1,
+		[]Values{
+			{CRAZY_OUT1[1]},
+			{CRAZY_OUT1[2], CRAZY_OUT1[3], CRAZY_OUT1[4]},
+			{CRAZY_OUT1[5]},
+		},
+	},
+	// newlines between segments
+	{
+		"UNH+1'\r\nUNT+2+1'\n",
+		[]Values{
+			{Values{"UNH", "1"}, Values{"UNT", "2", "1"}},
+		},
+	},
+}
+
+func TestDecoderMessage(t *testing.T) {
+	for i, tt := range decoderMessageTests {
+		d := NewDecoder(strings.NewReader(tt.in))
+
+		var out []Values
+		for {
+			message, err := d.Message()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				t.Fatalf("%d. received error: %s", i, err)
+			}
+			out = append(out, message)
+		}
+
+		if !reflect.DeepEqual(out, tt.out) {
+			t.Fatalf("%d. mismatch\nhave: %#+v\nwant: %#+v", i, out, tt.out)
+		}
+	}
+}
+
+func TestDecoderErrors(t *testing.T) {
+	tests := []string{
+		"UNA:+",
+		"UNH+1'BGM+1",
+		"UNH+1'BGM+1'",
+	}
+
+	for i, in := range tests {
+		d := NewDecoder(strings.NewReader(in))
+
+		var err error
+		for err == nil {
+			_, err = d.Message()
+		}
+
+		if err == io.EOF {
+			t.Fatalf("%d. expected an error, got io.EOF", i)
+		}
+	}
+}
+
+func TestDecoderSyntaxError(t *testing.T) {
+	for i, tt := range syntaxErrorTests {
+		d := NewDecoder(strings.NewReader(tt.in))
+
+		var err error
+		for err == nil {
+			_, err = d.Segment()
+		}
+
+		checkSyntaxError(t, i, err, tt.out)
+	}
+}
+
+func TestNewDecoderHeader(t *testing.T) {
+	hdr := Header{"UNA", "~|.?^'"}
+	d := NewDecoderHeader(strings.NewReader("TES|a~b|c^d'"), hdr)
+
+	segment, err := d.Segment()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := Values{"TES", Values{"a", "b"}, Values{"c", "d"}}
+	if !reflect.DeepEqual(segment, expected) {
+		t.Fatalf("mismatch\nhave: %#+v\nwant: %#+v", segment, expected)
+	}
+
+	if d.Header() != hdr {
+		t.Fatalf("expected header %v, got %v", hdr, d.Header())
+	}
+}
@@ -0,0 +1,668 @@
+package edifact
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/kdar/health/edifact/parse"
+)
+
+// Struct tags
+//
+// UnmarshalValues, MarshalValues, UnmarshalSegment and MarshalSegment
+// map segments to struct fields using tags of the form
+//
+//	`edifact:"PTT,3.1"`
+//
+// which is the first component of data element 3 of the PTT segment.
+// Data elements and components both start at 1; without a component
+// the field gets the whole data element. The segment can be left out
+// (`edifact:"3.1"`) in a struct used with UnmarshalSegment or
+// MarshalSegment, or in a struct tagged with just a segment name:
+//
+//	type Message struct {
+//		Patient Patient `edifact:"PTT"`
+//		Drugs   []Drug  `edifact:"DRU"` // one for each DRU segment
+//	}
+//
+// A struct field tagged with a data element is a composite, and its
+// own fields are tagged with just the component (`edifact:"2"`). A
+// slice field tagged with a data element gets one value for each
+// repetition.
+//
+// Fields can be strings, numbers, encoding.TextUnmarshalers (and
+// TextMarshalers), structs, pointers and slices of them. Floats are
+// read with either decimal mark, and written with a point, or the
+// decimal mark of the header passed to MarshalValuesHeader. Fields
+// tagged with "-" and untagged fields that aren't embedded structs
+// are skipped.
+
+// a parsed struct tag
+type tag struct {
+	segment   string
+	element   int
+	component int
+}
+
+func parseTag(field reflect.StructField) (t tag, ok bool, err error) {
+	s := field.Tag.Get("edifact")
+	if s == "" || s == "-" {
+		return t, false, nil
+	}
+
+	parts := strings.SplitN(s, ",", 2)
+	position := parts[0]
+	if len(parts) == 2 {
+		t.segment = parts[0]
+		position = parts[1]
+	} else if !isPosition(position) {
+		t.segment = position
+		position = ""
+	}
+
+	if position != "" {
+		parts = strings.SplitN(position, ".", 2)
+		if t.element, err = strconv.Atoi(parts[0]); err != nil || t.element < 1 {
+			return t, false, fmt.Errorf("edifact: invalid tag %q on field %s", s, field.Name)
+		}
+		if len(parts) == 2 {
+			if t.component, err = strconv.Atoi(parts[1]); err != nil || t.component < 1 {
+				return t, false, fmt.Errorf("edifact: invalid tag %q on field %s", s, field.Name)
+			}
+		}
+	}
+
+	return t, true, nil
+}
+
+// reports whether s starts like a position (e.g. 3.1) instead
+// of a segment name.
+func isPosition(s string) bool {
+	return s != "" && s[0] >= '0' && s[0] <= '9'
+}
+
+// UnmarshalValues fills in the struct pointed to by v from segments,
+// like the ones Unmarshal returns, based on the struct tags of its
+// fields. If a segment appears more than once, fields that aren't
+// slices get the last one.
+func UnmarshalValues(segments Values, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("edifact: interface must be a non-nil pointer to a struct")
+	}
+
+	for _, segment := range segments {
+		segment, ok := segment.(Values)
+		if !ok {
+			continue
+		}
+
+		if err := unmarshalMessage(segment, rv.Elem()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// UnmarshalSegment fills in the struct pointed to by v from segment,
+// based on the struct tags of its fields.
+func UnmarshalSegment(segment Values, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return errors.New("edifact: interface must be a non-nil pointer to a struct")
+	}
+
+	return unmarshalSegment(segment, SegmentName(segment), rv.Elem())
+}
+
+// fills in the fields of dst that segment maps to.
+func unmarshalMessage(segment Values, dst reflect.Value) error {
+	name := SegmentName(segment)
+	typ := dst.Type()
+
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		fieldValue := dst.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		t, ok, err := parseTag(field)
+		if err != nil {
+			return err
+		}
+
+		switch {
+		case !ok:
+			if field.Anonymous && field.Tag.Get("edifact") != "-" && indirectType(field.Type).Kind() == reflect.Struct {
+				if err := unmarshalMessage(segment, indirect(fieldValue)); err != nil {
+					return err
+				}
+			}
+		case t.segment != name:
+		case t.element > 0:
+			if err := unmarshalElement(segment, t, fieldValue); err != nil {
+				return err
+			}
+		case fieldValue.Kind() == reflect.Slice:
+			elem := reflect.New(fieldValue.Type().Elem()).Elem()
+			if err := unmarshalSegment(segment, name, indirect(elem)); err != nil {
+				return err
+			}
+			fieldValue.Set(reflect.Append(fieldValue, elem))
+		default:
+			if err := unmarshalSegment(segment, name, indirect(fieldValue)); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// fills in the fields of the segment struct dst.
+func unmarshalSegment(segment Values, name string, dst reflect.Value) error {
+	if dst.Kind() != reflect.Struct {
+		return fmt.Errorf("edifact: %s can't be unmarshaled into %s", name, dst.Type())
+	}
+
+	typ := dst.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		fieldValue := dst.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		t, ok, err := parseTag(field)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			if field.Anonymous && field.Tag.Get("edifact") != "-" && indirectType(field.Type).Kind() == reflect.Struct {
+				if err := unmarshalSegment(segment, name, indirect(fieldValue)); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if t.element == 0 || (t.segment != "" && t.segment != name) {
+			continue
+		}
+
+		if err := unmarshalElement(segment, t, fieldValue); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func unmarshalElement(segment Values, t tag, dst reflect.Value) error {
+	src := Component(Element(segment, t.element), t.component)
+
+	if err := unmarshalData(src, dst); err != nil {
+		if t.component > 0 {
+			return fmt.Errorf("edifact: %s.%d.%d: %s", SegmentName(segment), t.element, t.component, err)
+		}
+		return fmt.Errorf("edifact: %s.%d: %s", SegmentName(segment), t.element, err)
+	}
+
+	return nil
+}
+
+// reports whether values holds repetitions: if any of them is a
+// composite, such as "a*b:c". Otherwise it's a composite.
+func isRepetition(values Values) bool {
+	for _, v := range values {
+		if _, ok := v.(Values); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func unmarshalData(src interface{}, dst reflect.Value) error {
+	if dst.CanAddr() {
+		if u, ok := dst.Addr().Interface().(encoding.TextUnmarshaler); ok {
+			s := Text(src)
+			if s == "" {
+				return nil
+			}
+			return u.UnmarshalText([]byte(s))
+		}
+	}
+
+	switch dst.Kind() {
+	case reflect.String:
+		dst.SetString(Text(src))
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s := Text(src); s != "" {
+			n, err := strconv.ParseInt(s, 10, dst.Type().Bits())
+			if err != nil {
+				return err
+			}
+			dst.SetInt(n)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s := Text(src); s != "" {
+			n, err := strconv.ParseUint(s, 10, dst.Type().Bits())
+			if err != nil {
+				return err
+			}
+			dst.SetUint(n)
+		}
+	case reflect.Float32, reflect.Float64:
+		if s := Text(src); s != "" {
+			n, err := normalizeNumber(s)
+			if err != nil {
+				return err
+			}
+			f, err := strconv.ParseFloat(n, dst.Type().Bits())
+			if err != nil {
+				return err
+			}
+			dst.SetFloat(f)
+		}
+	case reflect.Ptr:
+		if IsEmpty(src) {
+			return nil
+		}
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		return unmarshalData(src, dst.Elem())
+	case reflect.Struct:
+		return unmarshalComposite(src, dst)
+	case reflect.Slice:
+		for _, repetition := range Repetitions(src, indirectType(dst.Type().Elem()).Kind() == reflect.Struct) {
+			elem := reflect.New(dst.Type().Elem()).Elem()
+			if err := unmarshalData(repetition, elem); err != nil {
+				return err
+			}
+			dst.Set(reflect.Append(dst, elem))
+		}
+	default:
+		return fmt.Errorf("unsupported type: %s", dst.Type())
+	}
+
+	return nil
+}
+
+func unmarshalComposite(src interface{}, dst reflect.Value) error {
+	typ := dst.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		t, ok, err := parseTag(field)
+		if err != nil {
+			return err
+		}
+		if !ok || t.element == 0 {
+			continue
+		}
+
+		// in a composite the first number is the component
+		if err := unmarshalData(Component(src, t.element), dst.Field(i)); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// MarshalValues returns the segments for the struct v, based on the
+// struct tags of its fields. Segments are in the order their first
+// field appears in the struct.
+func MarshalValues(v interface{}) (Values, error) {
+	return marshalValues(v, parse.UNA_DECIMAL)
+}
+
+// MarshalValuesHeader is like MarshalValues, but numbers are written
+// with the decimal mark of hdr, and hdr is the first value returned
+// so Marshal uses its delimiters.
+func MarshalValuesHeader(v interface{}, hdr Header) (Values, error) {
+	if err := hdr.validate(); err != nil {
+		return nil, err
+	}
+
+	values, err := marshalValues(v, hdr.Decimal())
+	if err != nil {
+		return nil, err
+	}
+	return append(Values{hdr}, values...), nil
+}
+
+func marshalValues(v interface{}, decimal byte) (Values, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil, errors.New("edifact: interface must be a struct or a pointer to one")
+	}
+
+	e := &encodeState{index: map[string]int{}, decimal: decimal}
+	if err := e.marshalMessage(rv); err != nil {
+		return nil, err
+	}
+
+	for i, segment := range e.segments {
+		e.segments[i] = trimValues(segment.(Values))
+	}
+	return e.segments, nil
+}
+
+// MarshalSegment returns the segment name for the struct v, based on
+// the struct tags of its fields.
+func MarshalSegment(name string, v interface{}) (Values, error) {
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return nil, errors.New("edifact: interface must be a struct or a pointer to one")
+	}
+
+	e := &encodeState{decimal: parse.UNA_DECIMAL}
+	segment := Values{name}
+	if err := e.marshalSegment(rv, name, &segment); err != nil {
+		return nil, err
+	}
+	return trimValues(segment), nil
+}
+
+// our state for marshaling a struct into segments
+type encodeState struct {
+	segments Values
+	// the index in segments of the segments filled in by tags
+	// with a data element
+	index map[string]int
+	// the decimal mark to write numbers with
+	decimal byte
+}
+
+func (e *encodeState) marshalMessage(src reflect.Value) error {
+	typ := src.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		fieldValue := src.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		t, ok, err := parseTag(field)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			if field.Anonymous && field.Tag.Get("edifact") != "-" && indirectType(field.Type).Kind() == reflect.Struct {
+				if fieldValue = reflect.Indirect(fieldValue); fieldValue.IsValid() {
+					if err := e.marshalMessage(fieldValue); err != nil {
+						return err
+					}
+				}
+			}
+			continue
+		}
+
+		if t.segment == "" {
+			return fmt.Errorf("edifact: tag on field %s has no segment", field.Name)
+		}
+
+		if t.element > 0 {
+			n, ok := e.index[t.segment]
+			if !ok {
+				n = len(e.segments)
+				e.index[t.segment] = n
+				e.segments = append(e.segments, Values{t.segment})
+			}
+
+			segment := e.segments[n].(Values)
+			if err := e.marshalElement(fieldValue, t, &segment); err != nil {
+				return err
+			}
+			e.segments[n] = segment
+			continue
+		}
+
+		var structs []reflect.Value
+		if fieldValue.Kind() == reflect.Slice {
+			for j := 0; j < fieldValue.Len(); j++ {
+				structs = append(structs, fieldValue.Index(j))
+			}
+		} else {
+			structs = append(structs, fieldValue)
+		}
+
+		for _, s := range structs {
+			if s = reflect.Indirect(s); !s.IsValid() {
+				continue
+			}
+
+			segment := Values{t.segment}
+			if err := e.marshalSegment(s, t.segment, &segment); err != nil {
+				return err
+			}
+			e.segments = append(e.segments, segment)
+		}
+	}
+
+	return nil
+}
+
+func (e *encodeState) marshalSegment(src reflect.Value, name string, segment *Values) error {
+	if src.Kind() != reflect.Struct {
+		return fmt.Errorf("edifact: %s can't be marshaled into %s", src.Type(), name)
+	}
+
+	typ := src.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		fieldValue := src.Field(i)
+		if field.PkgPath != "" && !field.Anonymous {
+			continue
+		}
+
+		t, ok, err := parseTag(field)
+		if err != nil {
+			return err
+		}
+
+		if !ok {
+			if field.Anonymous && field.Tag.Get("edifact") != "-" && indirectType(field.Type).Kind() == reflect.Struct {
+				if fieldValue = reflect.Indirect(fieldValue); fieldValue.IsValid() {
+					if err := e.marshalSegment(fieldValue, name, segment); err != nil {
+						return err
+					}
+				}
+			}
+			continue
+		}
+
+		if t.element == 0 || (t.segment != "" && t.segment != name) {
+			continue
+		}
+
+		if err := e.marshalElement(fieldValue, t, segment); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (e *encodeState) marshalElement(src reflect.Value, t tag, segment *Values) error {
+	data, err := e.marshalData(src)
+	if err != nil {
+		if t.component > 0 {
+			return fmt.Errorf("edifact: %s.%d.%d: %s", t.segment, t.element, t.component, err)
+		}
+		return fmt.Errorf("edifact: %s.%d: %s", t.segment, t.element, err)
+	}
+
+	for len(*segment) <= t.element {
+		*segment = append(*segment, "")
+	}
+
+	if t.component == 0 {
+		(*segment)[t.element] = data
+		return nil
+	}
+
+	composite, ok := (*segment)[t.element].(Values)
+	if !ok {
+		composite = Values{(*segment)[t.element]}
+	}
+	for len(composite) < t.component {
+		composite = append(composite, "")
+	}
+	composite[t.component-1] = data
+	(*segment)[t.element] = composite
+
+	return nil
+}
+
+func (e *encodeState) marshalData(src reflect.Value) (interface{}, error) {
+	if m, ok := textMarshaler(src); ok {
+		b, err := m.MarshalText()
+		return string(b), err
+	}
+
+	switch src.Kind() {
+	case reflect.String:
+		return src.String(), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(src.Int(), 10), nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(src.Uint(), 10), nil
+	case reflect.Float32, reflect.Float64:
+		f := src.Float()
+		if math.IsNaN(f) || math.IsInf(f, 0) {
+			return nil, fmt.Errorf("unsupported value: %v", f)
+		}
+		return formatNumber(f, src.Type().Bits(), e.decimal), nil
+	case reflect.Ptr:
+		if src.IsNil() {
+			return "", nil
+		}
+		return e.marshalData(src.Elem())
+	case reflect.Struct:
+		return e.marshalComposite(src)
+	case reflect.Slice:
+		var repetitions Values
+		for i := 0; i < src.Len(); i++ {
+			data, err := e.marshalData(src.Index(i))
+			if err != nil {
+				return nil, err
+			}
+
+			// each repetition has to be a Values so Marshal
+			// uses the repetition delimiter between them.
+			if _, ok := data.(Values); !ok {
+				data = Values{data}
+			}
+			repetitions = append(repetitions, data)
+		}
+
+		if len(repetitions) == 0 {
+			return "", nil
+		}
+		return repetitions, nil
+	}
+
+	return nil, fmt.Errorf("unsupported type: %s", src.Type())
+}
+
+// returns src as an encoding.TextMarshaler if it implements it.
+func textMarshaler(src reflect.Value) (encoding.TextMarshaler, bool) {
+	if src.Kind() == reflect.Ptr && src.IsNil() {
+		return nil, false
+	}
+	if m, ok := src.Interface().(encoding.TextMarshaler); ok {
+		return m, true
+	}
+	if src.CanAddr() {
+		m, ok := src.Addr().Interface().(encoding.TextMarshaler)
+		return m, ok
+	}
+	return nil, false
+}
+
+func (e *encodeState) marshalComposite(src reflect.Value) (interface{}, error) {
+	var composite Values
+
+	typ := src.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		t, ok, err := parseTag(field)
+		if err != nil {
+			return nil, err
+		}
+		if !ok || t.element == 0 {
+			continue
+		}
+
+		data, err := e.marshalData(src.Field(i))
+		if err != nil {
+			return nil, err
+		}
+
+		for len(composite) < t.element {
+			composite = append(composite, "")
+		}
+		composite[t.element-1] = data
+	}
+
+	return composite, nil
+}
+
+// removes the empty data elements (or components) at the end of
+// values, and does the same for the composites in it.
+func trimValues(values Values) Values {
+	for i, v := range values {
+		if composite, ok := v.(Values); ok && !isRepetition(composite) {
+			composite = trimValues(composite)
+			switch len(composite) {
+			case 0:
+				values[i] = ""
+			case 1:
+				values[i] = composite[0]
+			default:
+				values[i] = composite
+			}
+		}
+	}
+
+	for len(values) > 0 && IsEmpty(values[len(values)-1]) {
+		values = values[:len(values)-1]
+	}
+	return values
+}
+
+func indirect(v reflect.Value) reflect.Value {
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return v.Elem()
+	}
+	return v
+}
+
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
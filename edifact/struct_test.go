@@ -0,0 +1,234 @@
+package edifact
+
+import (
+	"math"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type structDate struct {
+	time.Time
+}
+
+func (d *structDate) UnmarshalText(text []byte) (err error) {
+	d.Time, err = time.Parse("20060102", string(text))
+	return err
+}
+
+func (d structDate) MarshalText() ([]byte, error) {
+	return []byte(d.Format("20060102")), nil
+}
+
+type structName struct {
+	Last  string `edifact:"1"`
+	First string `edifact:"2"`
+}
+
+type structReference struct {
+	Qualifier string `edifact:"1"`
+	Date      string `edifact:"2"`
+	Format    string `edifact:"3"`
+}
+
+type structPatient struct {
+	Qualifier int        `edifact:"1"`
+	Dob       structDate `edifact:"2"`
+	Name      structName `edifact:"3"`
+	Gender    string     `edifact:"4"`
+	Zip       string     `edifact:"6.4"`
+}
+
+type structDrug struct {
+	Code       string            `edifact:"1.2"`
+	Quantity   float64           `edifact:"2"`
+	References []structReference `edifact:"3"`
+	Notes      []string          `edifact:"4"`
+}
+
+type structHeader struct {
+	Type    string `edifact:"UIH,1.4"`
+	Version string `edifact:"UIH,1.2"`
+	Ref     string `edifact:"UIH,2"`
+}
+
+type structMessage struct {
+	structHeader
+	Patient *structPatient `edifact:"PTT"`
+	Drugs   []structDrug   `edifact:"DRU"`
+	Count   int            `edifact:"UIT,2"`
+	Ignored string         `edifact:"-"`
+}
+
+var structTests = []struct {
+	values Values
+	out    structMessage
+}{
+	{
+		Values{
+			Values{"UIH", Values{"SCRIPT", "008", "001", "RXHRES"}, "REF1"},
+			Values{"PTT", "1", "19900807", Values{"Smith", "John"}, "M", "", Values{"", "", "", "32385"}},
+			Values{"DRU", Values{"P", "Lipitor"}, "30.5", Values{Values{"07", "20120115", "102"}, Values{"36", "20130113", "102"}}},
+			Values{"DRU", Values{"P", "Advil"}, "", Values{"85", "20121001", "102"}, Values{Values{"a"}, Values{"b"}}},
+			Values{"UIT", "REF1", "5"},
+		},
+		structMessage{
+			structHeader: structHeader{Type: "RXHRES", Version: "008", Ref: "REF1"},
+			Patient: &structPatient{
+				Qualifier: 1,
+				Dob:       structDate{time.Date(1990, 8, 7, 0, 0, 0, 0, time.UTC)},
+				Name:      structName{"Smith", "John"},
+				Gender:    "M",
+				Zip:       "32385",
+			},
+			Drugs: []structDrug{
+				{
+					Code:     "Lipitor",
+					Quantity: 30.5,
+					References: []structReference{
+						{"07", "20120115", "102"},
+						{"36", "20130113", "102"},
+					},
+				},
+				{
+					Code:       "Advil",
+					References: []structReference{{"85", "20121001", "102"}},
+					Notes:      []string{"a", "b"},
+				},
+			},
+			Count: 5,
+		},
+	},
+}
+
+func TestUnmarshalValues(t *testing.T) {
+	for i, tt := range structTests {
+		var out structMessage
+		if err := UnmarshalValues(tt.values, &out); err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+
+		if !reflect.DeepEqual(out, tt.out) {
+			t.Fatalf("%d. mismatch\nhave: %#+v\nwant: %#+v", i, out, tt.out)
+		}
+	}
+}
+
+func TestMarshalValues(t *testing.T) {
+	for i, tt := range structTests {
+		values, err := MarshalValues(tt.out)
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+
+		// the components we don't have fields for are lost, and
+		// numbers are always written (use a pointer to leave one out)
+		expected := Values{
+			Values{"UIH", Values{"", "008", "", "RXHRES"}, "REF1"},
+			tt.values[1],
+			Values{"DRU", Values{"", "Lipitor"}, "30.5", tt.values[2].(Values)[3]},
+			Values{"DRU", Values{"", "Advil"}, "0", Values{Values{"85", "20121001", "102"}}, tt.values[3].(Values)[4]},
+			Values{"UIT", "", "5"},
+		}
+
+		if !reflect.DeepEqual(values, expected) {
+			t.Fatalf("%d. mismatch\nhave: %#+v\nwant: %#+v", i, values, expected)
+		}
+
+		// and back again
+		var out structMessage
+		if err := UnmarshalValues(values, &out); err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+		if !reflect.DeepEqual(out, tt.out) {
+			t.Fatalf("%d. mismatch\nhave: %#+v\nwant: %#+v", i, out, tt.out)
+		}
+	}
+}
+
+func TestSegment(t *testing.T) {
+	segment := Values{"PTT", "1", "19900807", Values{"Smith", "John"}, "M", "", Values{"", "", "", "32385"}}
+
+	var patient structPatient
+	if err := UnmarshalSegment(segment, &patient); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := MarshalSegment("PTT", &patient)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(out, segment) {
+		t.Fatalf("mismatch\nhave: %#+v\nwant: %#+v", out, segment)
+	}
+
+	// make sure it marshals with the right delimiters
+	b, err := Marshal(Values{out})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != "PTT+1+19900807+Smith:John+M++:::32385'" {
+		t.Fatalf("unexpected output: %s", b)
+	}
+}
+
+func TestUnmarshalSegmentErrors(t *testing.T) {
+	var patient structPatient
+	if err := UnmarshalSegment(Values{"PTT", "X"}, &patient); err == nil {
+		t.Fatal("expected an error for a bad number")
+	}
+
+	var bad struct {
+		Field string `edifact:"PTT,x"`
+	}
+	if err := UnmarshalSegment(Values{"PTT", "X"}, &bad); err == nil {
+		t.Fatal("expected an error for a bad tag")
+	}
+}
+
+func TestMarshalValuesErrors(t *testing.T) {
+	for i, f := range []float64{math.NaN(), math.Inf(1), math.Inf(-1)} {
+		msg := structMessage{Drugs: []structDrug{{Code: "Lipitor", Quantity: f}}}
+		if _, err := MarshalValues(msg); err == nil {
+			t.Fatalf("%d. expected an error for %v", i, f)
+		}
+	}
+}
+
+func TestMarshalValuesHeader(t *testing.T) {
+	hdr := Header{"UNA", ":+,? '"}
+	msg := structMessage{
+		structHeader: structHeader{Ref: "REF1"},
+		Drugs:        []structDrug{{Code: "Lipitor", Quantity: 30.5}},
+	}
+
+	values, err := MarshalValuesHeader(msg, hdr)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	b, err := Marshal(values)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := "UNA:+,? 'UIH++REF1'DRU+:Lipitor+30,5'UIT++0'"
+	if string(b) != expected {
+		t.Fatalf("unexpected output: %s. want %s", b, expected)
+	}
+
+	// and back again, which takes either decimal mark
+	decoded, err := Unmarshal(b)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var out structMessage
+	if err := UnmarshalValues(decoded, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(out, msg) {
+		t.Fatalf("mismatch\nhave: %#+v\nwant: %#+v", out, msg)
+	}
+}
@@ -1,26 +1,205 @@
 package hl7v2_3
 
 import (
+	"fmt"
+	"reflect"
 	"strings"
+
+	"github.com/kdar/health/hl7"
+	"github.com/kdar/health/hl7x"
 )
 
-// it really should be []interface{}, but for now
-// just doing string until I run into something
-// that breaks
-type Varies []string
+// Varies is a field whose data type is given by another field,
+// such as OBX-5 whose type is in OBX-2.
+//
+// When decoded, Values holds one raw hl7.Data per repetition. Once
+// Resolve is called with the data type (Obx does this itself), each
+// value is decoded into the Go type for it:
+//
+//	NM                    hl7x.Number
+//	SI                    hl7x.SequenceID
+//	ST TX FT ID IS TN     String
+//	CE CWE CNE            Ce
+//	SN                    Sn
+//	ED                    Ed
+//	RP                    Rp
+//	TS DTM                Ts
+//	DT                    hl7x.Date
+//	TM                    hl7x.Time
+//	CN                    Cn
+//	XCN                   Xcn
+//	XPN                   Xpn
+//	XAD                   Xad
+//	XTN                   Xtn
+//	XON                   Xon
+//	CX                    Cx
+//	HD                    Hd
+//	EI                    Ei
+//	PL                    Pl
+//	CQ                    Cq
+//	MO                    Mo
+//	CP                    Cp
+//
+// A type registered with hl7x.RegisterDataType (e.g. a local extension)
+// is decoded into the registered Go type, taking precedence over the
+// above. Any other type is left as hl7.Data.
+type Varies struct {
+	// the data type the values were resolved with
+	Type   string
+	Values []interface{}
+
+	raw []hl7.Data
+}
+
+// newVariesValue returns a pointer to a new value for the data type,
+// or nil if it's not a type we know about.
+func newVariesValue(typ string) interface{} {
+	if value, ok := hl7x.NewDataType(typ); ok {
+		return value
+	}
+
+	switch typ {
+	case "NM":
+		return &hl7x.Number{}
+	case "SI":
+		return &hl7x.SequenceID{}
+	case "ST", "TX", "FT", "ID", "IS", "TN":
+		return new(String)
+	case "CE", "CWE", "CNE":
+		return &Ce{}
+	case "SN":
+		return &Sn{}
+	case "ED":
+		return &Ed{}
+	case "RP":
+		return &Rp{}
+	case "TS", "DTM":
+		return &Ts{}
+	case "DT":
+		return &hl7x.Date{}
+	case "TM":
+		return &hl7x.Time{}
+	case "CN":
+		return &Cn{}
+	case "XCN":
+		return &Xcn{}
+	case "XPN":
+		return &Xpn{}
+	case "XAD":
+		return &Xad{}
+	case "XTN":
+		return &Xtn{}
+	case "XON":
+		return &Xon{}
+	case "CX":
+		return &Cx{}
+	case "HD":
+		return &Hd{}
+	case "EI":
+		return &Ei{}
+	case "PL":
+		return &Pl{}
+	case "CQ":
+		return &Cq{}
+	case "MO":
+		return &Mo{}
+	case "CP":
+		return &Cp{}
+	}
+
+	return nil
+}
+
+// UnmarshalHL7 implements hl7x.Unmarshaler. It only keeps the raw
+// data until Resolve is called.
+func (v *Varies) UnmarshalHL7(src hl7.Data) error {
+	v.Type = ""
+	v.Values = nil
+	v.raw = nil
+
+	reps, ok := src.(hl7.Repeated)
+	if !ok {
+		reps = hl7.Repeated{src}
+	}
+
+	for _, rep := range reps {
+		v.raw = append(v.raw, rep)
+		v.Values = append(v.Values, rep)
+	}
+
+	return nil
+}
+
+// Resolve decodes the values into the Go type for the data type typ.
+// Values that fail to decode are left as hl7.Data and reported in the
+// returned error.
+func (v *Varies) Resolve(typ string) error {
+	v.Type = strings.ToUpper(strings.TrimSpace(typ))
+
+	var errs []string
+	for i, raw := range v.raw {
+		value := newVariesValue(v.Type)
+		if value == nil {
+			v.Values[i] = raw
+			continue
+		}
+
+		if err := hl7x.Unmarshal(raw, value); err != nil {
+			errs = append(errs, err.Error())
+			v.Values[i] = raw
+			continue
+		}
+
+		// store the value, not the pointer to it
+		v.Values[i] = reflect.ValueOf(value).Elem().Interface()
+	}
+
+	if errs != nil {
+		return fmt.Errorf("could not decode %s value: %s", v.Type, strings.Join(errs, "; "))
+	}
+	return nil
+}
+
+// MarshalHL7 implements hl7x.Marshaler.
+func (v Varies) MarshalHL7() (hl7.Data, error) {
+	var reps hl7.Repeated
+	for _, value := range v.Values {
+		data, ok := value.(hl7.Data)
+		if !ok {
+			var err error
+			data, err = hl7x.MarshalField(value)
+			if err != nil {
+				return nil, err
+			}
+		}
+		reps = append(reps, data)
+	}
+
+	switch len(reps) {
+	case 0:
+		return hl7.Field{}, nil
+	case 1:
+		return reps[0], nil
+	}
+	return reps, nil
+}
 
 func (v Varies) String() string {
-	return strings.Join(v, "")
+	parts := make([]string, len(v.Values))
+	for i, value := range v.Values {
+		parts[i] = fmt.Sprint(value)
+	}
+	return strings.Join(parts, "~")
 }
 
 // Is
 // Id
 // St
-// Si
-// Nm
 // CmOsd
 // Tn
-// Dt
+//
+// Si, Nm, Dt and Tm use hl7x.SequenceID, hl7x.Number,
+// hl7x.Date and hl7x.Time.
 type String string
 
 func (s String) String() string {
@@ -0,0 +1,18 @@
+package hl7v2_3
+
+import "github.com/kdar/health/hl7x"
+
+type Cp struct {
+	// price
+	Price Mo `position:"CP.1"`
+	// price type
+	PriceType String `position:"CP.2"`
+	// from value
+	FromValue hl7x.Number `position:"CP.3"`
+	// to value
+	ToValue hl7x.Number `position:"CP.4"`
+	// range units
+	RangeUnits Ce `position:"CP.5"`
+	// range type
+	RangeType String `position:"CP.6"`
+}
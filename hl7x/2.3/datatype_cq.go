@@ -1,8 +1,10 @@
 package hl7v2_3
 
+import "github.com/kdar/health/hl7x"
+
 type Cq struct {
 	// quantity
-	Quantity String `position:"CQ.1"`
+	Quantity hl7x.Number `position:"CQ.1"`
 	// units
 	Units Ce `position:"CQ.2"`
 }
@@ -10,7 +10,7 @@ type Cx struct {
 	// assigning authority
 	AssigningAuthority String `position:"CX.4"`
 	// identifier type code
-	IdentifierTypeCode String `position:"CX.5"`
+	IdentifierTypeCode String `position:"CX.5" table:"0203"`
 	// assigning facility
 	AssigningFacility String `position:"CX.6"`
 }
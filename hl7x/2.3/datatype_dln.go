@@ -1,10 +1,12 @@
 package hl7v2_3
 
+import "github.com/kdar/health/hl7x"
+
 type Dln struct {
 	// Driver´s License Number
 	DriversLicenseNumber String `position:"DLN.1"`
 	// Issuing State province country
 	IssuingStateProvinceCountry String `position:"DLN.2"`
 	// expiration date
-	ExpirationDate String `position:"DLN.3"`
+	ExpirationDate hl7x.Date `position:"DLN.3"`
 }
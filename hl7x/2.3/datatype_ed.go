@@ -0,0 +1,42 @@
+package hl7v2_3
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"strings"
+)
+
+type Ed struct {
+	// source application
+	SourceApplication Hd `position:"ED.1"`
+	// type of data
+	TypeOfData String `position:"ED.2"`
+	// data subtype
+	DataSubtype String `position:"ED.3"`
+	// encoding
+	Encoding String `position:"ED.4"`
+	// data
+	Data String `position:"ED.5"`
+}
+
+// Bytes decodes Data according to Encoding (A, Hex, or Base64).
+func (e Ed) Bytes() ([]byte, error) {
+	switch strings.ToUpper(string(e.Encoding)) {
+	case "", "A":
+		return []byte(e.Data), nil
+	case "HEX":
+		return hex.DecodeString(string(e.Data))
+	case "BASE64":
+		// some senders wrap the data or leave off the padding
+		data := strings.Map(func(r rune) rune {
+			if r == '\r' || r == '\n' || r == ' ' {
+				return -1
+			}
+			return r
+		}, string(e.Data))
+		return base64.RawStdEncoding.DecodeString(strings.TrimRight(data, "="))
+	}
+
+	return nil, fmt.Errorf("unknown ED encoding: %s", e.Encoding)
+}
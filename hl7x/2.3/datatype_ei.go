@@ -8,5 +8,5 @@ type Ei struct {
 	// universal ID
 	UniversalID String `position:"EI.3"`
 	// universal String type
-	UniversalIdType String `position:"EI.4"`
+	UniversalIdType String `position:"EI.4" table:"0301"`
 }
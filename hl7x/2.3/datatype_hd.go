@@ -6,5 +6,5 @@ type Hd struct {
 	// universal ID
 	UniversalID String `position:"HD.2"`
 	// universal String type
-	UniversalIDType String `position:"HD.3"`
+	UniversalIDType String `position:"HD.3" table:"0301"`
 }
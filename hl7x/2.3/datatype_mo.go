@@ -1,8 +1,10 @@
 package hl7v2_3
 
+import "github.com/kdar/health/hl7x"
+
 type Mo struct {
 	// quantity
-	Quantity String `position:"MO.1"`
+	Quantity hl7x.Number `position:"MO.1"`
 	// denomination
 	Denomination String `position:"MO.2"`
 }
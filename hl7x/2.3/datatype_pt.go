@@ -2,7 +2,7 @@ package hl7v2_3
 
 type Pt struct {
 	// processing ID
-	ProcessingID String `position:"PT.1"`
+	ProcessingID String `position:"PT.1" table:"0103"`
 	// processing mode
-	ProcessingMode String `position:"PT.2"`
+	ProcessingMode String `position:"PT.2" table:"0207"`
 }
@@ -0,0 +1,12 @@
+package hl7v2_3
+
+type Rp struct {
+	// pointer
+	Pointer String `position:"RP.1"`
+	// application ID
+	ApplicationID Hd `position:"RP.2"`
+	// type of data
+	TypeOfData String `position:"RP.3"`
+	// subtype
+	Subtype String `position:"RP.4"`
+}
@@ -0,0 +1,24 @@
+package hl7v2_3
+
+import "github.com/kdar/health/hl7x"
+
+type Sn struct {
+	// comparator
+	Comparator String `position:"SN.1"`
+	// num1
+	Num1 hl7x.Number `position:"SN.2"`
+	// separator or suffix
+	SeparatorOrSuffix String `position:"SN.3"`
+	// num2
+	Num2 hl7x.Number `position:"SN.4"`
+}
+
+// IsRange reports whether this is a range, e.g. ^10^-^20
+func (s Sn) IsRange() bool {
+	return s.SeparatorOrSuffix == "-" && !s.Num1.IsZero() && !s.Num2.IsZero()
+}
+
+// IsRatio reports whether this is a ratio, e.g. ^1^:^128 or ^1^/^2
+func (s Sn) IsRatio() bool {
+	return (s.SeparatorOrSuffix == ":" || s.SeparatorOrSuffix == "/") && !s.Num2.IsZero()
+}
@@ -1,8 +1,10 @@
 package hl7v2_3
 
+import "github.com/kdar/health/hl7x"
+
 type Ts struct {
 	// time of an event
-	TimeOfAnEvent String `position:"TS.1"`
+	TimeOfAnEvent hl7x.DateTime `position:"TS.1"`
 	// degree of precision
 	DegreeOfPrecision String `position:"TS.2"`
 }
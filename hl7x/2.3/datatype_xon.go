@@ -0,0 +1,22 @@
+package hl7v2_3
+
+import "github.com/kdar/health/hl7x"
+
+type Xon struct {
+	// organization name
+	OrganizationName String `position:"XON.1"`
+	// organization name type code
+	OrganizationNameTypeCode String `position:"XON.2"`
+	// ID number (NM)
+	IDNumberNM hl7x.Number `position:"XON.3"`
+	// check digit
+	CheckDigit String `position:"XON.4"`
+	// code identifying the check digit scheme employed
+	CodeIdentifyingTheCheckDigitSchemeEmployed String `position:"XON.5"`
+	// assigning authority
+	AssigningAuthority Hd `position:"XON.6"`
+	// identifier type code
+	IdentifierTypeCode String `position:"XON.7"`
+	// assigning facility ID
+	AssigningFacilityID Hd `position:"XON.8"`
+}
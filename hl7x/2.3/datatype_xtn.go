@@ -4,9 +4,9 @@ type Xtn struct {
 	// [(999)] 999-9999 [X99999][C any text]
 	A_999_999_9999X99999CAnyText String `position:"XTN.1"`
 	// telecommunication use code
-	TelecommunicationUseCode String `position:"XTN.2"`
+	TelecommunicationUseCode String `position:"XTN.2" table:"0201"`
 	// telecommunication equipment type (ID)
-	TelecommunicationEquipmentTypeId String `position:"XTN.3"`
+	TelecommunicationEquipmentTypeId String `position:"XTN.3" table:"0202"`
 	// Email address
 	EmailAddress String `position:"XTN.4"`
 	// Country Code
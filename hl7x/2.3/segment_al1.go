@@ -0,0 +1,18 @@
+package hl7v2_3
+
+import "github.com/kdar/health/hl7x"
+
+type Al1 struct {
+	// Set ID - AL1
+	SetIDAL1 hl7x.SequenceID `position:"AL1.1" require:"true"`
+	// Allergy Type
+	AllergyType String `position:"AL1.2" table:"0127"`
+	// Allergy Code/Mnemonic/ Description
+	AllergyCodeMnemonicDescription Ce `position:"AL1.3" require:"true"`
+	// Allergy Severity
+	AllergySeverity String `position:"AL1.4" table:"0128"`
+	// Allergy Reaction
+	AllergyReaction String `position:"AL1.5"`
+	// Identification Date
+	IdentificationDate hl7x.Date `position:"AL1.6"`
+}
@@ -0,0 +1,44 @@
+package hl7v2_3
+
+import "github.com/kdar/health/hl7x"
+
+type Dg1 struct {
+	// Set ID - Diagnosis
+	SetIDDiagnosis hl7x.SequenceID `position:"DG1.1" require:"true"`
+	// Diagnosis Coding Method
+	DiagnosisCodingMethod String `position:"DG1.2" table:"0053"`
+	// Diagnosis Code
+	DiagnosisCode Ce `position:"DG1.3"`
+	// Diagnosis Description
+	DiagnosisDescription String `position:"DG1.4"`
+	// Diagnosis Date/Time
+	DiagnosisDateTime Ts `position:"DG1.5"`
+	// Diagnosis Type
+	DiagnosisType String `position:"DG1.6" require:"true" table:"0052"`
+	// Major Diagnostic Category
+	MajorDiagnosticCategory Ce `position:"DG1.7"`
+	// Diagnostic Related Group
+	DiagnosticRelatedGroup Ce `position:"DG1.8"`
+	// DRG Approval Indicator
+	DRGApprovalIndicator String `position:"DG1.9" table:"0136"`
+	// DRG Grouper Review Code
+	DRGGrouperReviewCode String `position:"DG1.10" table:"0056"`
+	// Outlier Type
+	OutlierType Ce `position:"DG1.11"`
+	// Outlier Days
+	OutlierDays hl7x.Number `position:"DG1.12"`
+	// Outlier Cost
+	OutlierCost Cp `position:"DG1.13"`
+	// Grouper Version and Type
+	GrouperVersionAndType String `position:"DG1.14"`
+	// Diagnosis Priority
+	DiagnosisPriority hl7x.Number `position:"DG1.15"`
+	// Diagnosing Clinician
+	DiagnosingClinicians []Xcn `position:"DG1.16"`
+	// Diagnosis Classification
+	DiagnosisClassification String `position:"DG1.17" table:"0228"`
+	// Confidential Indicator
+	ConfidentialIndicator String `position:"DG1.18" table:"0136"`
+	// Attestation Date/Time
+	AttestationDateTime Ts `position:"DG1.19"`
+}
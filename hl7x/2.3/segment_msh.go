@@ -1,5 +1,7 @@
 package hl7v2_3
 
+import "github.com/kdar/health/hl7x"
+
 type Msh struct {
 	// Field Separator
 	FieldSeparator String `position:"MSH.1" require:"true"`
@@ -24,19 +26,19 @@ type Msh struct {
 	// Processing ID
 	ProcessingID Pt `position:"MSH.11" require:"true"`
 	// Version ID
-	VersionID String `position:"MSH.12" require:"true"`
+	VersionID String `position:"MSH.12" require:"true" table:"0104"`
 	// Sequence Number
-	SequenceNumber String `position:"MSH.13"`
+	SequenceNumber hl7x.Number `position:"MSH.13"`
 	// Continuation Pointer
 	ContinuationPointer String `position:"MSH.14"`
 	// Accept Acknowledgement Type
-	AcceptAcknowledgementType String `position:"MSH.15"`
+	AcceptAcknowledgementType String `position:"MSH.15" table:"0155"`
 	// Application Acknowledgement Type
-	ApplicationAcknowledgementType String `position:"MSH.16"`
+	ApplicationAcknowledgementType String `position:"MSH.16" table:"0155"`
 	// Country Code
 	CountryCode String `position:"MSH.17"`
 	// Character Set
-	CharacterSet String `position:"MSH.18"`
+	CharacterSet String `position:"MSH.18" table:"0211"`
 	// Principal Language of Message
 	PrincipalLanguageOfMessage Ce `position:"MSH.19"`
 }
@@ -1,8 +1,10 @@
 package hl7v2_3
 
+import "github.com/kdar/health/hl7x"
+
 type Obr struct {
 	// Set String - Observation Request
-	SetIDObservationRequest String `position:"OBR.1"`
+	SetIDObservationRequest hl7x.SequenceID `position:"OBR.1"`
 	// Placer Order Number
 	PlacerOrderNumbers []Ei `position:"OBR.2"`
 	// Filler Order Number
@@ -22,7 +24,7 @@ type Obr struct {
 	// Collector Identifier
 	CollectorIdentifiers []Xcn `position:"OBR.10"`
 	// Specimen Action Code
-	SpecimenActionCode String `position:"OBR.11"`
+	SpecimenActionCode String `position:"OBR.11" table:"0065"`
 	// Danger Code
 	DangerCode Ce `position:"OBR.12"`
 	// Relevant Clinical Information
@@ -48,9 +50,9 @@ type Obr struct {
 	// Charge To Practice
 	ChargeToPractice CmMoc `position:"OBR.23"`
 	// Diagnostic Service Section ID
-	DiagnosticServiceSectionId String `position:"OBR.24"`
+	DiagnosticServiceSectionId String `position:"OBR.24" table:"0074"`
 	// Result Status
-	ResultStatus String `position:"OBR.25"`
+	ResultStatus String `position:"OBR.25" table:"0123"`
 	// Parent Result
 	ParentResult CmPrl `position:"OBR.26"`
 	// Quantity/Timing
@@ -60,7 +62,7 @@ type Obr struct {
 	// Parent Number
 	ParentNumber CmEip `position:"OBR.29"`
 	// Transportation Mode
-	TransportationMode String `position:"OBR.30"`
+	TransportationMode String `position:"OBR.30" table:"0124"`
 	// Reason For Study
 	ReasonForStudies []Ce `position:"OBR.31"`
 	// Principal Result Interpreter
@@ -74,7 +76,7 @@ type Obr struct {
 	// Scheduled Date/Time
 	ScheduledDateTime Ts `position:"OBR.36"`
 	// Number Of Sample Containers
-	NumberOfSampleContainers String `position:"OBR.37"`
+	NumberOfSampleContainers hl7x.Number `position:"OBR.37"`
 	// Transport Logistics Of Collected Sample
 	TransportLogisticsOfCollectedSamples []Ce `position:"OBR.38"`
 	// Collector’s Comment
@@ -82,9 +84,9 @@ type Obr struct {
 	// Transport Arrangement Responsibility
 	TransportArrangementResponsibility Ce `position:"OBR.40"`
 	// Transport Arranged
-	TransportArranged String `position:"OBR.41"`
+	TransportArranged String `position:"OBR.41" table:"0224"`
 	// Escort Required
-	EscortRequired String `position:"OBR.42"`
+	EscortRequired String `position:"OBR.42" table:"0225"`
 	// Planned Patient Transport Comment
 	PlannedPatientTransportComments []Ce `position:"OBR.43"`
 }
@@ -1,10 +1,15 @@
 package hl7v2_3
 
+import (
+	"github.com/kdar/health/hl7"
+	"github.com/kdar/health/hl7x"
+)
+
 type Obx struct {
 	// Set String - OBX
-	SetIDOBX String `position:"OBX.1"`
+	SetIDOBX hl7x.SequenceID `position:"OBX.1"`
 	// Value Type
-	ValueType String `position:"OBX.2" require:"true"`
+	ValueType String `position:"OBX.2" require:"true" table:"0125"`
 	// Observation Identifier
 	ObservationIdentifier Ce `position:"OBX.3" require:"true"`
 	// Observation Sub-ID
@@ -16,13 +21,13 @@ type Obx struct {
 	// References Range
 	ReferencesRange String `position:"OBX.7"`
 	// Abnormal Flags
-	AbnormalFlags []String `position:"OBX.8"`
+	AbnormalFlags []String `position:"OBX.8" table:"0078"`
 	// Probability
-	Probability String `position:"OBX.9"`
+	Probability hl7x.Number `position:"OBX.9"`
 	// Nature of Abnormal Test
-	NatureOfAbnormalTest String `position:"OBX.10"`
+	NatureOfAbnormalTest String `position:"OBX.10" table:"0080"`
 	// Observ Result Status
-	ObservResultStatus String `position:"OBX.11" require:"true"`
+	ObservResultStatus String `position:"OBX.11" require:"true" table:"0085"`
 	// Date Last Obs Normal Values
 	DateLastObsNormalValues Ts `position:"OBX.12"`
 	// User Defined Access Checks
@@ -37,7 +42,15 @@ type Obx struct {
 	ObservationMethods []Ce `position:"OBX.17"`
 }
 
-// func (o *Obx) UnmarshalHL7(data hl7.Data) error {
-// 	fmt.Println(data)
-// 	return nil
-// }
+// UnmarshalHL7 implements hl7x.Unmarshaler so that the
+// observation values are decoded using the value type.
+func (o *Obx) UnmarshalHL7(data hl7.Data) error {
+	// obx has the same fields but not this method, so
+	// hl7x.Unmarshal won't end up back here.
+	type obx Obx
+	if err := hl7x.Unmarshal(data, (*obx)(o)); err != nil {
+		return err
+	}
+
+	return o.ObservationValues.Resolve(string(o.ValueType))
+}
@@ -0,0 +1,148 @@
+package hl7v2_3
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/kdar/health/hl7"
+	"github.com/kdar/health/hl7x"
+)
+
+var obxValueTests = []struct {
+	in  string
+	out []interface{}
+}{
+	{
+		"OBX|1|NM|GLU^Glucose^L||105.0|mg/dL",
+		[]interface{}{hl7x.Number{Value: 105, Text: "105.0"}},
+	},
+	{
+		"OBX|1|ST|NOTE||first~second",
+		[]interface{}{String("first"), String("second")},
+	},
+	{
+		"OBX|1|CE|001719^HIV-1 ABS, SEMI-QN^L||HTN^Hypertension^I9",
+		[]interface{}{Ce{Identifier: "HTN", Text: "Hypertension", NameOfCodingSystem: "I9"}},
+	},
+	{
+		"OBX|1|CWE|001719||N^Negative",
+		[]interface{}{Ce{Identifier: "N", Text: "Negative"}},
+	},
+	{
+		"OBX|1|SN|TITER||^1^:^128",
+		[]interface{}{Sn{Num1: hl7x.Number{Value: 1, Text: "1"}, SeparatorOrSuffix: ":", Num2: hl7x.Number{Value: 128, Text: "128"}}},
+	},
+	{
+		"OBX|1|SN|WBC||<^5",
+		[]interface{}{Sn{Comparator: "<", Num1: hl7x.Number{Value: 5, Text: "5"}}},
+	},
+	{
+		"OBX|1|ED|PDF||LAB^AP^PDF^Base64^aGVsbG8=",
+		[]interface{}{Ed{SourceApplication: Hd{NamespaceID: "LAB"}, TypeOfData: "AP", DataSubtype: "PDF", Encoding: "Base64", Data: "aGVsbG8="}},
+	},
+	{
+		"OBX|1|DT|DUE||20141001",
+		[]interface{}{hl7x.Date{Time: time.Date(2014, 10, 1, 0, 0, 0, 0, time.UTC), Precision: hl7x.PrecisionDay}},
+	},
+	{
+		"OBX|1|ZZ|CUSTOM||a^b",
+		[]interface{}{hl7.Component{hl7.Field("a"), hl7.Field("b")}},
+	},
+}
+
+func TestObxValues(t *testing.T) {
+	msh := "MSH|^~\\&|LAB|HOSP|||20140922091808||ORU^R01|1|P|2.3\r"
+
+	for i, tt := range obxValueTests {
+		segments, err := hl7.Unmarshal([]byte(msh + tt.in + "\r"))
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+
+		var obx Obx
+		if err := hl7x.Unmarshal(segments[1], &obx); err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+
+		if !reflect.DeepEqual(obx.ObservationValues.Values, tt.out) {
+			t.Fatalf("%d. mismatch\nhave: %#v\nwant: %#v", i, obx.ObservationValues.Values, tt.out)
+		}
+
+		out, err := hl7x.Marshal(&obx)
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+
+		b, err := hl7.Marshal([]hl7.Segment{segments[0], out})
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+
+		if string(b) != msh+tt.in+"\r" {
+			t.Fatalf("%d. unexpected output: %q. want %q", i, b, msh+tt.in+"\r")
+		}
+	}
+}
+
+func TestEdBytes(t *testing.T) {
+	tests := []struct {
+		ed  Ed
+		out string
+	}{
+		{Ed{Encoding: "Base64", Data: "aGVsbG8="}, "hello"},
+		{Ed{Encoding: "Base64", Data: "aGVs\nbG8"}, "hello"},
+		{Ed{Encoding: "Hex", Data: "68656c6c6f"}, "hello"},
+		{Ed{Encoding: "A", Data: "hello"}, "hello"},
+	}
+
+	for i, tt := range tests {
+		b, err := tt.ed.Bytes()
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+		if string(b) != tt.out {
+			t.Fatalf("%d. have %q, want %q", i, b, tt.out)
+		}
+	}
+
+	if _, err := (Ed{Encoding: "Base64", Data: "!!"}).Bytes(); err == nil {
+		t.Fatalf("did not error on invalid base64")
+	}
+}
+
+func TestObxInvalidValue(t *testing.T) {
+	segment := hl7.Segment{hl7.Field("OBX"), hl7.Field("1"), hl7.Field("NM"), hl7.Field("GLU"), hl7.Field(""), hl7.Field("high")}
+
+	var obx Obx
+	if err := hl7x.Unmarshal(segment, &obx); err == nil {
+		t.Fatalf("did not error on an invalid NM")
+	}
+
+	if !reflect.DeepEqual(obx.ObservationValues.Values, []interface{}{hl7.Field("high")}) {
+		t.Fatalf("raw value was not kept: %#v", obx.ObservationValues.Values)
+	}
+}
+
+// a local extension data type
+type gts struct {
+	Start String `position:"GTS.1"`
+	End   String `position:"GTS.2"`
+}
+
+func TestObxRegisteredDataType(t *testing.T) {
+	hl7x.RegisterDataType("GTS", gts{})
+
+	segment := hl7.Segment{hl7.Field("OBX"), hl7.Field("1"), hl7.Field("GTS"), hl7.Field("SCHED"), hl7.Field(""),
+		hl7.Component{hl7.Field("0800"), hl7.Field("1700")}}
+
+	var obx Obx
+	if err := hl7x.Unmarshal(segment, &obx); err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []interface{}{gts{Start: "0800", End: "1700"}}
+	if !reflect.DeepEqual(obx.ObservationValues.Values, expected) {
+		t.Fatalf("have %#v, want %#v", obx.ObservationValues.Values, expected)
+	}
+}
@@ -2,7 +2,7 @@ package hl7v2_3
 
 type Orc struct {
 	// Order Control
-	OrderControl String `position:"ORC.1" require:"true"`
+	OrderControl String `position:"ORC.1" require:"true" table:"0119"`
 	// Placer Order Number
 	PlacerOrderNumbers []Ei `position:"ORC.2"`
 	// Filler Order Number
@@ -10,9 +10,9 @@ type Orc struct {
 	// Placer Group Number
 	PlacerGroupNumber Ei `position:"ORC.4"`
 	// Order Status
-	OrderStatus String `position:"ORC.5"`
+	OrderStatus String `position:"ORC.5" table:"0038"`
 	// Response Flag
-	ResponseFlag String `position:"ORC.6"`
+	ResponseFlag String `position:"ORC.6" table:"0121"`
 	// Quantity/Timing
 	QuantityTiming Tq `position:"ORC.7" require:"true"`
 	// Parent
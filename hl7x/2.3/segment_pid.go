@@ -1,8 +1,10 @@
 package hl7v2_3
 
+import "github.com/kdar/health/hl7x"
+
 type Pid struct {
 	// Set String - Patient ID
-	SetIDPatientID String `position:"PID.1"`
+	SetIDPatientID hl7x.SequenceID `position:"PID.1"`
 	// Patient String (External ID)
 	PatientIDExternalID Cx `position:"PID.2"`
 	// Patient String (Internal ID)
@@ -16,11 +18,11 @@ type Pid struct {
 	// Date of Birth
 	DateOfBirth Ts `position:"PID.7"`
 	// Sex
-	Sex String `position:"PID.8"`
+	Sex String `position:"PID.8" table:"0001"`
 	// Patient Alias
 	PatientAliases []Xpn `position:"PID.9"`
 	// Race
-	Race String `position:"PID.10"`
+	Race String `position:"PID.10" table:"0005"`
 	// Patient Address
 	PatientAddresses []Xad `position:"PID.11"`
 	// County Code
@@ -32,9 +34,9 @@ type Pid struct {
 	// Primary Language
 	PrimaryLanguage Ce `position:"PID.15"`
 	// Marital Status
-	MaritalStatuses []String `position:"PID.16"`
+	MaritalStatuses []String `position:"PID.16" table:"0002"`
 	// Religion
-	Religion String `position:"PID.17"`
+	Religion String `position:"PID.17" table:"0006"`
 	// Patient Account Number
 	PatientAccountNumber Cx `position:"PID.18"`
 	// SSN Number - Patient
@@ -44,15 +46,15 @@ type Pid struct {
 	// Mother's Identifier
 	MotherSIdentifier Cx `position:"PID.21"`
 	// Ethnic Group
-	EthnicGroup String `position:"PID.22"`
+	EthnicGroup String `position:"PID.22" table:"0189"`
 	// Birth Place
 	BirthPlace String `position:"PID.23"`
 	// Multiple Birth Indicator
-	MultipleBirthIndicator String `position:"PID.24"`
+	MultipleBirthIndicator String `position:"PID.24" table:"0136"`
 	// Birth Order
-	BirthOrder String `position:"PID.25"`
+	BirthOrder hl7x.Number `position:"PID.25"`
 	// Citizenship
-	Citizenship String `position:"PID.26"`
+	Citizenship String `position:"PID.26" table:"0171"`
 	// Veterans Military Status
 	VeteransMilitaryStatus Ce `position:"PID.27"`
 	// Nationality Code
@@ -60,5 +62,5 @@ type Pid struct {
 	// Patient Death Date and Time
 	PatientDeathDateAndTime Ts `position:"PID.29"`
 	// Patient Death Indicator
-	PatientDeathIndicator String `position:"PID.30"`
+	PatientDeathIndicator String `position:"PID.30" table:"0136"`
 }
@@ -1,14 +1,16 @@
 package hl7v2_3
 
+import "github.com/kdar/health/hl7x"
+
 type Pv1 struct {
 	// Set String - Patient Visit
-	SetIDPatientVisit String `position:"PV1.1"`
+	SetIDPatientVisit hl7x.SequenceID `position:"PV1.1"`
 	// Patient Class
-	PatientClass String `position:"PV1.2" require:"true"`
+	PatientClass String `position:"PV1.2" require:"true" table:"0004"`
 	// Assigned Patient Location
 	AssignedPatientLocation Pl `position:"PV1.3"`
 	// Admission Type
-	AdmissionType String `position:"PV1.4"`
+	AdmissionType String `position:"PV1.4" table:"0007"`
 	// Preadmit Number
 	PreadmitNumber Cx `position:"PV1.5"`
 	// Prior Patient Location
@@ -20,69 +22,69 @@ type Pv1 struct {
 	// Consulting Doctor
 	ConsultingDoctors []Xcn `position:"PV1.9"`
 	// Hospital Service
-	HospitalService String `position:"PV1.10"`
+	HospitalService String `position:"PV1.10" table:"0069"`
 	// Temporary Location
 	TemporaryLocation Pl `position:"PV1.11"`
 	// Preadmit Test Indicator
-	PreadmitTestIndicator String `position:"PV1.12"`
+	PreadmitTestIndicator String `position:"PV1.12" table:"0087"`
 	// Readmission Indicator
-	ReadmissionIndicator String `position:"PV1.13"`
+	ReadmissionIndicator String `position:"PV1.13" table:"0092"`
 	// Admit Source
-	AdmitSource String `position:"PV1.14"`
+	AdmitSource String `position:"PV1.14" table:"0023"`
 	// Ambulatory Status
-	AmbulatoryStatus String `position:"PV1.15"`
+	AmbulatoryStatus String `position:"PV1.15" table:"0009"`
 	// VIP Indicator
-	VipIndicator String `position:"PV1.16"`
+	VipIndicator String `position:"PV1.16" table:"0099"`
 	// Admitting Doctor
 	AdmittingDoctor Xcn `position:"PV1.17"`
 	// Patient Type
-	PatientType String `position:"PV1.18"`
+	PatientType String `position:"PV1.18" table:"0018"`
 	// Visit Number
 	VisitNumber Cx `position:"PV1.19"`
 	// Financial Class
 	FinancialClasses []Fc `position:"PV1.20"`
 	// Charge Price Indicator
-	ChargePriceIndicator String `position:"PV1.21"`
+	ChargePriceIndicator String `position:"PV1.21" table:"0032"`
 	// Courtesy Code
-	CourtesyCode String `position:"PV1.22"`
+	CourtesyCode String `position:"PV1.22" table:"0045"`
 	// Credit Rating
-	CreditRating String `position:"PV1.23"`
+	CreditRating String `position:"PV1.23" table:"0046"`
 	// Contract Code
-	ContractCodes []String `position:"PV1.24"`
+	ContractCodes []String `position:"PV1.24" table:"0044"`
 	// Contract Effective Date
-	ContractEffectiveDates []String `position:"PV1.25"`
+	ContractEffectiveDates []hl7x.Date `position:"PV1.25"`
 	// Contract Amount
-	ContractAmounts []String `position:"PV1.26"`
+	ContractAmounts []hl7x.Number `position:"PV1.26"`
 	// Contract Period
-	ContractPeriods []String `position:"PV1.27"`
+	ContractPeriods []hl7x.Number `position:"PV1.27"`
 	// Interest Code
-	InterestCode String `position:"PV1.28"`
+	InterestCode String `position:"PV1.28" table:"0073"`
 	// Transfer to Bad Debt Code
-	TransferToBadDebtCode String `position:"PV1.29"`
+	TransferToBadDebtCode String `position:"PV1.29" table:"0110"`
 	// Transfer to Bad Debt Date
-	TransferToBadDebtDate String `position:"PV1.30"`
+	TransferToBadDebtDate hl7x.Date `position:"PV1.30"`
 	// Bad Debt Agency Code
-	BadDebtAgencyCode String `position:"PV1.31"`
+	BadDebtAgencyCode String `position:"PV1.31" table:"0021"`
 	// Bad Debt Transfer Amount
-	BadDebtTransferAmount String `position:"PV1.32"`
+	BadDebtTransferAmount hl7x.Number `position:"PV1.32"`
 	// Bad Debt Recovery Amount
-	BadDebtRecoveryAmount String `position:"PV1.33"`
+	BadDebtRecoveryAmount hl7x.Number `position:"PV1.33"`
 	// Delete Account Indicator
-	DeleteAccountIndicator String `position:"PV1.34"`
+	DeleteAccountIndicator String `position:"PV1.34" table:"0111"`
 	// Delete Account Date
-	DeleteAccountDate String `position:"PV1.35"`
+	DeleteAccountDate hl7x.Date `position:"PV1.35"`
 	// Discharge Disposition
-	DischargeDisposition String `position:"PV1.36"`
+	DischargeDisposition String `position:"PV1.36" table:"0112"`
 	// Discharged to Location
 	DischargedToLocation CmDld `position:"PV1.37"`
 	// Diet Type
-	DietType String `position:"PV1.38"`
+	DietType String `position:"PV1.38" table:"0114"`
 	// Servicing Facility
-	ServicingFacility String `position:"PV1.39"`
+	ServicingFacility String `position:"PV1.39" table:"0115"`
 	// Bed Status
-	BedStatus String `position:"PV1.40"`
+	BedStatus String `position:"PV1.40" table:"0116"`
 	// Account Status
-	AccountStatus String `position:"PV1.41"`
+	AccountStatus String `position:"PV1.41" table:"0117"`
 	// Pending Location
 	PendingLocation Pl `position:"PV1.42"`
 	// Prior Temporary Location
@@ -92,17 +94,17 @@ type Pv1 struct {
 	// Discharge Date/Time
 	DischargeDateTime Ts `position:"PV1.45"`
 	// Current Patient Balance
-	CurrentPatientBalance String `position:"PV1.46"`
+	CurrentPatientBalance hl7x.Number `position:"PV1.46"`
 	// Total Charges
-	TotalCharges String `position:"PV1.47"`
+	TotalCharges hl7x.Number `position:"PV1.47"`
 	// Total Adjustments
-	TotalAdjustments String `position:"PV1.48"`
+	TotalAdjustments hl7x.Number `position:"PV1.48"`
 	// Total Payments
-	TotalPayments String `position:"PV1.49"`
+	TotalPayments hl7x.Number `position:"PV1.49"`
 	// Alternate Visit ID
 	AlternateVisitID Cx `position:"PV1.50"`
 	// Visit Indicator
-	VisitIndicator String `position:"PV1.51"`
+	VisitIndicator String `position:"PV1.51" table:"0326"`
 	// Other Healthcare Provider
 	OtherHealthcareProviders []Xcn `position:"PV1.52"`
 }
@@ -0,0 +1,80 @@
+package hl7v2_3
+
+import "github.com/kdar/health/hl7x"
+
+type Pv2 struct {
+	// Prior Pending Location
+	PriorPendingLocation Pl `position:"PV2.1"`
+	// Accommodation Code
+	AccommodationCode Ce `position:"PV2.2"`
+	// Admit Reason
+	AdmitReason Ce `position:"PV2.3"`
+	// Transfer Reason
+	TransferReason Ce `position:"PV2.4"`
+	// Patient Valuables
+	PatientValuables []String `position:"PV2.5"`
+	// Patient Valuables Location
+	PatientValuablesLocation String `position:"PV2.6"`
+	// Visit User Code
+	VisitUserCode String `position:"PV2.7" table:"0130"`
+	// Expected Admit Date
+	ExpectedAdmitDate Ts `position:"PV2.8"`
+	// Expected Discharge Date
+	ExpectedDischargeDate Ts `position:"PV2.9"`
+	// Estimated Length of Inpatient Stay
+	EstimatedLengthOfInpatientStay hl7x.Number `position:"PV2.10"`
+	// Actual Length of Inpatient Stay
+	ActualLengthOfInpatientStay hl7x.Number `position:"PV2.11"`
+	// Visit Description
+	VisitDescription String `position:"PV2.12"`
+	// Referral Source Code
+	ReferralSourceCode Xcn `position:"PV2.13"`
+	// Previous Service Date
+	PreviousServiceDate hl7x.Date `position:"PV2.14"`
+	// Employment Illness Related Indicator
+	EmploymentIllnessRelatedIndicator String `position:"PV2.15" table:"0136"`
+	// Purge Status Code
+	PurgeStatusCode String `position:"PV2.16" table:"0213"`
+	// Purge Status Date
+	PurgeStatusDate hl7x.Date `position:"PV2.17"`
+	// Special Program Code
+	SpecialProgramCode String `position:"PV2.18" table:"0214"`
+	// Retention Indicator
+	RetentionIndicator String `position:"PV2.19" table:"0136"`
+	// Expected Number of Insurance Plans
+	ExpectedNumberOfInsurancePlans hl7x.Number `position:"PV2.20"`
+	// Visit Publicity Code
+	VisitPublicityCode String `position:"PV2.21" table:"0215"`
+	// Visit Protection Indicator
+	VisitProtectionIndicator String `position:"PV2.22" table:"0136"`
+	// Clinic Organization Name
+	ClinicOrganizationNames []Xon `position:"PV2.23"`
+	// Patient Status Code
+	PatientStatusCode String `position:"PV2.24" table:"0216"`
+	// Visit Priority Code
+	VisitPriorityCode String `position:"PV2.25" table:"0217"`
+	// Previous Treatment Date
+	PreviousTreatmentDate hl7x.Date `position:"PV2.26"`
+	// Expected Discharge Disposition
+	ExpectedDischargeDisposition String `position:"PV2.27" table:"0112"`
+	// Signature on File Date
+	SignatureOnFileDate hl7x.Date `position:"PV2.28"`
+	// First Similar Illness Date
+	FirstSimilarIllnessDate hl7x.Date `position:"PV2.29"`
+	// Patient Charge Adjustment Code
+	PatientChargeAdjustmentCode String `position:"PV2.30" table:"0218"`
+	// Recurring Service Code
+	RecurringServiceCode String `position:"PV2.31" table:"0219"`
+	// Billing Media Code
+	BillingMediaCode String `position:"PV2.32" table:"0136"`
+	// Expected Surgery Date & Time
+	ExpectedSurgeryDateTime Ts `position:"PV2.33"`
+	// Military Partnership Code
+	MilitaryPartnershipCode String `position:"PV2.34" table:"0136"`
+	// Military Non-Availabiltiy Code
+	MilitaryNonAvailabiltiyCode String `position:"PV2.35" table:"0136"`
+	// Newborn Baby Indicator
+	NewbornBabyIndicator String `position:"PV2.36" table:"0136"`
+	// Baby Detained Indicator
+	BabyDetainedIndicator String `position:"PV2.37" table:"0136"`
+}
@@ -0,0 +1,353 @@
+package hl7v2_3
+
+import "github.com/kdar/health/hl7x"
+
+// Version is the HL7 version the types in this package are for.
+const Version = "2.3"
+
+// Tables are the HL7-defined tables for version 2.3, keyed by table
+// number. They're registered with hl7x for Version when this package
+// is imported. Tables that are user-defined in the standard (e.g. 0005
+// Race) aren't included; register your own with hl7x.RegisterTable.
+var Tables = map[string]hl7x.Table{
+	// Sex
+	"0001": {
+		"F": "Female",
+		"M": "Male",
+		"O": "Other",
+		"U": "Unknown",
+	},
+	// Marital status
+	"0002": {
+		"A": "Separated",
+		"D": "Divorced",
+		"M": "Married",
+		"S": "Single",
+		"W": "Widowed",
+	},
+	// Patient class
+	"0004": {
+		"E": "Emergency",
+		"I": "Inpatient",
+		"O": "Outpatient",
+		"P": "Preadmit",
+		"R": "Recurring patient",
+		"B": "Obstetrics",
+	},
+	// Order status
+	"0038": {
+		"A":  "Some, but not all, results available",
+		"CA": "Order was canceled",
+		"CM": "Order is completed",
+		"DC": "Order was discontinued",
+		"ER": "Error, order not found",
+		"HD": "Order is on hold",
+		"IP": "In process, unspecified",
+		"RP": "Order has been replaced",
+		"SC": "In process, scheduled",
+	},
+	// Specimen action code
+	"0065": {
+		"A": "Add ordered tests to the existing specimen",
+		"G": "Generated order; reflex order",
+		"L": "Lab to obtain specimen from patient",
+		"O": "Specimen obtained by service other than Lab",
+		"P": "Pending specimen; Order sent prior to delivery",
+		"R": "Revised order",
+		"S": "Schedule the tests specified below",
+	},
+	// Diagnostic service section ID
+	"0074": {
+		"AU":  "Audiology",
+		"BG":  "Blood gases",
+		"BLB": "Blood bank",
+		"CUS": "Cardiac Ultrasound",
+		"CTH": "Cardiac catheterization",
+		"CT":  "CAT scan",
+		"CH":  "Chemistry",
+		"CP":  "Cytopathology",
+		"EC":  "Electrocardiac (e.g., EKG, EEC, Holter)",
+		"EN":  "Electroneuro (EEG, EMG,EP,PSG)",
+		"HM":  "Hematology",
+		"ICU": "Bedside ICU Monitoring",
+		"IMM": "Immunology",
+		"LAB": "Laboratory",
+		"MB":  "Microbiology",
+		"MCB": "Mycobacteriology",
+		"MYC": "Mycology",
+		"NMS": "Nuclear medicine scan",
+		"NMR": "Nuclear magnetic resonance",
+		"NRS": "Nursing service measures",
+		"OUS": "OB Ultrasound",
+		"OT":  "Occupational Therapy",
+		"OTH": "Other",
+		"OSL": "Outside Lab",
+		"PHR": "Pharmacy",
+		"PT":  "Physical Therapy",
+		"PHY": "Physician (Hx. Dx, admission note, etc.)",
+		"PF":  "Pulmonary function",
+		"RC":  "Respiratory Care (therapy)",
+		"RT":  "Radiation therapy",
+		"RUS": "Radiology ultrasound",
+		"RX":  "Radiograph",
+		"SR":  "Serology",
+		"SP":  "Surgical Pathology",
+		"TX":  "Toxicology",
+		"VUS": "Vascular Ultrasound",
+		"VR":  "Virology",
+		"XRC": "Cineradiograph",
+	},
+	// Abnormal flags
+	"0078": {
+		"L":  "Below low normal",
+		"H":  "Above high normal",
+		"LL": "Below lower panic limits",
+		"HH": "Above upper panic limits",
+		"<":  "Below absolute low-off instrument scale",
+		">":  "Above absolute high-off instrument scale",
+		"N":  "Normal (applies to non-numeric results)",
+		"A":  "Abnormal (applies to non-numeric results)",
+		"AA": "Very abnormal (applies to non-numeric units)",
+		"U":  "Significant change up",
+		"D":  "Significant change down",
+		"B":  "Better--use when direction not relevant",
+		"W":  "Worse--use when direction not relevant",
+		"S":  "Susceptible",
+		"R":  "Resistant",
+		"I":  "Intermediate",
+		"MS": "Moderately susceptible",
+		"VS": "Very susceptible",
+	},
+	// Nature of abnormal testing
+	"0080": {
+		"A": "An age-based population",
+		"N": "None - generic normal range",
+		"R": "A race-based population",
+		"S": "A sex-based population",
+	},
+	// Observation result status codes interpretation
+	"0085": {
+		"C": "Record coming over is a correction and thus replaces a final result",
+		"D": "Deletes the OBX record",
+		"F": "Final results",
+		"I": "Specimen in lab; results pending",
+		"O": "Order detail description only (no result)",
+		"P": "Preliminary results",
+		"R": "Results entered -- not verified",
+		"S": "Partial results",
+		"U": "Results status change to Final without retransmitting results already sent as preliminary",
+		"W": "Post original as wrong, e.g., transmitted for wrong patient",
+		"X": "Results cannot be obtained for this observation",
+	},
+	// Processing ID
+	"0103": {
+		"D": "Debugging",
+		"P": "Production",
+		"T": "Training",
+	},
+	// Version ID
+	"0104": {
+		"2.0":  "Release 2.0",
+		"2.0D": "Demo 2.0",
+		"2.1":  "Release 2.1",
+		"2.2":  "Release 2.2",
+		"2.3":  "Release 2.3",
+	},
+	// Order control codes
+	"0119": {
+		"AF": "Order/service refill request approval",
+		"CA": "Cancel order/service request",
+		"CH": "Child order/service",
+		"CN": "Combined result",
+		"CR": "Canceled as requested",
+		"DC": "Discontinue order/service request",
+		"DE": "Data errors",
+		"DF": "Order/service refill request denied",
+		"DR": "Discontinued as requested",
+		"FU": "Order/service refilled, unsolicited",
+		"HD": "Hold order request",
+		"HR": "On hold as requested",
+		"LI": "Link order/service to patient care problem or goal",
+		"NA": "Number assigned",
+		"NW": "New order/service",
+		"OC": "Order/service canceled",
+		"OD": "Order/service discontinued",
+		"OE": "Order/service released",
+		"OF": "Order/service refilled as requested",
+		"OH": "Order/service held",
+		"OK": "Order/service accepted & OK",
+		"OR": "Released as requested",
+		"PA": "Parent order/service",
+		"RE": "Observations/Performed Service to follow",
+		"RF": "Refill order/service request",
+		"RL": "Release previous hold",
+		"RO": "Replacement order",
+		"RP": "Order/service replace request",
+		"RQ": "Replaced as requested",
+		"RR": "Request received",
+		"RU": "Replaced unsolicited",
+		"SC": "Status changed",
+		"SN": "Send order/service number",
+		"SR": "Response to send order/service status request",
+		"SS": "Send order/service status request",
+		"UA": "Unable to accept order/service",
+		"UC": "Unable to cancel",
+		"UD": "Unable to discontinue",
+		"UF": "Unable to refill",
+		"UH": "Unable to put on hold",
+		"UM": "Unable to replace",
+		"UN": "Unlink order/service from patient care problem or goal",
+		"UR": "Unable to release",
+		"UX": "Unable to change",
+		"XO": "Change order/service request",
+		"XR": "Changed as requested",
+		"XX": "Order/service changed, unsol.",
+	},
+	// Response flag
+	"0121": {
+		"E": "Report exceptions only",
+		"R": "Same as E, also Replacement and Parent-Child",
+		"D": "Same as R, also other associated segments",
+		"F": "Same as D, plus confirmations explicitly",
+		"N": "Only the MSA segment is returned",
+	},
+	// Result status
+	"0123": {
+		"O": "Order received; specimen not yet received",
+		"I": "No results available; specimen received, procedure incomplete",
+		"S": "No results available; procedure scheduled, but not done",
+		"A": "Some, but not all, results available",
+		"P": "Preliminary: A verified early result is available, final results not yet obtained",
+		"C": "Correction to results",
+		"R": "Results stored; not yet verified",
+		"F": "Final results; results stored and verified. Can only be changed with a corrected result.",
+		"X": "No results available; Order canceled.",
+		"Y": "No order on record for this test. (Used only on queries)",
+		"Z": "No record of this patient. (Used only on queries)",
+	},
+	// Transportation mode
+	"0124": {
+		"CART": "Cart - patient travels on cart or gurney",
+		"PORT": "The examining device goes to patient's location",
+		"WALK": "Patient walks to diagnostic service",
+		"WHLC": "Wheelchair",
+	},
+	// Value type
+	"0125": {
+		"AD":  "Address",
+		"CE":  "Coded Entry",
+		"CF":  "Coded Element With Formatted Values",
+		"CK":  "Composite ID With Check Digit",
+		"CN":  "Composite ID And Name",
+		"CP":  "Composite Price",
+		"CX":  "Extended Composite ID With Check Digit",
+		"DT":  "Date",
+		"ED":  "Encapsulated Data",
+		"FT":  "Formatted Text (Display)",
+		"MO":  "Money",
+		"NM":  "Numeric",
+		"PN":  "Person Name",
+		"RP":  "Reference Pointer",
+		"SN":  "Structured Numeric",
+		"ST":  "String Data.",
+		"TM":  "Time",
+		"TN":  "Telephone Number",
+		"TS":  "Time Stamp (Date & Time)",
+		"TX":  "Text Data (Display)",
+		"XAD": "Extended Address",
+		"XCN": "Extended Composite Name And Number For Persons",
+		"XON": "Extended Composite Name And Number For Organizations",
+		"XPN": "Extended Person Name",
+		"XTN": "Extended Telecommunications Number",
+	},
+	// Yes/no indicator
+	"0136": {
+		"Y": "Yes",
+		"N": "No",
+	},
+	// Accept/application acknowledgment conditions
+	"0155": {
+		"AL": "Always",
+		"NE": "Never",
+		"ER": "Error/reject conditions only",
+		"SU": "Successful completion only",
+	},
+	// Telecommunication use code
+	"0201": {
+		"PRN": "Primary Residence Number",
+		"ORN": "Other Residence Number",
+		"WPN": "Work Number",
+		"VHN": "Vacation Home Number",
+		"ASN": "Answering Service Number",
+		"EMR": "Emergency Number",
+		"NET": "Network (email) Address",
+		"BPN": "Beeper Number",
+	},
+	// Telecommunication equipment type
+	"0202": {
+		"PH":       "Telephone",
+		"FX":       "Fax",
+		"MD":       "Modem",
+		"CP":       "Cellular Phone",
+		"BP":       "Beeper",
+		"Internet": "Internet Address: Use Only If Telecommunication Use Code Is NET",
+		"X.400":    "X.400 email address: Use Only If Telecommunication Use Code Is NET",
+	},
+	// Processing mode
+	"0207": {
+		"A": "Archive",
+		"R": "Restore from archive",
+		"I": "Initial load",
+		"T": "Current processing, transmitted at intervals (scheduled or on demand)",
+	},
+	// Alternate character sets
+	"0211": {
+		"ASCII":     "The printable 7-bit ASCII character set",
+		"8859/1":    "The printable characters from the ISO 8859/1 Character set",
+		"8859/2":    "The printable characters from the ISO 8859/2 Character set",
+		"8859/3":    "The printable characters from the ISO 8859/3 Character set",
+		"8859/4":    "The printable characters from the ISO 8859/4 Character set",
+		"8859/5":    "The printable characters from the ISO 8859/5 Character set",
+		"8859/6":    "The printable characters from the ISO 8859/6 Character set",
+		"8859/7":    "The printable characters from the ISO 8859/7 Character set",
+		"8859/8":    "The printable characters from the ISO 8859/8 Character set",
+		"8859/9":    "The printable characters from the ISO 8859/9 Character set",
+		"ISO IR14":  "Code for Information Exchange (one byte)(JIS X 0201-1976)",
+		"ISO IR87":  "Code for the Japanese Graphic Character set for information interchange (JIS X 0208-1990)",
+		"ISO IR159": "Code of the supplementary Japanese Graphic Character set for information interchange (JIS X 0212-1990)",
+		"UNICODE":   "The world wide character standard from ISO/IEC 10646-1-1993",
+	},
+	// Transport arranged
+	"0224": {
+		"A": "Arranged",
+		"N": "Not Arranged",
+		"U": "Unknown",
+	},
+	// Escort required
+	"0225": {
+		"R": "Required",
+		"N": "Not Required",
+		"U": "Unknown",
+	},
+	// Universal ID type
+	"0301": {
+		"DNS":    "An Internet dotted name",
+		"GUID":   "Same as UUID",
+		"HCD":    "The CEN Healthcare Coding Scheme Designator",
+		"HL7":    "Reserved for future HL7 registration schemes",
+		"ISO":    "An International Standards Organization Object Identifier",
+		"L":      "Local",
+		"M":      "Local",
+		"N":      "Local",
+		"Random": "Usually a base64 encoded string of random bits",
+		"UUID":   "The DCE Universal Unique Identifier",
+		"x400":   "An X.400 MHS format identifier",
+		"x500":   "An X.500 directory name",
+	},
+}
+
+func init() {
+	for number, table := range Tables {
+		hl7x.RegisterTable(Version, number, table)
+	}
+}
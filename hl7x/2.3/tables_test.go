@@ -0,0 +1,35 @@
+package hl7v2_3
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kdar/health/hl7"
+	"github.com/kdar/health/hl7x"
+)
+
+func TestValidateTables(t *testing.T) {
+	data := []byte("MSH|^~\\&|LAB|HOSP|||20140922091808||ADT^A01|1|P|2.3\r" +
+		"PID|1||123||DOE^JOHN||19700101|X||W||||^WPN^PH~^ZZZ^PH|||||||||||||Y\r")
+
+	segments, err := hl7.Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var pid Pid
+	if err := hl7x.Unmarshal(segments[1], &pid); err != nil {
+		t.Fatal(err)
+	}
+
+	// Race (0005) is user-defined, so W isn't checked
+	expected := []*hl7x.CodeError{
+		{Position: "PID.8", Table: "0001", Value: "X"},
+		{Position: "PID.14[2].2", Table: "0201", Value: "ZZZ"},
+	}
+
+	errs := hl7x.ValidateTables(Version, &pid)
+	if !reflect.DeepEqual(errs, expected) {
+		t.Fatalf("expected %v, got %v", expected, errs)
+	}
+}
@@ -0,0 +1,391 @@
+package hl7x
+
+import (
+	"bytes"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// Precision is the smallest unit of time stated in an HL7
+// date/time value. e.g. "201409" has a precision of PrecisionMonth.
+type Precision int
+
+const (
+	PrecisionNone Precision = iota
+	PrecisionYear
+	PrecisionMonth
+	PrecisionDay
+	PrecisionHour
+	PrecisionMinute
+	PrecisionSecond
+)
+
+// the number of digits each unit takes up
+var precisionWidths = [...]int{
+	PrecisionYear:   4,
+	PrecisionMonth:  2,
+	PrecisionDay:    2,
+	PrecisionHour:   2,
+	PrecisionMinute: 2,
+	PrecisionSecond: 2,
+}
+
+func (p Precision) String() string {
+	switch p {
+	case PrecisionNone:
+		return "none"
+	case PrecisionYear:
+		return "year"
+	case PrecisionMonth:
+		return "month"
+	case PrecisionDay:
+		return "day"
+	case PrecisionHour:
+		return "hour"
+	case PrecisionMinute:
+		return "minute"
+	case PrecisionSecond:
+		return "second"
+	}
+
+	return "Precision(" + strconv.Itoa(int(p)) + ")"
+}
+
+// the most fractional second digits HL7 allows
+const maxFractionDigits = 4
+
+// DateTime is an HL7 point in time (TS.1 in older versions, DTM in newer).
+// Format: YYYY[MM[DD[HH[MM[SS[.S[S[S[S]]]]]]]]][+/-ZZZZ]
+type DateTime struct {
+	Time time.Time
+
+	// Precision is the smallest unit stated in the value. Units
+	// smaller than this in Time are zero.
+	Precision Precision
+
+	// FractionDigits is the number of fractional second
+	// digits stated (0-4).
+	FractionDigits int
+
+	// Zoned reports whether a timezone offset was stated. When it
+	// wasn't, Time is in UTC but should be treated as the local time
+	// of the sender.
+	Zoned bool
+}
+
+// NewDateTime creates a DateTime from t that will be
+// encoded with the given precision and t's timezone offset.
+func NewDateTime(t time.Time, p Precision) DateTime {
+	return DateTime{Time: t, Precision: p, Zoned: p >= PrecisionHour}
+}
+
+// IsZero reports whether the value was empty.
+func (d DateTime) IsZero() bool {
+	return d.Precision == PrecisionNone && d.Time.IsZero()
+}
+
+func (d DateTime) String() string {
+	b, _ := d.MarshalText()
+	return string(b)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *DateTime) UnmarshalText(text []byte) error {
+	m, err := parseMoment(text, PrecisionYear, PrecisionSecond, true)
+	if err != nil {
+		return fmt.Errorf("invalid DTM %q: %s", text, err)
+	}
+
+	*d = DateTime{Time: m.t, Precision: m.precision, FractionDigits: m.fraction, Zoned: m.zoned}
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d DateTime) MarshalText() ([]byte, error) {
+	if d.IsZero() {
+		return []byte{}, nil
+	}
+
+	m := moment{t: d.Time, precision: d.Precision, fraction: d.FractionDigits, zoned: d.Zoned}
+	if m.precision == PrecisionNone {
+		m.precision = PrecisionSecond
+		m.zoned = true
+	}
+
+	return m.format(PrecisionYear)
+}
+
+// Date is an HL7 date (DT).
+// Format: YYYY[MM[DD]]
+type Date struct {
+	Time time.Time
+
+	// Precision is the smallest unit stated in the value.
+	Precision Precision
+}
+
+// NewDate creates a Date from t that will be encoded
+// with the given precision.
+func NewDate(t time.Time, p Precision) Date {
+	return Date{Time: t, Precision: p}
+}
+
+// IsZero reports whether the value was empty.
+func (d Date) IsZero() bool {
+	return d.Precision == PrecisionNone && d.Time.IsZero()
+}
+
+func (d Date) String() string {
+	b, _ := d.MarshalText()
+	return string(b)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (d *Date) UnmarshalText(text []byte) error {
+	m, err := parseMoment(text, PrecisionYear, PrecisionDay, false)
+	if err != nil {
+		return fmt.Errorf("invalid DT %q: %s", text, err)
+	}
+
+	*d = Date{Time: m.t, Precision: m.precision}
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (d Date) MarshalText() ([]byte, error) {
+	if d.IsZero() {
+		return []byte{}, nil
+	}
+
+	m := moment{t: d.Time, precision: d.Precision}
+	if m.precision == PrecisionNone || m.precision > PrecisionDay {
+		m.precision = PrecisionDay
+	}
+
+	return m.format(PrecisionYear)
+}
+
+// Time is an HL7 time of day (TM). The date portion of
+// Time is always January 1st, year 0.
+// Format: HH[MM[SS[.S[S[S[S]]]]]][+/-ZZZZ]
+type Time struct {
+	Time time.Time
+
+	// Precision is the smallest unit stated in the value.
+	Precision Precision
+
+	// FractionDigits is the number of fractional second
+	// digits stated (0-4).
+	FractionDigits int
+
+	// Zoned reports whether a timezone offset was stated.
+	Zoned bool
+}
+
+// NewTime creates a Time from the clock of t that will be encoded
+// with the given precision and t's timezone offset.
+func NewTime(t time.Time, p Precision) Time {
+	return Time{Time: t, Precision: p, Zoned: true}
+}
+
+// IsZero reports whether the value was empty.
+func (t Time) IsZero() bool {
+	return t.Precision == PrecisionNone && t.Time.IsZero()
+}
+
+func (t Time) String() string {
+	b, _ := t.MarshalText()
+	return string(b)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (t *Time) UnmarshalText(text []byte) error {
+	m, err := parseMoment(text, PrecisionHour, PrecisionSecond, true)
+	if err != nil {
+		return fmt.Errorf("invalid TM %q: %s", text, err)
+	}
+
+	*t = Time{Time: m.t, Precision: m.precision, FractionDigits: m.fraction, Zoned: m.zoned}
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (t Time) MarshalText() ([]byte, error) {
+	if t.IsZero() {
+		return []byte{}, nil
+	}
+
+	m := moment{t: t.Time, precision: t.Precision, fraction: t.FractionDigits, zoned: t.Zoned}
+	if m.precision < PrecisionHour {
+		m.precision = PrecisionSecond
+	}
+
+	return m.format(PrecisionHour)
+}
+
+// moment is what's shared between all the HL7 date/time formats
+type moment struct {
+	t         time.Time
+	precision Precision
+	fraction  int
+	zoned     bool
+}
+
+// parseMoment parses the units first through last from text, followed by
+// optional fractional seconds and timezone offset if last is PrecisionSecond
+// and zoned is true.
+func parseMoment(text []byte, first, last Precision, zoned bool) (moment, error) {
+	m := moment{}
+	if len(text) == 0 {
+		return m, nil
+	}
+
+	// year, month, day, hour, minute, second
+	units := [...]int{0, 1, 1, 0, 0, 0}
+
+	i := 0
+	for p := first; p <= last && i < len(text) && isDigit(text[i]); p++ {
+		w := precisionWidths[p]
+		if i+w > len(text) {
+			return m, fmt.Errorf("truncated %s", p)
+		}
+
+		n, err := parseDigits(text[i : i+w])
+		if err != nil {
+			return m, err
+		}
+
+		units[p-PrecisionYear] = n
+		m.precision = p
+		i += w
+	}
+
+	if m.precision == PrecisionNone {
+		return m, fmt.Errorf("expected %s", first)
+	}
+
+	nsec := 0
+	if i < len(text) && text[i] == '.' {
+		if m.precision != PrecisionSecond {
+			return m, fmt.Errorf("fractional seconds without seconds")
+		}
+		i++
+
+		start := i
+		for i < len(text) && isDigit(text[i]) {
+			i++
+		}
+		digits := text[start:i]
+		if len(digits) == 0 || len(digits) > maxFractionDigits {
+			return m, fmt.Errorf("expected 1 to %d fractional second digits", maxFractionDigits)
+		}
+
+		n, _ := parseDigits(digits)
+		for x := len(digits); x < 9; x++ {
+			n *= 10
+		}
+		nsec = n
+		m.fraction = len(digits)
+	}
+
+	loc := time.UTC
+	if i < len(text) && zoned && (text[i] == '+' || text[i] == '-') {
+		zone := text[i+1:]
+		if len(zone) != 4 {
+			return m, fmt.Errorf("timezone offset must be +/-ZZZZ")
+		}
+
+		hh, err := parseDigits(zone[:2])
+		if err != nil {
+			return m, err
+		}
+		mm, err := parseDigits(zone[2:])
+		if err != nil {
+			return m, err
+		}
+
+		offset := hh*3600 + mm*60
+		if text[i] == '-' {
+			offset = -offset
+		}
+		loc = time.FixedZone("", offset)
+		m.zoned = true
+		i = len(text)
+	}
+
+	if i != len(text) {
+		return m, fmt.Errorf("unexpected %q", text[i:])
+	}
+
+	if units[1] < 1 || units[1] > 12 {
+		return m, fmt.Errorf("month out of range")
+	}
+	if units[2] < 1 || units[2] > 31 {
+		return m, fmt.Errorf("day out of range")
+	}
+	if units[3] > 23 || units[4] > 59 || units[5] > 59 {
+		return m, fmt.Errorf("time out of range")
+	}
+
+	m.t = time.Date(units[0], time.Month(units[1]), units[2], units[3], units[4], units[5], nsec, loc)
+	if m.t.Day() != units[2] {
+		return m, fmt.Errorf("day out of range")
+	}
+
+	return m, nil
+}
+
+// format writes out the moment starting at the unit first.
+func (m moment) format(first Precision) ([]byte, error) {
+	buf := &bytes.Buffer{}
+
+	units := [...]int{
+		m.t.Year(), int(m.t.Month()), m.t.Day(),
+		m.t.Hour(), m.t.Minute(), m.t.Second(),
+	}
+
+	for p := first; p <= m.precision; p++ {
+		n := units[p-PrecisionYear]
+		if p == PrecisionYear && (n < 0 || n > 9999) {
+			return nil, fmt.Errorf("year %d cannot be represented", n)
+		}
+		fmt.Fprintf(buf, "%0*d", precisionWidths[p], n)
+	}
+
+	if m.precision == PrecisionSecond && m.fraction > 0 {
+		digits := m.fraction
+		if digits > maxFractionDigits {
+			digits = maxFractionDigits
+		}
+
+		n := m.t.Nanosecond()
+		for x := digits; x < 9; x++ {
+			n /= 10
+		}
+		fmt.Fprintf(buf, ".%0*d", digits, n)
+	}
+
+	if m.zoned {
+		buf.WriteString(m.t.Format("-0700"))
+	}
+
+	return buf.Bytes(), nil
+}
+
+func isDigit(b byte) bool {
+	return b >= '0' && b <= '9'
+}
+
+// parseDigits parses b as an unsigned decimal number.
+func parseDigits(b []byte) (int, error) {
+	n := 0
+	for _, c := range b {
+		if !isDigit(c) {
+			return 0, fmt.Errorf("expected digit, found %q", c)
+		}
+		n = n*10 + int(c-'0')
+	}
+
+	return n, nil
+}
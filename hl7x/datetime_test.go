@@ -0,0 +1,99 @@
+package hl7x
+
+import (
+	"testing"
+	"time"
+)
+
+var dateTimeTests = []struct {
+	in       string
+	t        time.Time
+	prec     Precision
+	fraction int
+	zoned    bool
+}{
+	{"2014", time.Date(2014, 1, 1, 0, 0, 0, 0, time.UTC), PrecisionYear, 0, false},
+	{"201409", time.Date(2014, 9, 1, 0, 0, 0, 0, time.UTC), PrecisionMonth, 0, false},
+	{"20140922", time.Date(2014, 9, 22, 0, 0, 0, 0, time.UTC), PrecisionDay, 0, false},
+	{"2014092209", time.Date(2014, 9, 22, 9, 0, 0, 0, time.UTC), PrecisionHour, 0, false},
+	{"201409220918", time.Date(2014, 9, 22, 9, 18, 0, 0, time.UTC), PrecisionMinute, 0, false},
+	{"20140922091808", time.Date(2014, 9, 22, 9, 18, 8, 0, time.UTC), PrecisionSecond, 0, false},
+	{"20140922091808.1", time.Date(2014, 9, 22, 9, 18, 8, 100000000, time.UTC), PrecisionSecond, 1, false},
+	{"20140922091808.1200", time.Date(2014, 9, 22, 9, 18, 8, 120000000, time.UTC), PrecisionSecond, 4, false},
+	{"20140922091808-0500", time.Date(2014, 9, 22, 9, 18, 8, 0, time.FixedZone("", -5*3600)), PrecisionSecond, 0, true},
+	{"201409220918+0530", time.Date(2014, 9, 22, 9, 18, 0, 0, time.FixedZone("", 5*3600+30*60)), PrecisionMinute, 0, true},
+}
+
+func TestDateTime(t *testing.T) {
+	for i, tt := range dateTimeTests {
+		var d DateTime
+		if err := d.UnmarshalText([]byte(tt.in)); err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+
+		if !d.Time.Equal(tt.t) || d.Precision != tt.prec || d.FractionDigits != tt.fraction || d.Zoned != tt.zoned {
+			t.Fatalf("%d. mismatch\nhave: %s %s %d %v\nwant: %s %s %d %v", i,
+				d.Time, d.Precision, d.FractionDigits, d.Zoned,
+				tt.t, tt.prec, tt.fraction, tt.zoned)
+		}
+
+		_, offset := d.Time.Zone()
+		_, wantOffset := tt.t.Zone()
+		if offset != wantOffset {
+			t.Fatalf("%d. offset mismatch: have %d, want %d", i, offset, wantOffset)
+		}
+
+		if out := d.String(); out != tt.in {
+			t.Fatalf("%d. unexpected output: %s. want %s", i, out, tt.in)
+		}
+	}
+}
+
+var badDateTimes = []string{
+	"201",
+	"2014091",
+	"20141301",
+	"20140231",
+	"20140922091808.12345",
+	"201409220918.1",
+	"20140922091808-05",
+	"20140922 0918",
+}
+
+func TestDateTimeInvalid(t *testing.T) {
+	for _, in := range badDateTimes {
+		var d DateTime
+		if err := d.UnmarshalText([]byte(in)); err == nil {
+			t.Fatalf("did not error on %q", in)
+		}
+	}
+}
+
+func TestDateAndTime(t *testing.T) {
+	var d Date
+	if err := d.UnmarshalText([]byte("19700101")); err != nil {
+		t.Fatalf("received error: %s", err)
+	}
+	if d.Precision != PrecisionDay || d.Time.Year() != 1970 || d.String() != "19700101" {
+		t.Fatalf("unexpected date: %s %s", d.Time, d.Precision)
+	}
+	if err := d.UnmarshalText([]byte("19700101120000")); err == nil {
+		t.Fatalf("DT should not accept a time")
+	}
+
+	var tm Time
+	if err := tm.UnmarshalText([]byte("1230.5-0700")); err == nil {
+		t.Fatalf("TM should not accept fractions without seconds")
+	}
+	if err := tm.UnmarshalText([]byte("123005.5-0700")); err != nil {
+		t.Fatalf("received error: %s", err)
+	}
+	if tm.Precision != PrecisionSecond || tm.Time.Hour() != 12 || tm.Time.Second() != 5 || tm.String() != "123005.5-0700" {
+		t.Fatalf("unexpected time: %s %s", tm.Time, tm.Precision)
+	}
+
+	dt := NewDateTime(time.Date(2014, 9, 22, 9, 18, 0, 0, time.UTC), PrecisionMinute)
+	if out := dt.String(); out != "201409220918+0000" {
+		t.Fatalf("unexpected output: %s", out)
+	}
+}
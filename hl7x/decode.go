@@ -1,6 +1,7 @@
 package hl7x
 
 import (
+	"encoding"
 	"errors"
 	"fmt"
 	"reflect"
@@ -8,15 +9,22 @@ import (
 	"github.com/kdar/health/hl7"
 )
 
+// Unmarshal decodes src into the value pointed to by dst. This is usually
+// a segment struct, but can be any field type too (a composite, a primitive
+// such as *DateTime, or a slice of repetitions). If dst implements
+// Unmarshaler, its UnmarshalHL7 is used instead.
 func Unmarshal(src hl7.Data, dst interface{}) error {
+	if u, ok := dst.(Unmarshaler); ok {
+		return u.UnmarshalHL7(src)
+	}
+
 	v := reflect.ValueOf(dst)
-	if v.Kind() != reflect.Ptr || v.Elem().Kind() != reflect.Struct {
-		return errors.New("interface must be a pointer to struct")
+	if v.Kind() != reflect.Ptr || v.IsNil() {
+		return errors.New("interface must be a non-nil pointer")
 	}
-	v = v.Elem()
 
 	d := newDecoder()
-	d.decode(src, reflect.ValueOf(dst).Elem())
+	d.decode(src, v.Elem())
 	if d.err.Errors != nil {
 		return d.err
 	}
@@ -34,6 +42,27 @@ func newDecoder() *decoder {
 }
 
 func (d *decoder) decode(src hl7.Data, dst reflect.Value) {
+	if u, ok := unmarshaler(dst); ok {
+		if err := u.UnmarshalHL7(src); err != nil {
+			d.err.append(err)
+		}
+		return
+	}
+
+	if u, ok := textUnmarshaler(dst); ok {
+		d.decodeText(src, u)
+		return
+	}
+
+	// only slices can hold repetitions, so anything else
+	// just gets the first one.
+	if r, ok := src.(hl7.Repeated); ok && dst.Kind() != reflect.Slice {
+		if len(r) == 0 {
+			return
+		}
+		src = r[0]
+	}
+
 	dstKind := dst.Kind()
 	switch dstKind {
 	case reflect.String:
@@ -58,6 +87,48 @@ func (d *decoder) decodeString(src hl7.Data, dst reflect.Value) {
 	dst.SetString(string(v))
 }
 
+// unmarshaler returns dst as an Unmarshaler if it implements it.
+func unmarshaler(dst reflect.Value) (Unmarshaler, bool) {
+	if !dst.CanAddr() {
+		return nil, false
+	}
+
+	u, ok := dst.Addr().Interface().(Unmarshaler)
+	return u, ok
+}
+
+// textUnmarshaler returns dst as an encoding.TextUnmarshaler if it
+// implements it. This is how the primitive types (DateTime, Number, ...)
+// get decoded.
+func textUnmarshaler(dst reflect.Value) (encoding.TextUnmarshaler, bool) {
+	if !dst.CanAddr() {
+		return nil, false
+	}
+
+	u, ok := dst.Addr().Interface().(encoding.TextUnmarshaler)
+	return u, ok
+}
+
+func (d *decoder) decodeText(src hl7.Data, dst encoding.TextUnmarshaler) {
+	// a primitive is only ever the first component if
+	// there happens to be more.
+	for {
+		if _, ok := src.(hl7.Field); ok {
+			break
+		}
+
+		next, ok := src.Index(0)
+		if !ok {
+			return
+		}
+		src = next
+	}
+
+	if err := dst.UnmarshalText(src.(hl7.Field)); err != nil {
+		d.err.append(err)
+	}
+}
+
 func (d *decoder) decodeStruct(src hl7.Data, dst reflect.Value) {
 	typ := dst.Type()
 	for i := 0; i < typ.NumField(); i++ {
@@ -91,6 +162,11 @@ func (d *decoder) decodeSlice(src hl7.Data, dst reflect.Value) {
 	dstElemType := dstType.Elem()
 	sliceType := reflect.SliceOf(dstElemType)
 
+	// a composite that isn't repeated is a single repetition
+	if _, ok := src.(hl7.Repeated); !ok && dstElemType.Kind() == reflect.Struct {
+		src = hl7.Repeated{src}
+	}
+
 	srcLen := src.Len()
 	dstSlice := reflect.MakeSlice(sliceType, srcLen, srcLen)
 	for i := 0; i < srcLen; i++ {
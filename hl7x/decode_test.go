@@ -0,0 +1,137 @@
+package hl7x_test
+
+import (
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kdar/health/hl7"
+	"github.com/kdar/health/hl7x"
+)
+
+// a vendor that pads its codes with spaces
+type paddedCode string
+
+func (c *paddedCode) UnmarshalHL7(src hl7.Data) error {
+	f, ok := src.(hl7.Field)
+	if !ok {
+		return errors.New("paddedCode must be a field")
+	}
+
+	*c = paddedCode(strings.TrimSpace(string(f)))
+	return nil
+}
+
+func (c paddedCode) MarshalHL7() (hl7.Data, error) {
+	return hl7.Field(c + "  "), nil
+}
+
+type vendorCe struct {
+	Identifier paddedCode `position:"CE.1"`
+	Text       string     `position:"CE.2"`
+}
+
+type vendorObx struct {
+	SetID      string   `position:"OBX.1"`
+	ValueType  string   `position:"OBX.2"`
+	Identifier vendorCe `position:"OBX.3"`
+}
+
+// a Z-segment that handles the whole segment itself
+type zpd struct {
+	Values []string
+}
+
+func (z *zpd) UnmarshalHL7(src hl7.Data) error {
+	for i := 1; i < src.Len(); i++ {
+		v, _ := src.Index(i)
+		f, _ := v.(hl7.Field)
+		z.Values = append(z.Values, string(f))
+	}
+	return nil
+}
+
+func (z *zpd) MarshalHL7() (hl7.Data, error) {
+	segment := hl7.Segment{hl7.Field("ZPD")}
+	for _, v := range z.Values {
+		segment = append(segment, hl7.Field(v))
+	}
+	return segment, nil
+}
+
+type badMarshaler struct{}
+
+func (b badMarshaler) MarshalHL7() (hl7.Data, error) {
+	return hl7.Component{hl7.Field("a"), hl7.Field("b")}, nil
+}
+
+type badObx struct {
+	SetID string      `position:"OBX.1"`
+	Value vendorInner `position:"OBX.2"`
+}
+
+type vendorInner struct {
+	Part badMarshaler `position:"XX.1"`
+	Text string       `position:"XX.2"`
+}
+
+func TestFieldUnmarshaler(t *testing.T) {
+	segment := hl7.Segment{
+		hl7.Field("OBX"),
+		hl7.Field("1"),
+		hl7.Field("CE"),
+		hl7.Component{hl7.Field(" GLU "), hl7.Field("Glucose")},
+	}
+
+	var obx vendorObx
+	if err := hl7x.Unmarshal(segment, &obx); err != nil {
+		t.Fatalf("received error: %s", err)
+	}
+
+	if obx.Identifier.Identifier != "GLU" {
+		t.Fatalf("custom unmarshaler not used: %q", obx.Identifier.Identifier)
+	}
+
+	out, err := hl7x.Marshal(&obx)
+	if err != nil {
+		t.Fatalf("received error: %s", err)
+	}
+
+	want := hl7.Segment{
+		hl7.Field("OBX"),
+		hl7.Field("1"),
+		hl7.Field("CE"),
+		hl7.Component{hl7.Field("GLU  "), hl7.Field("Glucose")},
+	}
+	if !reflect.DeepEqual(out, want) {
+		t.Fatalf("mismatch\nhave: %#v\nwant: %#v", out, want)
+	}
+}
+
+func TestSegmentUnmarshaler(t *testing.T) {
+	segment := hl7.Segment{hl7.Field("ZPD"), hl7.Field("a"), hl7.Field("b")}
+
+	var z zpd
+	if err := hl7x.Unmarshal(segment, &z); err != nil {
+		t.Fatalf("received error: %s", err)
+	}
+
+	if !reflect.DeepEqual(z.Values, []string{"a", "b"}) {
+		t.Fatalf("unexpected values: %v", z.Values)
+	}
+
+	out, err := hl7x.Marshal(&z)
+	if err != nil {
+		t.Fatalf("received error: %s", err)
+	}
+	if !reflect.DeepEqual(out, segment) {
+		t.Fatalf("mismatch\nhave: %#v\nwant: %#v", out, segment)
+	}
+}
+
+func TestMarshalerLevel(t *testing.T) {
+	if _, err := hl7x.Marshal(badObx{SetID: "1"}); err == nil {
+		t.Fatalf("did not error on a component placed inside a component")
+	}
+}
@@ -0,0 +1,293 @@
+package hl7x
+
+import (
+	"encoding"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/kdar/health/hl7"
+)
+
+// the nesting levels of a segment
+const (
+	levelField = iota + 1
+	levelComponent
+	levelSubComponent
+)
+
+// Marshal converts a segment struct (e.g. hl7v2_3.Obx) into an hl7.Segment
+// that can be passed to hl7.Marshal. The segment name is taken from
+// the position tag of the struct's first field. If src implements
+// Marshaler, its MarshalHL7 is used instead.
+func Marshal(src interface{}) (hl7.Segment, error) {
+	if m, ok := src.(Marshaler); ok {
+		data, err := m.MarshalHL7()
+		if err != nil {
+			return nil, err
+		}
+
+		segment, ok := data.(hl7.Segment)
+		if !ok {
+			return nil, fmt.Errorf("%T.MarshalHL7 returned %T, expected hl7.Segment", src, data)
+		}
+		return segment, nil
+	}
+
+	v := reflect.ValueOf(src)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, errors.New("interface must be a struct or a pointer to struct")
+	}
+
+	name := segmentName(v.Type())
+	if name == "" {
+		return nil, fmt.Errorf("could not determine the segment name of %s", v.Type())
+	}
+
+	e := newEncoder()
+	segment := e.encodeSegment(name, v)
+	if e.err.Errors != nil {
+		return nil, e.err
+	}
+	return segment, nil
+}
+
+// MarshalField converts a value that sits in a single field of a segment
+// (a primitive, a composite, or a slice of repetitions) into hl7.Data.
+func MarshalField(src interface{}) (hl7.Data, error) {
+	if src == nil {
+		return hl7.Field{}, nil
+	}
+
+	e := newEncoder()
+	data := e.encode(reflect.ValueOf(src), levelField)
+	if e.err.Errors != nil {
+		return nil, e.err
+	}
+	return data, nil
+}
+
+// segmentName gets the segment name from the position tag of
+// the first field. e.g. `position:"OBX.1"` -> OBX
+func segmentName(typ reflect.Type) string {
+	if typ.NumField() == 0 {
+		return ""
+	}
+
+	pos := typ.Field(0).Tag.Get("position")
+	if i := strings.Index(pos, "."); i > 0 {
+		return pos[:i]
+	}
+	return ""
+}
+
+type encoder struct {
+	err *Error
+}
+
+func newEncoder() *encoder {
+	return &encoder{
+		err: &Error{},
+	}
+}
+
+func (e *encoder) encodeSegment(name string, src reflect.Value) hl7.Segment {
+	segment := hl7.Segment{hl7.Field(name)}
+	for i := 0; i < src.NumField(); i++ {
+		segment = append(segment, e.encode(src.Field(i), levelField))
+	}
+
+	// trailing empty fields are left off
+	for len(segment) > 1 && isEmpty(segment[len(segment)-1]) {
+		segment = segment[:len(segment)-1]
+	}
+
+	return segment
+}
+
+func (e *encoder) encode(src reflect.Value, level int) hl7.Data {
+	if m, ok := marshaler(src); ok {
+		return e.encodeMarshaler(m, level)
+	}
+
+	if m, ok := textMarshaler(src); ok {
+		return e.encodeText(m)
+	}
+
+	switch src.Kind() {
+	case reflect.String:
+		return hl7.Field(src.String())
+	case reflect.Struct:
+		return e.encodeStruct(src, level)
+	case reflect.Slice:
+		return e.encodeSlice(src, level)
+	case reflect.Ptr, reflect.Interface:
+		if src.IsNil() {
+			return hl7.Field{}
+		}
+		return e.encode(src.Elem(), level)
+	default:
+		e.err.append(fmt.Errorf("unsupported type: %s", src.Kind()))
+	}
+
+	return hl7.Field{}
+}
+
+// marshaler returns src as a Marshaler if it implements it.
+func marshaler(src reflect.Value) (Marshaler, bool) {
+	if !src.CanInterface() || (src.Kind() == reflect.Ptr && src.IsNil()) {
+		return nil, false
+	}
+
+	if m, ok := src.Interface().(Marshaler); ok {
+		return m, true
+	}
+
+	if src.CanAddr() {
+		m, ok := src.Addr().Interface().(Marshaler)
+		return m, ok
+	}
+
+	return nil, false
+}
+
+// encodeMarshaler calls MarshalHL7 and makes sure what it gives
+// back can be placed at the current level.
+func (e *encoder) encodeMarshaler(src Marshaler, level int) hl7.Data {
+	data, err := src.MarshalHL7()
+	if err != nil {
+		e.err.append(err)
+		return hl7.Field{}
+	}
+
+	if data == nil {
+		return hl7.Field{}
+	}
+
+	fits := false
+	switch data.(type) {
+	case hl7.Field:
+		fits = true
+	case hl7.SubComponent:
+		fits = level <= levelComponent
+	case hl7.Component, hl7.Repeated:
+		fits = level == levelField
+	}
+
+	if !fits {
+		e.err.append(fmt.Errorf("%T.MarshalHL7 returned %T, which can't be placed at this level", src, data))
+		return hl7.Field{}
+	}
+
+	return data
+}
+
+// textMarshaler returns src as an encoding.TextMarshaler if it
+// implements it.
+func textMarshaler(src reflect.Value) (encoding.TextMarshaler, bool) {
+	if !src.CanInterface() || (src.Kind() == reflect.Ptr && src.IsNil()) {
+		return nil, false
+	}
+
+	if m, ok := src.Interface().(encoding.TextMarshaler); ok {
+		return m, true
+	}
+
+	if src.CanAddr() {
+		m, ok := src.Addr().Interface().(encoding.TextMarshaler)
+		return m, ok
+	}
+
+	return nil, false
+}
+
+func (e *encoder) encodeText(src encoding.TextMarshaler) hl7.Data {
+	b, err := src.MarshalText()
+	if err != nil {
+		e.err.append(err)
+		return hl7.Field{}
+	}
+
+	return hl7.Field(b)
+}
+
+// encodeStruct encodes a composite as a Component, or a SubComponent if
+// it's already inside a component. Subcomponents can't nest any further,
+// so at that level only the first part of the composite is kept.
+func (e *encoder) encodeStruct(src reflect.Value, level int) hl7.Data {
+	if src.NumField() == 0 {
+		return hl7.Field{}
+	}
+
+	if level >= levelSubComponent {
+		return e.encode(src.Field(0), level)
+	}
+
+	var parts []hl7.Data
+	for i := 0; i < src.NumField(); i++ {
+		parts = append(parts, e.encode(src.Field(i), level+1))
+	}
+
+	for len(parts) > 0 && isEmpty(parts[len(parts)-1]) {
+		parts = parts[:len(parts)-1]
+	}
+
+	switch len(parts) {
+	case 0:
+		return hl7.Field{}
+	case 1:
+		return parts[0]
+	}
+
+	if level == levelComponent {
+		sub := hl7.SubComponent{}
+		for _, part := range parts {
+			// encode only gives back Fields at the subcomponent level
+			sub = append(sub, part.(hl7.Field))
+		}
+		return sub
+	}
+
+	return hl7.Component(parts)
+}
+
+// encodeSlice encodes repetitions. Only fields can repeat, so
+// anything deeper just keeps the first one.
+func (e *encoder) encodeSlice(src reflect.Value, level int) hl7.Data {
+	if src.Type().Elem().Kind() == reflect.Uint8 {
+		return hl7.Field(src.Bytes())
+	}
+
+	if src.Len() == 0 {
+		return hl7.Field{}
+	}
+
+	if level > levelField || src.Len() == 1 {
+		return e.encode(src.Index(0), level)
+	}
+
+	repeated := hl7.Repeated{}
+	for i := 0; i < src.Len(); i++ {
+		repeated = append(repeated, e.encode(src.Index(i), level))
+	}
+	return repeated
+}
+
+// isEmpty reports whether data holds nothing but empty fields.
+func isEmpty(data hl7.Data) bool {
+	if f, ok := data.(hl7.Field); ok {
+		return len(f) == 0
+	}
+
+	for i := 0; i < data.Len(); i++ {
+		if sub, ok := data.Index(i); ok && !isEmpty(sub) {
+			return false
+		}
+	}
+
+	return true
+}
@@ -0,0 +1,75 @@
+package hl7x_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/kdar/health/hl7"
+	"github.com/kdar/health/hl7x"
+	"github.com/kdar/health/hl7x/2.3"
+)
+
+const roundTripIn = "MSH|^~\\&|LAB|HOSP|||20140922091808.12-0500||ORU^R01|20140922091808|P|2.3\r" +
+	"PID|1||12345^^^HOSP^MR~67890^^^HOSP^PI||DOE^JOHN^Q||19700101|M\r" +
+	"OBX|01|NM|GLU^Glucose^L||0105.50|mg/dL|70-110|H|||F|||201409220915\r"
+
+func TestMarshalRoundTrip(t *testing.T) {
+	segments, err := hl7.Unmarshal([]byte(roundTripIn))
+	if err != nil {
+		t.Fatalf("received error: %s", err)
+	}
+
+	var msh hl7v2_3.Msh
+	var pid hl7v2_3.Pid
+	var obx hl7v2_3.Obx
+	dsts := []interface{}{&msh, &pid, &obx}
+
+	var out []hl7.Segment
+	for i, segment := range segments {
+		if err := hl7x.Unmarshal(segment, dsts[i]); err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+
+		s, err := hl7x.Marshal(dsts[i])
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+		out = append(out, s)
+	}
+
+	when := msh.DateTimeOfMessage.TimeOfAnEvent
+	if !when.Time.Equal(time.Date(2014, 9, 22, 14, 18, 8, 120000000, time.UTC)) || when.FractionDigits != 2 {
+		t.Fatalf("unexpected message time: %s", when.Time)
+	}
+	if pid.DateOfBirth.TimeOfAnEvent.Precision != hl7x.PrecisionDay {
+		t.Fatalf("unexpected date of birth precision: %s", pid.DateOfBirth.TimeOfAnEvent.Precision)
+	}
+	if len(pid.PatientIDInternalIDs) != 2 || pid.PatientIDInternalIDs[1].ID != "67890" {
+		t.Fatalf("unexpected patient ids: %v", pid.PatientIDInternalIDs)
+	}
+	if obx.SetIDOBX.Value != 1 {
+		t.Fatalf("unexpected set id: %d", obx.SetIDOBX.Value)
+	}
+
+	b, err := hl7.Marshal(out)
+	if err != nil {
+		t.Fatalf("received error: %s", err)
+	}
+
+	if !bytes.Equal(b, []byte(roundTripIn)) {
+		t.Fatalf("unexpected output:\n%s\nwant:\n%s",
+			strings.Replace(string(b), "\r", "\n", -1),
+			strings.Replace(roundTripIn, "\r", "\n", -1))
+	}
+}
+
+func TestUnmarshalInvalidPrimitive(t *testing.T) {
+	segment := hl7.Segment{hl7.Field("OBX"), hl7.Field("x1")}
+
+	var obx hl7v2_3.Obx
+	if err := hl7x.Unmarshal(segment, &obx); err == nil {
+		t.Fatalf("did not error on an invalid set id")
+	}
+}
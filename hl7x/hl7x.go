@@ -1,5 +1,22 @@
+// Package hl7x decodes and encodes HL7 segments into and out
+// of Go structs, such as the ones in hl7x/2.3.
 package hl7x
 
-// type Unmarshaler interface {
-// 	UnmarshalHL7(hl7.Data) error
-// }
+import "github.com/kdar/health/hl7"
+
+// Unmarshaler is the interface implemented by types that can decode
+// themselves from HL7 data. It can be implemented by whole segments
+// or by any field, component, or subcomponent type in them. src is
+// whatever is at that position in the message (a Segment, Repeated,
+// Component, SubComponent, or Field).
+type Unmarshaler interface {
+	UnmarshalHL7(src hl7.Data) error
+}
+
+// Marshaler is the interface implemented by types that can encode
+// themselves into HL7 data. The data returned must fit at the position
+// the value is in: a segment must return an hl7.Segment, a component a
+// Field or SubComponent, and a subcomponent a Field.
+type Marshaler interface {
+	MarshalHL7() (hl7.Data, error)
+}
@@ -0,0 +1,206 @@
+package hl7x
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+
+	"github.com/kdar/health/hl7"
+)
+
+// UnmarshalMessage decodes the segments of a message into the message
+// struct pointed to by dst. Each segment goes into the field for its name,
+// which is taken from the field's segment tag (e.g. `segment:"ZPD"`), or
+// otherwise from the position tags of the field's type. A field can be a
+// segment struct, a pointer to one, or a slice of either for segments that
+// repeat. It can also be an interface{} (or []interface{}) with a segment
+// tag, in which case the value is a new one of the type registered with
+// RegisterSegment.
+//
+// Segments that have no field, including repeats of a segment whose field
+// isn't a slice, are added to the []interface{} field tagged `segment:"*"`
+// if there is one. They're decoded into their registered type, or kept as
+// the hl7.Segment if there isn't one. Without that field they're skipped.
+//
+// Segment groups aren't nested: every segment is matched against the
+// fields of dst itself.
+func UnmarshalMessage(segments []hl7.Segment, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Ptr || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return errors.New("interface must be a non-nil pointer to struct")
+	}
+	v = v.Elem()
+
+	d := newDecoder()
+	fields, extra := d.messageFields(v)
+
+	seen := make(map[string]bool)
+	for _, segment := range segments {
+		name := segmentID(segment)
+
+		field, ok := fields[name]
+		if ok && (field.Kind() == reflect.Slice || !seen[name]) {
+			seen[name] = true
+			d.decodeSegment(segment, name, field)
+			continue
+		}
+
+		if extra.IsValid() {
+			value := reflect.New(extra.Type().Elem()).Elem()
+			d.decodeSegment(segment, name, value)
+			extra.Set(reflect.Append(extra, value))
+		}
+	}
+
+	if d.err.Errors != nil {
+		return d.err
+	}
+	return nil
+}
+
+// messageFields maps segment names to the fields of the message struct v,
+// and finds the field for segments that don't have one.
+func (d *decoder) messageFields(v reflect.Value) (map[string]reflect.Value, reflect.Value) {
+	fields := make(map[string]reflect.Value)
+	var extra reflect.Value
+
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		name := field.Tag.Get("segment")
+		switch name {
+		case "-":
+			continue
+		case "*":
+			if field.Type != reflect.TypeOf([]interface{}{}) {
+				d.err.append(fmt.Errorf("%s.%s must be a []interface{} to hold other segments", typ.Name(), field.Name))
+				continue
+			}
+			extra = v.Field(i)
+			continue
+		case "":
+			ft := field.Type
+			for ft.Kind() == reflect.Slice || ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if ft.Kind() == reflect.Struct {
+				name = segmentName(ft)
+			}
+		}
+
+		if name != "" {
+			fields[name] = v.Field(i)
+		}
+	}
+
+	return fields, extra
+}
+
+// decodeSegment decodes segment into dst, appending
+// to dst if it's a slice.
+func (d *decoder) decodeSegment(segment hl7.Segment, name string, dst reflect.Value) {
+	switch dst.Kind() {
+	case reflect.Slice:
+		elem := reflect.New(dst.Type().Elem()).Elem()
+		d.decodeSegment(segment, name, elem)
+		dst.Set(reflect.Append(dst, elem))
+	case reflect.Ptr:
+		if dst.IsNil() {
+			dst.Set(reflect.New(dst.Type().Elem()))
+		}
+		d.decode(segment, dst.Elem())
+	case reflect.Interface:
+		var value reflect.Value
+		if v, ok := NewSegment(name); ok {
+			value = reflect.ValueOf(v).Elem()
+			d.decode(segment, value)
+		} else {
+			value = reflect.ValueOf(segment)
+		}
+
+		if !value.Type().AssignableTo(dst.Type()) {
+			d.err.append(fmt.Errorf("%s segment of type %s can't be assigned to %s", name, value.Type(), dst.Type()))
+			return
+		}
+		dst.Set(value)
+	default:
+		d.decode(segment, dst)
+	}
+}
+
+// segmentID gets the name of segment, e.g. PID
+func segmentID(segment hl7.Segment) string {
+	if len(segment) == 0 {
+		return ""
+	}
+
+	f, _ := segment[0].(hl7.Field)
+	return string(f)
+}
+
+// MarshalMessage is the opposite of UnmarshalMessage. It converts the
+// segments in the message struct src into hl7.Segments, in the order of
+// its fields. Nil pointers and interfaces are left out, and hl7.Segment
+// values (such as ones UnmarshalMessage couldn't decode) are kept as is.
+func MarshalMessage(src interface{}) ([]hl7.Segment, error) {
+	v := reflect.ValueOf(src)
+	if v.Kind() == reflect.Ptr {
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil, errors.New("interface must be a struct or a pointer to struct")
+	}
+
+	var segments []hl7.Segment
+	err := &Error{}
+
+	var add func(v reflect.Value)
+	add = func(v reflect.Value) {
+		if (v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface) && v.IsNil() {
+			return
+		}
+		if v.Kind() == reflect.Interface {
+			v = v.Elem()
+		}
+
+		if s, ok := v.Interface().(hl7.Segment); ok {
+			segments = append(segments, s)
+			return
+		}
+
+		if v.Kind() == reflect.Slice {
+			for i := 0; i < v.Len(); i++ {
+				add(v.Index(i))
+			}
+			return
+		}
+
+		if v.Kind() == reflect.Struct && v.CanAddr() {
+			v = v.Addr()
+		}
+		segment, e := Marshal(v.Interface())
+		if e != nil {
+			err.append(e)
+			return
+		}
+		segments = append(segments, segment)
+	}
+
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" || field.Tag.Get("segment") == "-" {
+			continue
+		}
+		add(v.Field(i))
+	}
+
+	if err.Errors != nil {
+		return nil, err
+	}
+	return segments, nil
+}
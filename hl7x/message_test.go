@@ -0,0 +1,109 @@
+package hl7x_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kdar/health/hl7"
+	"github.com/kdar/health/hl7x"
+)
+
+type messagePid struct {
+	SetID string `position:"PID.1"`
+	ID    string `position:"PID.2"`
+}
+
+type messageObx struct {
+	SetID string `position:"OBX.1"`
+	Type  string `position:"OBX.2"`
+}
+
+// a site's visit number segment
+type zvn struct {
+	SetID  string `position:"ZVN.1"`
+	Number string `position:"ZVN.2"`
+}
+
+func init() {
+	hl7x.RegisterSegment("ZVN", zvn{})
+	hl7x.RegisterSegment("ZPD", &zpd{})
+}
+
+type message struct {
+	Pid   *messagePid
+	Obxs  []messageObx
+	Zvn   interface{}   `segment:"ZVN"`
+	Other []interface{} `segment:"*"`
+}
+
+func TestUnmarshalMessage(t *testing.T) {
+	segments := []hl7.Segment{
+		{hl7.Field("PID"), hl7.Field("1"), hl7.Field("123")},
+		{hl7.Field("OBX"), hl7.Field("1"), hl7.Field("NM")},
+		{hl7.Field("OBX"), hl7.Field("2"), hl7.Field("ST")},
+		{hl7.Field("ZVN"), hl7.Field("1"), hl7.Field("V100")},
+		{hl7.Field("ZPD"), hl7.Field("a"), hl7.Field("b")},
+		{hl7.Field("ZVN"), hl7.Field("2"), hl7.Field("V200")},
+		{hl7.Field("ZXX"), hl7.Field("x")},
+	}
+
+	expected := message{
+		Pid:  &messagePid{SetID: "1", ID: "123"},
+		Obxs: []messageObx{{"1", "NM"}, {"2", "ST"}},
+		Zvn:  zvn{SetID: "1", Number: "V100"},
+		Other: []interface{}{
+			zpd{Values: []string{"a", "b"}},
+			zvn{SetID: "2", Number: "V200"},
+			hl7.Segment{hl7.Field("ZXX"), hl7.Field("x")},
+		},
+	}
+
+	var m message
+	if err := hl7x.UnmarshalMessage(segments, &m); err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(m, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, m)
+	}
+}
+
+func TestUnmarshalMessageBadOther(t *testing.T) {
+	var m struct {
+		Other []string `segment:"*"`
+	}
+
+	if err := hl7x.UnmarshalMessage(nil, &m); err == nil {
+		t.Fatal("expected an error")
+	}
+}
+
+func TestMarshalMessage(t *testing.T) {
+	m := message{
+		Pid:  &messagePid{SetID: "1", ID: "123"},
+		Obxs: []messageObx{{"1", "NM"}, {"2", "ST"}},
+		Zvn:  zvn{SetID: "1", Number: "V100"},
+		Other: []interface{}{
+			&zpd{Values: []string{"a", "b"}},
+			hl7.Segment{hl7.Field("ZXX"), hl7.Field("x")},
+		},
+	}
+
+	expected := []hl7.Segment{
+		{hl7.Field("PID"), hl7.Field("1"), hl7.Field("123")},
+		{hl7.Field("OBX"), hl7.Field("1"), hl7.Field("NM")},
+		{hl7.Field("OBX"), hl7.Field("2"), hl7.Field("ST")},
+		{hl7.Field("ZVN"), hl7.Field("1"), hl7.Field("V100")},
+		{hl7.Field("ZPD"), hl7.Field("a"), hl7.Field("b")},
+		{hl7.Field("ZXX"), hl7.Field("x")},
+	}
+
+	segments, err := hl7x.MarshalMessage(&m)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if !reflect.DeepEqual(segments, expected) {
+		t.Fatalf("expected %#v, got %#v", expected, segments)
+	}
+}
@@ -0,0 +1,144 @@
+package hl7x
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Number is an HL7 numeric (NM) value. e.g. "-03.50"
+//
+// The text the value was decoded from is kept in Text so that it is
+// encoded back exactly, leading and trailing zeros included, as long as
+// Value hasn't been changed.
+type Number struct {
+	Value float64
+	Text  string
+}
+
+// NewNumber creates a Number from f.
+func NewNumber(f float64) Number {
+	return Number{Value: f, Text: strconv.FormatFloat(f, 'f', -1, 64)}
+}
+
+// IsZero reports whether the value was empty.
+func (n Number) IsZero() bool {
+	return n.Text == "" && n.Value == 0
+}
+
+func (n Number) String() string {
+	b, _ := n.MarshalText()
+	return string(b)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (n *Number) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*n = Number{}
+		return nil
+	}
+
+	f, err := parseNumeric(text)
+	if err != nil {
+		return err
+	}
+
+	*n = Number{Value: f, Text: string(text)}
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (n Number) MarshalText() ([]byte, error) {
+	if n.IsZero() {
+		return []byte{}, nil
+	}
+
+	if f, err := parseNumeric([]byte(n.Text)); err == nil && f == n.Value {
+		return []byte(n.Text), nil
+	}
+
+	return []byte(strconv.FormatFloat(n.Value, 'f', -1, 64)), nil
+}
+
+// SequenceID is an HL7 sequence ID (SI). A non-negative integer
+// used to number repeating segments.
+//
+// Like Number, the original text is kept in Text.
+type SequenceID struct {
+	Value int
+	Text  string
+}
+
+// NewSequenceID creates a SequenceID from i.
+func NewSequenceID(i int) SequenceID {
+	return SequenceID{Value: i, Text: strconv.Itoa(i)}
+}
+
+// IsZero reports whether the value was empty.
+func (s SequenceID) IsZero() bool {
+	return s.Text == "" && s.Value == 0
+}
+
+func (s SequenceID) String() string {
+	b, _ := s.MarshalText()
+	return string(b)
+}
+
+// UnmarshalText implements encoding.TextUnmarshaler.
+func (s *SequenceID) UnmarshalText(text []byte) error {
+	if len(text) == 0 {
+		*s = SequenceID{}
+		return nil
+	}
+
+	i, err := parseDigits(text)
+	if err != nil {
+		return fmt.Errorf("invalid SI %q: %s", text, err)
+	}
+
+	*s = SequenceID{Value: i, Text: string(text)}
+	return nil
+}
+
+// MarshalText implements encoding.TextMarshaler.
+func (s SequenceID) MarshalText() ([]byte, error) {
+	if s.IsZero() {
+		return []byte{}, nil
+	}
+
+	if s.Value < 0 {
+		return nil, fmt.Errorf("invalid SI %d: must not be negative", s.Value)
+	}
+
+	if i, err := parseDigits([]byte(s.Text)); err == nil && i == s.Value {
+		return []byte(s.Text), nil
+	}
+
+	return []byte(strconv.Itoa(s.Value)), nil
+}
+
+// parseNumeric parses an NM value. HL7 allows an optional leading sign,
+// digits and an optional decimal point. No exponents or thousands separators.
+func parseNumeric(text []byte) (float64, error) {
+	i := 0
+	if len(text) > 0 && (text[0] == '+' || text[0] == '-') {
+		i++
+	}
+
+	digits, points := 0, 0
+	for ; i < len(text); i++ {
+		switch {
+		case isDigit(text[i]):
+			digits++
+		case text[i] == '.':
+			points++
+		default:
+			return 0, fmt.Errorf("invalid NM %q: unexpected %q", text, text[i])
+		}
+	}
+
+	if digits == 0 || points > 1 {
+		return 0, fmt.Errorf("invalid NM %q", text)
+	}
+
+	return strconv.ParseFloat(string(text), 64)
+}
@@ -0,0 +1,64 @@
+package hl7x
+
+import (
+	"testing"
+)
+
+var numberTests = []struct {
+	in    string
+	value float64
+}{
+	{"105", 105},
+	{"0105.50", 105.5},
+	{"-3", -3},
+	{"+.5", 0.5},
+	{"1.", 1},
+}
+
+func TestNumber(t *testing.T) {
+	for i, tt := range numberTests {
+		var n Number
+		if err := n.UnmarshalText([]byte(tt.in)); err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+
+		if n.Value != tt.value {
+			t.Fatalf("%d. have %v, want %v", i, n.Value, tt.value)
+		}
+
+		if out := n.String(); out != tt.in {
+			t.Fatalf("%d. unexpected output: %s. want %s", i, out, tt.in)
+		}
+	}
+
+	for _, in := range []string{"1e5", "1,000", "1.2.3", "-", "."} {
+		var n Number
+		if err := n.UnmarshalText([]byte(in)); err == nil {
+			t.Fatalf("did not error on %q", in)
+		}
+	}
+
+	// changing the value discards the original text
+	n := Number{Value: 12.25, Text: "012.5"}
+	if out := n.String(); out != "12.25" {
+		t.Fatalf("unexpected output: %s", out)
+	}
+}
+
+func TestSequenceID(t *testing.T) {
+	var s SequenceID
+	if err := s.UnmarshalText([]byte("01")); err != nil {
+		t.Fatalf("received error: %s", err)
+	}
+	if s.Value != 1 || s.String() != "01" {
+		t.Fatalf("unexpected sequence id: %d %s", s.Value, s.String())
+	}
+
+	if err := s.UnmarshalText([]byte("-1")); err == nil {
+		t.Fatalf("did not error on a negative sequence id")
+	}
+
+	if out := NewSequenceID(3).String(); out != "3" {
+		t.Fatalf("unexpected output: %s", out)
+	}
+}
@@ -0,0 +1,173 @@
+// Package profile loads HL7 v2 conformance profiles (message profiles)
+// and validates messages against them.
+//
+// A profile narrows the base standard for one interface: which segments
+// and fields must be sent (usage), how many times they can repeat
+// (cardinality), and how long they can be.
+package profile
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Usage says whether an element must, may, or must not be sent.
+type Usage string
+
+const (
+	Required           Usage = "R"
+	RequiredOrEmpty    Usage = "RE"
+	Optional           Usage = "O"
+	Conditional        Usage = "C"
+	ConditionalOrEmpty Usage = "CE"
+	NotSupported       Usage = "X"
+	Backward           Usage = "B"
+	Withdrawn          Usage = "W"
+)
+
+// Max is the most times an element may repeat.
+type Max int
+
+// Unbounded is the Max of an element that can repeat any number
+// of times (* in the profile).
+const Unbounded Max = -1
+
+// UnmarshalXMLAttr implements xml.UnmarshalerAttr.
+func (m *Max) UnmarshalXMLAttr(attr xml.Attr) error {
+	s := strings.TrimSpace(attr.Value)
+	if s == "*" {
+		*m = Unbounded
+		return nil
+	}
+
+	i, err := strconv.Atoi(s)
+	if err != nil {
+		return fmt.Errorf("invalid Max %q", attr.Value)
+	}
+	*m = Max(i)
+	return nil
+}
+
+func (m Max) String() string {
+	if m == Unbounded {
+		return "*"
+	}
+	return strconv.Itoa(int(m))
+}
+
+// Profile is an HL7 v2 conformance profile. Only the static
+// definition (the message structure) is used.
+type Profile struct {
+	HL7Version  string    `xml:"HL7Version,attr"`
+	ProfileType string    `xml:"ProfileType,attr"`
+	MetaData    MetaData  `xml:"MetaData"`
+	StaticDef   StaticDef `xml:"StaticDef"`
+}
+
+type MetaData struct {
+	Name    string `xml:"Name,attr"`
+	OrgName string `xml:"OrgName,attr"`
+	Version string `xml:"Version,attr"`
+	Status  string `xml:"Status,attr"`
+}
+
+type StaticDef struct {
+	MsgType     string `xml:"MsgType,attr"`
+	EventType   string `xml:"EventType,attr"`
+	MsgStructID string `xml:"MsgStructID,attr"`
+	// the segments and segment groups of the message, in order
+	Elements []Element `xml:",any"`
+}
+
+// Element is a Segment or a SegGroup.
+type Element struct {
+	XMLName  xml.Name
+	Name     string `xml:"Name,attr"`
+	LongName string `xml:"LongName,attr"`
+	Usage    Usage  `xml:"Usage,attr"`
+	Min      int    `xml:"Min,attr"`
+	Max      Max    `xml:"Max,attr"`
+
+	// Fields of a Segment
+	Fields []Field `xml:"Field"`
+	// Elements of a SegGroup
+	Elements []Element `xml:",any"`
+}
+
+// IsGroup reports whether the element is a SegGroup.
+func (e Element) IsGroup() bool {
+	return e.XMLName.Local == "SegGroup"
+}
+
+type Field struct {
+	Name     string `xml:"Name,attr"`
+	Usage    Usage  `xml:"Usage,attr"`
+	Min      int    `xml:"Min,attr"`
+	Max      Max    `xml:"Max,attr"`
+	Datatype string `xml:"Datatype,attr"`
+	Length   int    `xml:"Length,attr"`
+	Table    string `xml:"Table,attr"`
+	ItemNo   string `xml:"ItemNo,attr"`
+
+	Components []Component `xml:"Component"`
+}
+
+// Component is a Component or a SubComponent of a field.
+type Component struct {
+	Name     string `xml:"Name,attr"`
+	Usage    Usage  `xml:"Usage,attr"`
+	Datatype string `xml:"Datatype,attr"`
+	Length   int    `xml:"Length,attr"`
+	Table    string `xml:"Table,attr"`
+
+	SubComponents []Component `xml:"SubComponent"`
+}
+
+// Parse reads a conformance profile.
+func Parse(r io.Reader) (*Profile, error) {
+	p := &Profile{}
+	if err := xml.NewDecoder(r).Decode(p); err != nil {
+		return nil, err
+	}
+
+	p.StaticDef.Elements = prune(p.StaticDef.Elements)
+	if len(p.StaticDef.Elements) == 0 {
+		return nil, fmt.Errorf("profile has no segments")
+	}
+
+	return p, nil
+}
+
+// ParseFile reads a conformance profile from the named file.
+func ParseFile(name string) (*Profile, error) {
+	fp, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer fp.Close()
+
+	return Parse(fp)
+}
+
+// prune drops everything that isn't a Segment or SegGroup (ImpNote,
+// Description, ...), since the any tag picks them up too.
+func prune(elems []Element) []Element {
+	var out []Element
+	for _, e := range elems {
+		switch e.XMLName.Local {
+		case "Segment":
+			e.Elements = nil
+		case "SegGroup":
+			e.Fields = nil
+			e.Elements = prune(e.Elements)
+		default:
+			continue
+		}
+		out = append(out, e)
+	}
+	return out
+}
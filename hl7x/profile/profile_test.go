@@ -0,0 +1,186 @@
+package profile
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/kdar/health/hl7"
+	"github.com/kdar/health/hl7x"
+	hl7v2_3 "github.com/kdar/health/hl7x/2.3"
+)
+
+const (
+	testMSH = "MSH|^~\\&|LAB|HOSP|||20140922091808||ORU^R01|1|P|2.3"
+	testPID = "PID|1||123^^^LAB||DOE^JOHN"
+)
+
+var validateTests = []struct {
+	segments []string
+	failures []string
+}{
+	{
+		[]string{testMSH, testPID, "OBR|1", "OBX|1|NM", "OBX|2|ST"},
+		nil,
+	},
+	{
+		// PATIENT is RE
+		[]string{testMSH, "OBR|1"},
+		nil,
+	},
+	{
+		[]string{testMSH, testPID, "OBR|1", testPID, "OBR|1", "OBR|2"},
+		nil,
+	},
+	{
+		[]string{"MSH|^~\\&|LAB|HOSP|||20140922091808|SECRET|ORU|1|P|2.3", testPID, "OBR|1"},
+		[]string{"MSH.8 not allowed", "MSH.9.2 missing"},
+	},
+	{
+		[]string{testMSH, "PID|1|X|123^^^LAB", "OBR|1"},
+		[]string{"PID.2 not allowed", "PID.5 missing"},
+	},
+	{
+		[]string{testMSH, "PID|1||1^^^LAB~2^^^LAB~3^^^LAB||DOE", "OBR|1"},
+		[]string{"PID.3 too many: 3 > 2"},
+	},
+	{
+		[]string{testMSH, "PID|1||12345678901~123^^^LABORATORY||DOE", "OBR|1"},
+		[]string{"PID.3[1].1 too long: 11 > 10", "PID.3[2].4.1 too long: 10 > 5"},
+	},
+	{
+		[]string{testMSH, testPID, "ZPD|1", "OBR|1", "OBX|1|NM", "ZPD|2"},
+		[]string{"ZPD[1] unexpected", "ZPD[2] unexpected"},
+	},
+	{
+		[]string{testMSH, testPID, "OBR|1", "OBX|1|NM", "OBX|2|NM", "OBX|3|NM", "OBX|4|NM"},
+		[]string{"OBX too many: 4 > 3"},
+	},
+	{
+		[]string{testMSH, testPID, "OBX|1|NM"},
+		[]string{"ORDER_OBSERVATION missing", "OBX unexpected"},
+	},
+}
+
+func parseSegments(t *testing.T, segments []string) []hl7.Segment {
+	out, err := hl7.Unmarshal([]byte(strings.Join(segments, "\r") + "\r"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+func failures(report *Report) []string {
+	var out []string
+	for _, r := range report.Failures() {
+		s := r.Position + " " + r.Status.String()
+		if r.Detail != "" {
+			s += ": " + r.Detail
+		}
+		out = append(out, s)
+	}
+	return out
+}
+
+func TestValidate(t *testing.T) {
+	p, err := ParseFile("testdata/oru_r01.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for i, tt := range validateTests {
+		report := p.Validate(parseSegments(t, tt.segments))
+
+		if f := failures(report); !reflect.DeepEqual(f, tt.failures) {
+			t.Fatalf("%d. expected %q, got %q\n%s", i, tt.failures, f, report)
+		}
+		if report.Valid() != (tt.failures == nil) {
+			t.Fatalf("%d. expected valid to be %v", i, tt.failures == nil)
+		}
+	}
+
+	// no MSH
+	segments := parseSegments(t, []string{testMSH, testPID, "OBR|1"})
+	expected := []string{"MSH missing"}
+	if f := failures(p.Validate(segments[1:])); !reflect.DeepEqual(f, expected) {
+		t.Fatalf("expected %q, got %q", expected, f)
+	}
+}
+
+func TestParse(t *testing.T) {
+	p, err := ParseFile("testdata/oru_r01.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if p.StaticDef.MsgStructID != "ORU_R01" || len(p.StaticDef.Elements) != 2 {
+		t.Fatalf("unexpected static definition: %+v", p.StaticDef)
+	}
+
+	group := p.StaticDef.Elements[1]
+	if !group.IsGroup() || group.Max != Unbounded || len(group.Elements) != 2 {
+		t.Fatalf("unexpected group: %+v", group)
+	}
+
+	pid := group.Elements[0].Elements[0]
+	if pid.Name != "PID" || len(pid.Elements) != 0 || len(pid.Fields) != 5 {
+		t.Fatalf("unexpected segment: %+v", pid)
+	}
+
+	if _, err := Parse(strings.NewReader("<HL7v2xConformanceProfile/>")); err == nil {
+		t.Fatal("expected an error for a profile without segments")
+	}
+}
+
+func TestReport(t *testing.T) {
+	p, err := ParseFile("testdata/oru_r01.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	report := p.Validate(parseSegments(t, []string{testMSH, testPID, "OBR|1"}))
+
+	// every field gets a result
+	var positions []string
+	for _, r := range report.Results {
+		if strings.HasPrefix(r.Position, "PID") {
+			positions = append(positions, r.Position)
+		}
+	}
+
+	expected := []string{"PID", "PID.1", "PID.2", "PID.3", "PID.4", "PID.5"}
+	if !reflect.DeepEqual(positions, expected) {
+		t.Fatalf("expected %v, got %v", expected, positions)
+	}
+}
+
+type oruR01 struct {
+	Msh  *hl7v2_3.Msh
+	Pid  *hl7v2_3.Pid
+	Obr  *hl7v2_3.Obr
+	Obxs []hl7v2_3.Obx
+}
+
+func TestValidateMessage(t *testing.T) {
+	p, err := ParseFile("testdata/oru_r01.xml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var msg oruR01
+	segments := parseSegments(t, []string{testMSH, testPID, "OBR|1", "OBX|1|NM"})
+	if err := hl7x.UnmarshalMessage(segments, &msg); err != nil {
+		t.Fatal(err)
+	}
+
+	msg.Pid.PatientName = hl7v2_3.Xpn{}
+	report, err := p.ValidateMessage(&msg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := []string{"PID.5 missing"}
+	if f := failures(report); !reflect.DeepEqual(f, expected) {
+		t.Fatalf("expected %q, got %q\n%s", expected, f, report)
+	}
+}
@@ -0,0 +1,444 @@
+package profile
+
+import (
+	"bytes"
+	"fmt"
+	"text/tabwriter"
+
+	"github.com/kdar/health/hl7"
+	"github.com/kdar/health/hl7x"
+)
+
+// Status is the outcome of checking an element against the profile.
+type Status int
+
+const (
+	OK Status = iota
+	// a required element isn't there
+	Missing
+	// an element the profile doesn't support (usage X) is there
+	NotAllowed
+	// repeats fewer times than the profile's Min
+	TooFew
+	// repeats more times than the profile's Max
+	TooMany
+	// longer than the profile's Length
+	TooLong
+	// a segment the profile doesn't have
+	Unexpected
+)
+
+func (s Status) String() string {
+	switch s {
+	case OK:
+		return "OK"
+	case Missing:
+		return "missing"
+	case NotAllowed:
+		return "not allowed"
+	case TooFew:
+		return "too few"
+	case TooMany:
+		return "too many"
+	case TooLong:
+		return "too long"
+	case Unexpected:
+		return "unexpected"
+	}
+
+	return fmt.Sprintf("Status(%d)", int(s))
+}
+
+// Result is the outcome of checking one element of the message.
+type Result struct {
+	// Position of the element, e.g. PID, PID.3 or OBX[2].5[2].1.
+	// Segments and repetitions are numbered from 1, and only when
+	// there's more than one of them.
+	Position string
+	Name     string
+	Usage    Usage
+	Status   Status
+	// Detail explains a failure, e.g. "12 > 10"
+	Detail string
+}
+
+func (r Result) String() string {
+	s := fmt.Sprintf("%s (%s) %s", r.Position, r.Name, r.Status)
+	if r.Detail != "" {
+		s += ": " + r.Detail
+	}
+	return s
+}
+
+// Report holds a result for every segment and field checked, in message
+// order. Failures in a field's repetitions or components follow it.
+type Report struct {
+	Profile *Profile
+	Results []Result
+}
+
+// Valid reports whether the message conforms to the profile.
+func (r *Report) Valid() bool {
+	return len(r.Failures()) == 0
+}
+
+// Failures returns the results that aren't OK.
+func (r *Report) Failures() []Result {
+	var failures []Result
+	for _, result := range r.Results {
+		if result.Status != OK {
+			failures = append(failures, result)
+		}
+	}
+	return failures
+}
+
+// String formats the report as a table.
+func (r *Report) String() string {
+	buf := &bytes.Buffer{}
+	w := tabwriter.NewWriter(buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "POSITION\tNAME\tUSAGE\tSTATUS\tDETAIL")
+	for _, result := range r.Results {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", result.Position, result.Name, result.Usage, result.Status, result.Detail)
+	}
+	w.Flush()
+	return buf.String()
+}
+
+// Validate checks the segments of a message against the profile.
+//
+// Segments are matched to the profile in order. Segments that aren't
+// in the profile at all (e.g. Z-segments) are reported as Unexpected
+// and skipped. A segment that's in the profile but out of order ends
+// the matching, and it and everything after it is Unexpected.
+func (p *Profile) Validate(segments []hl7.Segment) *Report {
+	v := &validator{
+		report:   &Report{Profile: p},
+		segments: segments,
+		known:    make(map[string]bool),
+		counts:   make(map[string]int),
+		seen:     make(map[string]int),
+	}
+
+	p.StaticDef.walk(func(e Element) {
+		v.known[e.Name] = true
+	})
+	for _, segment := range segments {
+		v.counts[segmentName(segment)]++
+	}
+
+	v.elements(p.StaticDef.Elements)
+
+	for ; v.pos < len(v.segments); v.pos++ {
+		v.unexpected()
+	}
+
+	return v.report
+}
+
+// ValidateMessage checks an hl7x message struct (see hl7x.UnmarshalMessage)
+// against the profile.
+func (p *Profile) ValidateMessage(msg interface{}) (*Report, error) {
+	segments, err := hl7x.MarshalMessage(msg)
+	if err != nil {
+		return nil, err
+	}
+	return p.Validate(segments), nil
+}
+
+// walk calls fn for every segment in the definition.
+func (s StaticDef) walk(fn func(Element)) {
+	var walk func([]Element)
+	walk = func(elems []Element) {
+		for _, e := range elems {
+			if e.IsGroup() {
+				walk(e.Elements)
+			} else {
+				fn(e)
+			}
+		}
+	}
+	walk(s.Elements)
+}
+
+type validator struct {
+	report   *Report
+	segments []hl7.Segment
+	pos      int
+
+	// the segment names in the profile
+	known map[string]bool
+	// how many of each segment the message has,
+	// and how many have been checked so far
+	counts map[string]int
+	seen   map[string]int
+
+	// the leading segments of the groups being matched
+	groups []map[string]bool
+}
+
+func (v *validator) add(r Result) {
+	v.report.Results = append(v.report.Results, r)
+}
+
+// current returns the name of the segment being matched, skipping
+// over any the profile doesn't know about.
+func (v *validator) current() string {
+	for v.pos < len(v.segments) {
+		name := segmentName(v.segments[v.pos])
+		if v.known[name] {
+			return name
+		}
+		v.unexpected()
+		v.pos++
+	}
+	return ""
+}
+
+func (v *validator) unexpected() {
+	name := segmentName(v.segments[v.pos])
+	v.seen[name]++
+	v.add(Result{Position: v.segmentPosition(name), Name: name, Status: Unexpected})
+}
+
+func (v *validator) elements(elems []Element) {
+	for _, e := range elems {
+		if e.IsGroup() {
+			v.group(e)
+		} else {
+			v.segment(e)
+		}
+	}
+}
+
+func (v *validator) group(e Element) {
+	leading := leadingSegments(e.Elements)
+	v.groups = append(v.groups, leading)
+
+	count := 0
+	for leading[v.current()] {
+		start := v.pos
+		v.elements(e.Elements)
+		count++
+
+		if v.pos == start {
+			break
+		}
+	}
+
+	v.groups = v.groups[:len(v.groups)-1]
+
+	v.cardinality(e.Name, e.Name, e.Usage, e.Min, e.Max, count)
+}
+
+func (v *validator) segment(e Element) {
+	name := e.LongName
+	if name == "" {
+		name = e.Name
+	}
+
+	count := 0
+	for v.current() == e.Name {
+		// once there are as many as allowed, another one
+		// may be the start of the next group
+		if e.Max > 0 && count >= int(e.Max) && v.startsGroup(e.Name) {
+			break
+		}
+
+		v.seen[e.Name]++
+		pos := v.segmentPosition(e.Name)
+
+		if e.Usage != NotSupported {
+			v.add(Result{Position: pos, Name: name, Usage: e.Usage, Status: OK})
+		}
+		for i, def := range e.Fields {
+			data, _ := v.segments[v.pos].Index(i + 1)
+			v.field(fmt.Sprintf("%s.%d", pos, i+1), def, data)
+		}
+
+		count++
+		v.pos++
+	}
+
+	v.cardinality(e.Name, name, e.Usage, e.Min, e.Max, count)
+}
+
+// startsGroup reports whether a segment named name can start
+// another repetition of one of the groups being matched.
+func (v *validator) startsGroup(name string) bool {
+	for _, leading := range v.groups {
+		if leading[name] {
+			return true
+		}
+	}
+	return false
+}
+
+func (v *validator) segmentPosition(name string) string {
+	if v.counts[name] > 1 {
+		return fmt.Sprintf("%s[%d]", name, v.seen[name])
+	}
+	return name
+}
+
+// cardinality checks that the element was sent the right number of times,
+// and reports whether it was.
+func (v *validator) cardinality(pos, name string, usage Usage, min int, max Max, count int) bool {
+	r := Result{Position: pos, Name: name, Usage: usage}
+
+	switch {
+	case count == 0 && usage == Required:
+		r.Status = Missing
+	case count > 0 && usage == NotSupported:
+		r.Status = NotAllowed
+	case count > 0 && count < min:
+		r.Status = TooFew
+		r.Detail = fmt.Sprintf("%d < %d", count, min)
+	case count > 0 && max > 0 && count > int(max):
+		r.Status = TooMany
+		r.Detail = fmt.Sprintf("%d > %s", count, max)
+	default:
+		return true
+	}
+
+	v.add(r)
+	return false
+}
+
+func (v *validator) field(pos string, def Field, data hl7.Data) {
+	var reps []hl7.Data
+	if r, ok := data.(hl7.Repeated); ok {
+		reps = r
+	} else if data != nil && !isEmpty(data) {
+		reps = []hl7.Data{data}
+	}
+
+	start := len(v.report.Results)
+	if !v.cardinality(pos, def.Name, def.Usage, def.Min, def.Max, len(reps)) {
+		return
+	}
+
+	for i, rep := range reps {
+		p := pos
+		if len(reps) > 1 {
+			p = fmt.Sprintf("%s[%d]", pos, i+1)
+		}
+		v.length(p, def.Name, def.Usage, def.Length, rep)
+		v.components(p, def.Components, rep)
+	}
+
+	for _, r := range v.report.Results[start:] {
+		if r.Position == pos {
+			return
+		}
+	}
+
+	// the field's own result goes before any for its parts
+	v.report.Results = append(v.report.Results, Result{})
+	copy(v.report.Results[start+1:], v.report.Results[start:])
+	v.report.Results[start] = Result{Position: pos, Name: def.Name, Usage: def.Usage, Status: OK}
+}
+
+// components checks the components (or subcomponents) defs against data.
+func (v *validator) components(pos string, defs []Component, data hl7.Data) {
+	if len(defs) == 0 || isEmpty(data) {
+		return
+	}
+
+	for i, def := range defs {
+		var part hl7.Data
+		switch data.(type) {
+		case hl7.Component, hl7.SubComponent:
+			part, _ = data.Index(i)
+		default:
+			// a single value is the first component
+			if i == 0 {
+				part = data
+			}
+		}
+
+		p := fmt.Sprintf("%s.%d", pos, i+1)
+
+		count := 0
+		if part != nil && !isEmpty(part) {
+			count = 1
+		}
+		if !v.cardinality(p, def.Name, def.Usage, 0, 0, count) || count == 0 {
+			continue
+		}
+
+		v.length(p, def.Name, def.Usage, def.Length, part)
+		v.components(p, def.SubComponents, part)
+	}
+}
+
+func (v *validator) length(pos, name string, usage Usage, max int, data hl7.Data) {
+	if max <= 0 {
+		return
+	}
+
+	if n := encodedLength(data); n > max {
+		v.add(Result{Position: pos, Name: name, Usage: usage, Status: TooLong, Detail: fmt.Sprintf("%d > %d", n, max)})
+	}
+}
+
+// leadingSegments returns the names of the segments a group can start
+// with: everything up to and including its first required segment.
+func leadingSegments(elems []Element) map[string]bool {
+	leading := make(map[string]bool)
+	for _, e := range elems {
+		if e.IsGroup() {
+			for name := range leadingSegments(e.Elements) {
+				leading[name] = true
+			}
+		} else {
+			leading[e.Name] = true
+		}
+
+		if e.Usage == Required {
+			break
+		}
+	}
+	return leading
+}
+
+func segmentName(segment hl7.Segment) string {
+	if len(segment) == 0 {
+		return ""
+	}
+
+	f, _ := segment[0].(hl7.Field)
+	return string(f)
+}
+
+// encodedLength is the length of data with its separators.
+func encodedLength(data hl7.Data) int {
+	if f, ok := data.(hl7.Field); ok {
+		return len(f)
+	}
+
+	n := 0
+	for i := 0; i < data.Len(); i++ {
+		if i > 0 {
+			n++
+		}
+		if sub, ok := data.Index(i); ok {
+			n += encodedLength(sub)
+		}
+	}
+	return n
+}
+
+// isEmpty reports whether data holds nothing but empty fields.
+func isEmpty(data hl7.Data) bool {
+	if f, ok := data.(hl7.Field); ok {
+		return len(f) == 0
+	}
+
+	for i := 0; i < data.Len(); i++ {
+		if sub, ok := data.Index(i); ok && !isEmpty(sub) {
+			return false
+		}
+	}
+	return true
+}
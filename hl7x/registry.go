@@ -0,0 +1,78 @@
+package hl7x
+
+import (
+	"reflect"
+	"sync"
+)
+
+var (
+	registryMu sync.RWMutex
+	// segment name -> struct type
+	segmentTypes = make(map[string]reflect.Type)
+	// data type name -> type
+	dataTypes = make(map[string]reflect.Type)
+)
+
+// RegisterSegment registers the type of v (a segment struct or a pointer
+// to one) for the segment name, e.g. a site's ZPD segment. UnmarshalMessage
+// uses it to decode segments into interface{} fields of a message struct.
+// Registering a name again replaces the type.
+func RegisterSegment(name string, v interface{}) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	segmentTypes[name] = registryType("RegisterSegment", name, v)
+}
+
+// RegisterDataType registers the type of v (or what it points to) for the
+// data type name, e.g. a local extension such as SNM. It's used to decode
+// fields whose type is only known at run time, like OBX-5. Registering a
+// name again replaces the type, so a local definition can override the
+// one a version package uses.
+func RegisterDataType(name string, v interface{}) {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	dataTypes[name] = registryType("RegisterDataType", name, v)
+}
+
+// NewSegment returns a pointer to a new value of the type
+// registered for the segment name.
+func NewSegment(name string) (interface{}, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	typ, ok := segmentTypes[name]
+	if !ok {
+		return nil, false
+	}
+	return reflect.New(typ).Interface(), true
+}
+
+// NewDataType returns a pointer to a new value of the type
+// registered for the data type name.
+func NewDataType(name string) (interface{}, bool) {
+	registryMu.RLock()
+	defer registryMu.RUnlock()
+
+	typ, ok := dataTypes[name]
+	if !ok {
+		return nil, false
+	}
+	return reflect.New(typ).Interface(), true
+}
+
+func registryType(fn, name string, v interface{}) reflect.Type {
+	if name == "" {
+		panic("hl7x: " + fn + " with an empty name")
+	}
+	if v == nil {
+		panic("hl7x: " + fn + " of " + name + " with a nil value")
+	}
+
+	typ := reflect.TypeOf(v)
+	if typ.Kind() == reflect.Ptr {
+		typ = typ.Elem()
+	}
+	return typ
+}
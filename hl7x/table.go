@@ -0,0 +1,152 @@
+package hl7x
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Table is an HL7 table (code set) that maps each
+// allowed code to its description.
+type Table map[string]string
+
+var (
+	tablesMu sync.RWMutex
+	// version -> table number -> table
+	tables = make(map[string]map[string]Table)
+)
+
+// RegisterTable makes a table available for validating values of the
+// given HL7 version. number is the table number without the HL7 prefix,
+// e.g. "0001". The HL7-defined tables are registered by the version
+// packages (e.g. hl7x/2.3). Registering a table that already exists
+// replaces it, so local user-defined tables can override those.
+func RegisterTable(version, number string, table Table) {
+	tablesMu.Lock()
+	defer tablesMu.Unlock()
+
+	if tables[version] == nil {
+		tables[version] = make(map[string]Table)
+	}
+	tables[version][number] = table
+}
+
+// LookupTable returns the table registered for the version and number.
+func LookupTable(version, number string) (Table, bool) {
+	tablesMu.RLock()
+	defer tablesMu.RUnlock()
+
+	t, ok := tables[version][number]
+	return t, ok
+}
+
+// CodeError describes a coded value that isn't in its table.
+type CodeError struct {
+	// Position of the value, e.g. PID.8 or PID.11[2].7
+	Position string
+	// Table number, e.g. 0001
+	Table string
+	Value string
+}
+
+func (e *CodeError) Error() string {
+	return fmt.Sprintf("%s: %q is not in table %s", e.Position, e.Value, e.Table)
+}
+
+// ValidateTables checks every field of the segment struct src that has a
+// table tag (e.g. `table:"0001"`) against the tables registered for the
+// version. Fields whose table isn't registered are not checked. Empty
+// values and the HL7 null ("") are allowed.
+func ValidateTables(version string, src interface{}) []*CodeError {
+	v := reflect.ValueOf(src)
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+
+	tv := &tableValidator{version: version}
+	tv.validateStruct(segmentName(v.Type()), v)
+	return tv.errs
+}
+
+type tableValidator struct {
+	version string
+	errs    []*CodeError
+}
+
+func (tv *tableValidator) validateStruct(prefix string, v reflect.Value) {
+	typ := v.Type()
+	for i := 0; i < typ.NumField(); i++ {
+		field := typ.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+
+		pos := positionIndex(field.Tag.Get("position"))
+		if pos == "" {
+			pos = fmt.Sprint(i + 1)
+		}
+
+		tv.validate(prefix+"."+pos, field.Tag.Get("table"), v.Field(i))
+	}
+}
+
+func (tv *tableValidator) validate(pos, table string, v reflect.Value) {
+	// primitives aren't coded values
+	if _, ok := textMarshaler(v); ok {
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		tv.check(pos, table, v.String())
+	case reflect.Struct:
+		tv.validateStruct(pos, v)
+	case reflect.Slice:
+		if v.Type().Elem().Kind() == reflect.Uint8 {
+			return
+		}
+		for i := 0; i < v.Len(); i++ {
+			p := pos
+			if v.Len() > 1 {
+				p = fmt.Sprintf("%s[%d]", pos, i+1)
+			}
+			tv.validate(p, table, v.Index(i))
+		}
+	case reflect.Ptr, reflect.Interface:
+		if !v.IsNil() {
+			tv.validate(pos, table, v.Elem())
+		}
+	}
+}
+
+func (tv *tableValidator) check(pos, table, value string) {
+	if table == "" || value == "" || value == `""` {
+		return
+	}
+
+	t, ok := LookupTable(tv.version, table)
+	if !ok {
+		return
+	}
+
+	if _, ok := t[value]; !ok {
+		tv.errs = append(tv.errs, &CodeError{Position: pos, Table: table, Value: value})
+	}
+}
+
+// positionIndex gets the index from a position tag,
+// e.g. PID.8 -> 8
+func positionIndex(pos string) string {
+	if i := strings.LastIndex(pos, "."); i >= 0 {
+		return pos[i+1:]
+	}
+	return ""
+}
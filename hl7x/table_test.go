@@ -0,0 +1,75 @@
+package hl7x_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/kdar/health/hl7x"
+)
+
+type tableXtn struct {
+	Number string `position:"XTN.1"`
+	Use    string `position:"XTN.2" table:"0201"`
+}
+
+type tablePid struct {
+	SetID  hl7x.SequenceID `position:"PID.1"`
+	Sex    string          `position:"PID.8" table:"0001"`
+	Race   string          `position:"PID.10" table:"0005"`
+	Phones []tableXtn      `position:"PID.13"`
+}
+
+func init() {
+	hl7x.RegisterTable("test", "0001", hl7x.Table{"F": "Female", "M": "Male"})
+	hl7x.RegisterTable("test", "0201", hl7x.Table{"PRN": "Primary Residence Number"})
+}
+
+var validateTablesTests = []struct {
+	in   tablePid
+	errs []*hl7x.CodeError
+}{
+	{
+		tablePid{Sex: "F", Phones: []tableXtn{{Use: "PRN"}}},
+		nil,
+	},
+	{
+		// empty and null values are allowed
+		tablePid{Sex: `""`, Phones: []tableXtn{{Number: "555-1234"}}},
+		nil,
+	},
+	{
+		// 0005 isn't registered so it isn't checked
+		tablePid{Sex: "X", Race: "anything"},
+		[]*hl7x.CodeError{{Position: "PID.8", Table: "0001", Value: "X"}},
+	},
+	{
+		tablePid{Phones: []tableXtn{{Use: "PRN"}, {Use: "CELL"}}},
+		[]*hl7x.CodeError{{Position: "PID.13[2].2", Table: "0201", Value: "CELL"}},
+	},
+	{
+		tablePid{Phones: []tableXtn{{Use: "WPN"}}},
+		[]*hl7x.CodeError{{Position: "PID.13.2", Table: "0201", Value: "WPN"}},
+	},
+}
+
+func TestValidateTables(t *testing.T) {
+	for i, tt := range validateTablesTests {
+		errs := hl7x.ValidateTables("test", &tt.in)
+		if !reflect.DeepEqual(errs, tt.errs) {
+			t.Fatalf("%d. expected %v, got %v", i, tt.errs, errs)
+		}
+	}
+}
+
+func TestRegisterTableOverrides(t *testing.T) {
+	hl7x.RegisterTable("test-override", "0001", hl7x.Table{"F": "Female"})
+	errs := hl7x.ValidateTables("test-override", tablePid{Sex: "U"})
+	if len(errs) != 1 {
+		t.Fatalf("expected 1 error, got %v", errs)
+	}
+
+	hl7x.RegisterTable("test-override", "0001", hl7x.Table{"F": "Female", "U": "Unknown"})
+	if errs := hl7x.ValidateTables("test-override", tablePid{Sex: "U"}); errs != nil {
+		t.Fatalf("expected no errors, got %v", errs)
+	}
+}
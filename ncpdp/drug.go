@@ -111,7 +111,7 @@ func (d *Drug) fill(values edifact.Values) {
 	d.Dosage1 = getString(subValues, 1)
 	d.Dosage2 = getString(subValues, 2)
 
-	subValues = getValues(values, 4)
+	subValues = getRepetitions(values, 4)
 	for n := range subValues {
 		subVals := getValues(subValues, n)
 		if len(subVals) == 3 {
@@ -145,6 +145,30 @@ func (d *Drug) fill(values edifact.Values) {
 	d.Substitution = getString(values, 5)
 }
 
+// returns the DRU segment of the drug
+func (d *Drug) values() edifact.Values {
+	dates := edifact.Values{}
+	if d.DateIssued != nil {
+		dates = append(dates, edifact.Values{"85", d.DateIssued.Format("20060102"), "102"})
+	}
+	if d.LastDemand != nil {
+		dates = append(dates, edifact.Values{"LD", d.LastDemand.Format("20060102"), "102"})
+	}
+	if d.DaysSupply != nil {
+		days := strconv.FormatInt(int64(*d.DaysSupply/(time.Hour*24)), 10)
+		dates = append(dates, edifact.Values{"ZDS", days, "804"})
+	}
+
+	return trimValues(edifact.Values{
+		"DRU",
+		edifact.Values{d.ItemDescriptionIdentification, d.ItemDescription, d.ItemNumber, d.CodeListResponsibilityAgency},
+		edifact.Values{d.QuantityQualifier, d.Quantity, d.CodeListQualifier},
+		edifact.Values{d.DosageId, d.Dosage1, d.Dosage2},
+		dates,
+		d.Substitution,
+	})
+}
+
 // return the name of the drug
 func (d *Drug) Name() string {
 	return d.ItemDescription
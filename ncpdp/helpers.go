@@ -1,6 +1,7 @@
 package ncpdp
 
 import (
+	"crypto/rand"
 	"github.com/kdar/health/edifact"
 )
 
@@ -16,14 +17,33 @@ func getString(values edifact.Values, index int) string {
 
 func getValues(values edifact.Values, index int) edifact.Values {
 	if len(values) > index {
-		if vals, ok := values[index].(edifact.Values); ok {
+		switch vals := values[index].(type) {
+		case edifact.Values:
 			return vals
+		case string:
+			// a composite with just its first component
+			if len(vals) > 0 {
+				return edifact.Values{vals}
+			}
 		}
 	}
 
 	return edifact.Values{}
 }
 
+// returns the repetitions of a data element, each of them a composite.
+// A data element that isn't repeated is its only repetition.
+func getRepetitions(values edifact.Values, index int) edifact.Values {
+	subValues := getValues(values, index)
+	if len(subValues) > 0 {
+		if _, ok := subValues[0].(edifact.Values); !ok {
+			return edifact.Values{subValues}
+		}
+	}
+
+	return subValues
+}
+
 func getName(values edifact.Values, index int) *Name {
 	subValues := getValues(values, index)
 	n := &Name{}
@@ -55,7 +75,7 @@ func getAddress(values edifact.Values, index int) *Address {
 
 func getPhones(values edifact.Values, index int) []*Phone {
 	var phones []*Phone
-	subValues := getValues(values, index)
+	subValues := getRepetitions(values, index)
 	for n := range subValues {
 		subVals := getValues(subValues, n)
 		phones = append(phones, &Phone{getString(subVals, 0), getString(subVals, 1)})
@@ -63,3 +83,63 @@ func getPhones(values edifact.Values, index int) []*Phone {
 
 	return phones
 }
+
+func getParty(values edifact.Values, index int) Party {
+	subValues := getValues(values, index)
+	return Party{getString(subValues, 0), getString(subValues, 1), getString(subValues, 2)}
+}
+
+// returns values without the empty data elements or components at the
+// end, which are left out when they're encoded.
+func trimValues(values edifact.Values) edifact.Values {
+	out := make(edifact.Values, len(values))
+	for i, v := range values {
+		if vals, ok := v.(edifact.Values); ok {
+			v = trimValues(vals)
+		}
+		out[i] = v
+	}
+
+	for len(out) > 0 {
+		switch v := out[len(out)-1].(type) {
+		case string:
+			if v != "" {
+				return out
+			}
+		case edifact.Values:
+			if len(v) > 0 {
+				return out
+			}
+		default:
+			return out
+		}
+		out = out[:len(out)-1]
+	}
+	return out
+}
+
+// the characters of UNOA, the syntax level Marshal declares, that
+// are allowed in a reference.
+const controlReferenceChars = "ABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// returns a new random control reference, 13 characters long like
+// the ones in the RXHRES examples.
+func newControlReference() (string, error) {
+	// random bytes past the last whole multiple of the characters
+	// would favor the first ones, so they're thrown away.
+	max := 256 - 256%len(controlReferenceChars)
+
+	ref := make([]byte, 0, 13)
+	b := make([]byte, 16)
+	for len(ref) < cap(ref) {
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+		for _, c := range b {
+			if int(c) < max && len(ref) < cap(ref) {
+				ref = append(ref, controlReferenceChars[int(c)%len(controlReferenceChars)])
+			}
+		}
+	}
+	return string(ref), nil
+}
@@ -2,22 +2,34 @@ package ncpdp
 
 import (
 	"errors"
+	"fmt"
 	//"log"
 	"github.com/kdar/health/edifact"
-	//"time"
+	"time"
 )
 
+// The delimiters SCRIPT 8.1 uses, which Marshal writes in the UNA
+// segment.
+var DefaultHeader = edifact.Header{"UNA", ":+./*'"}
+
 // base struct of all messages.
 // required by EDIFACT and NCPDP script
 type RXH struct {
-	// UIB
-	// SyntaxIdentifier            string
-	// SyntaxVersionNumber         string
-	// TransactionControlReference string
-	// SenderIds                   []string
-	// ReceiverIds                 []string
-
-	//una
+	// ----- Interchange header (UIB)
+
+	// Transaction control reference, which is also the message
+	// reference of the UIH and UIT. Marshal generates one if it's empty.
+	ControlReference string // 030 M
+
+	Sender    Party // 060-* M
+	Recipient Party // 070-* M
+
+	// Date and time of the interchange. Marshal uses the current time
+	// if it's zero.
+	Date time.Time // 080-* M
+
+	// the segments as they were unmarshaled. Marshal builds its own
+	// from the fields above.
 	UIB edifact.Values
 	UIH edifact.Values
 
@@ -32,6 +44,89 @@ func newRXH() *RXH {
 	return &RXH{}
 }
 
+// fills in the interchange header fields from the UIB segment
+func (r *RXH) fill(values edifact.Values) {
+	r.ControlReference = getString(values, 3)
+	if r.ControlReference == "" {
+		// sometimes sent as a composite
+		r.ControlReference = getString(getValues(values, 3), 0)
+	}
+
+	r.Sender = getParty(values, 6)
+	r.Recipient = getParty(values, 7)
+
+	// the time is HHMMSS with optional decimal seconds
+	subValues := getValues(values, 8)
+	date, clock := getString(subValues, 0), getString(subValues, 1)
+	if len(clock) >= 6 {
+		date += clock[:6]
+	} else {
+		date += "000000"
+	}
+	if t, err := time.Parse("20060102150405", date); err == nil {
+		r.Date = t
+	}
+}
+
+// fills in the envelope segment, if values is one. Returns false for
+// any other segment.
+func (r *RXH) fillEnvelope(name string, values edifact.Values) bool {
+	switch name {
+	case "UIB":
+		r.UIB = values
+		r.fill(values)
+	case "UIH":
+		r.UIH = values
+	case "UIT":
+		r.UIT = values
+	case "UIZ":
+		r.UIZ = values
+	default:
+		return false
+	}
+
+	return true
+}
+
+// fills in the ControlReference and Date of a message being sent, if
+// they're empty.
+func (r *RXH) prepare() error {
+	if r.ControlReference == "" {
+		ref, err := newControlReference()
+		if err != nil {
+			return err
+		}
+		r.ControlReference = ref
+	}
+	if r.Date.IsZero() {
+		r.Date = time.Now().UTC()
+	}
+
+	return nil
+}
+
+// returns the UIB and UIH segments for a message of type msgType
+func (r *RXH) header(msgType string) (uib, uih edifact.Values) {
+	uib = trimValues(edifact.Values{
+		"UIB",
+		edifact.Values{"UNOA", "0"},
+		"",
+		r.ControlReference,
+		"",
+		"",
+		r.Sender.values(),
+		r.Recipient.values(),
+		edifact.Values{r.Date.Format("20060102"), r.Date.Format("150405")},
+	})
+	uih = edifact.Values{
+		"UIH",
+		edifact.Values{"SCRIPT", "008", "001", msgType},
+		r.ControlReference,
+	}
+
+	return uib, uih
+}
+
 // func (r RXH) copy(rxh *RXH) {
 //   r.UIB = rxh.UIB
 //   r.UIH = rxh.UIH
@@ -57,6 +152,52 @@ func newRXHREX() *RXHREX {
 	}
 }
 
+// Medication history request, asking for a patient's medication
+// history. Answered with RXHRES.
+type RXHREQ struct {
+	*RXHREX
+
+	// Segment: PVD. The provider code is PC (prescriber).
+	RequestingPhysician *Provider
+}
+
+// creates a new RXHREQ
+func newRXHREQ() *RXHREQ {
+	return &RXHREQ{
+		RXHREX: newRXHREX(),
+	}
+}
+
+// Creates a new RXHREQ for the patient's medication history, requested
+// by the physician.
+func NewRXHREQ(physician *Provider, patient *Patient) *RXHREQ {
+	r := newRXHREQ()
+	r.RequestingPhysician = physician
+	r.Patient = patient
+	return r
+}
+
+// returns the segments between the UIH and UIT
+func (r *RXHREQ) segments() ([]edifact.Values, error) {
+	if r.RequestingPhysician == nil {
+		return nil, errors.New("ncpdp: RXHREQ has no requesting physician")
+	}
+	if r.Patient == nil {
+		return nil, errors.New("ncpdp: RXHREQ has no patient")
+	}
+
+	physician := *r.RequestingPhysician
+	if physician.ProviderCode == "" {
+		physician.ProviderCode = "PC"
+	}
+
+	segments := []edifact.Values{physician.values(), r.Patient.values()}
+	if r.COO != nil {
+		segments = append(segments, r.COO)
+	}
+	return segments, nil
+}
+
 // Segment: RES
 type Response struct {
 	// A = Approved
@@ -95,6 +236,11 @@ func (r *Response) fill(values edifact.Values) {
 	r.Text = getString(values, 4)
 }
 
+// returns the RES segment of the response
+func (r *Response) values() edifact.Values {
+	return trimValues(edifact.Values{"RES", r.ResponseType, r.CodeListQualifier, r.ReferenceNumber, r.Text})
+}
+
 // Response describing a patient’s medication history. Response to RXHREQ.
 type RXHRES struct {
 	*RXHREX
@@ -112,6 +258,44 @@ func newRXHRES() *RXHRES {
 	}
 }
 
+func (r *RXHRES) messageType() string { return "RXHRES" }
+
+// returns the segments between the UIH and UIT. The pharmacy and
+// prescriber of each drug go after it.
+func (r *RXHRES) segments() ([]edifact.Values, error) {
+	if r.Response == nil {
+		return nil, errors.New("ncpdp: RXHRES has no response")
+	}
+
+	segments := []edifact.Values{r.Response.values()}
+	if r.RequestingPhysician != nil {
+		physician := *r.RequestingPhysician
+		physician.ProviderCode = "PC"
+		segments = append(segments, physician.values())
+	}
+	if r.Patient != nil {
+		segments = append(segments, r.Patient.values())
+	}
+	if r.COO != nil {
+		segments = append(segments, r.COO)
+	}
+
+	for _, drug := range r.Drugs {
+		segments = append(segments, drug.values())
+		if drug.Pharmacy != nil {
+			pharmacy := *drug.Pharmacy
+			pharmacy.ProviderCode = "P2"
+			segments = append(segments, pharmacy.values())
+		}
+		if drug.Prescriber != nil {
+			prescriber := *drug.Prescriber
+			prescriber.ProviderCode = "PC"
+			segments = append(segments, prescriber.values())
+		}
+	}
+	return segments, nil
+}
+
 // Segment: STS
 type Status struct {
 	StatusTypeCode    string // 010 M
@@ -131,6 +315,19 @@ func (s *Status) fill(values edifact.Values) {
 	s.Text = getString(values, 3)
 }
 
+// returns the STS segment of the status
+func (s *Status) values() edifact.Values {
+	return trimValues(edifact.Values{"STS", s.StatusTypeCode, s.CodeListQualifier, s.Text})
+}
+
+// returns the STS segment of a message of type msgType
+func statusSegments(status *Status, msgType string) ([]edifact.Values, error) {
+	if status == nil {
+		return nil, fmt.Errorf("ncpdp: %s has no status", msgType)
+	}
+	return []edifact.Values{status.values()}, nil
+}
+
 // Message type: ERROR
 type ERROR struct {
 	*RXH
@@ -142,26 +339,93 @@ func newError() *ERROR {
 	return &ERROR{RXH: newRXH(), Status: newStatus()}
 }
 
+// creates a new ERROR
+func NewERROR() *ERROR {
+	return newError()
+}
+
+func (e *ERROR) messageType() string { return "ERROR" }
+
+func (e *ERROR) segments() ([]edifact.Values, error) {
+	return statusSegments(e.Status, "ERROR")
+}
+
+// Message type: STATUS. Tells the sender a message was delivered or
+// is pending, depending on the status code.
+type STATUS struct {
+	*RXH
+	Status *Status
+}
+
+// creates a new STATUS
+func NewSTATUS() *STATUS {
+	return &STATUS{RXH: newRXH(), Status: newStatus()}
+}
+
+func (s *STATUS) messageType() string { return "STATUS" }
+
+func (s *STATUS) segments() ([]edifact.Values, error) {
+	return statusSegments(s.Status, "STATUS")
+}
+
+// Message type: VERIFY. Tells the sender a message was received by the
+// recipient, when the sender asked for it.
+type VERIFY struct {
+	*RXH
+	Status *Status
+}
+
+// creates a new VERIFY
+func NewVERIFY() *VERIFY {
+	return &VERIFY{RXH: newRXH(), Status: newStatus()}
+}
+
+func (v *VERIFY) messageType() string { return "VERIFY" }
+
+func (v *VERIFY) segments() ([]edifact.Values, error) {
+	return statusSegments(v.Status, "VERIFY")
+}
+
 // unmarshals the values based on the function.
 // e.g. if function is RXHRES, values is unmarshaled
 // as such.
 func unmarshal(values edifact.Values, msgType string) (interface{}, error) {
 	// base types
 	var rxh *RXH
+	var rxhrex *RXHREX
+	var requestingPhysician **Provider
+	var status *Status
 	// return types
-	var error_ *ERROR
 	var rxhres *RXHRES
 	var ret interface{}
 
 	switch msgType {
+	case "RXHREQ":
+		rxhreq := newRXHREQ()
+		rxh = rxhreq.RXH
+		rxhrex = rxhreq.RXHREX
+		requestingPhysician = &rxhreq.RequestingPhysician
+		ret = rxhreq
 	case "RXHRES":
 		rxhres = newRXHRES()
 		rxh = rxhres.RXH
+		rxhrex = rxhres.RXHREX
+		requestingPhysician = &rxhres.RequestingPhysician
 		ret = rxhres
 	case "ERROR":
 		error_ := newError()
-		rxh = error_.RXH
+		rxh, status = error_.RXH, error_.Status
 		ret = error_
+	case "STATUS":
+		status_ := NewSTATUS()
+		rxh, status = status_.RXH, status_.Status
+		ret = status_
+	case "VERIFY":
+		verify := NewVERIFY()
+		rxh, status = verify.RXH, verify.Status
+		ret = verify
+	default:
+		return unmarshalRX(values, msgType)
 	}
 
 	seenDrug := false
@@ -169,20 +433,33 @@ func unmarshal(values edifact.Values, msgType string) (interface{}, error) {
 	for _, value := range values {
 		if vals, ok := value.(edifact.Values); ok {
 			if name, ok := vals[0].(string); ok {
+				if rxh.fillEnvelope(name, vals) {
+					continue
+				}
+				if status != nil {
+					// the status is all there is besides the envelope
+					if name == "STS" {
+						status.fill(vals)
+					}
+					continue
+				}
+
 				switch name {
-				case "UIB":
-					rxh.UIB = vals
-				case "UIH":
-					rxh.UIH = vals
 				case "RES":
-					rxhres.Response.fill(vals)
+					if rxhres != nil {
+						rxhres.Response.fill(vals)
+					}
 				case "PTT":
 					patient := newPatient()
 					patient.fill(vals)
-					rxhres.RXHREX.Patient = patient
+					rxhrex.Patient = patient
 				case "COO":
-					rxhres.RXHREX.COO = vals
+					rxhrex.COO = vals
 				case "DRU":
+					if rxhres == nil {
+						// only RXHRES has drugs
+						continue
+					}
 					seenDrug = true
 					drug := newDrug()
 					drug.fill(vals)
@@ -201,14 +478,8 @@ func unmarshal(values edifact.Values, msgType string) (interface{}, error) {
 							}
 						}
 					} else {
-						rxhres.RequestingPhysician = provider
+						*requestingPhysician = provider
 					}
-				case "STS":
-					error_.Status.fill(vals)
-				case "UIT":
-					rxh.UIT = vals
-				case "UIZ":
-					rxh.UIZ = vals
 				}
 			}
 		}
@@ -227,11 +498,10 @@ func UnmarshalValues(values edifact.Values) (interface{}, error) {
 				switch name {
 				case "UIH":
 					if subvals, ok := vals[1].(edifact.Values); ok && len(subvals) > 3 {
-						switch getString(subvals, 3) {
-						case "RXHRES":
-							return unmarshal(values, "RXHRES")
-						case "ERROR":
-							return unmarshal(values, "ERROR")
+						switch msgType := getString(subvals, 3); msgType {
+						case "RXHREQ", "RXHRES", "ERROR", "STATUS", "VERIFY",
+							"NEWRX", "REFREQ", "REFRES", "RXCHG", "CHGRES", "CANRX", "CANRES":
+							return unmarshal(values, msgType)
 						}
 					}
 				case "RES":
@@ -253,3 +523,47 @@ func Unmarshal(data []byte) (interface{}, error) {
 
 	return UnmarshalValues(values)
 }
+
+// the messages Marshal can write
+type message interface {
+	rxh() *RXH
+	messageType() string
+	segments() ([]edifact.Values, error)
+}
+
+func (r *RXH) rxh() *RXH { return r }
+
+func (r *RXHREQ) messageType() string { return "RXHREQ" }
+
+// marshals a message, such as an *RXHREQ, into a SCRIPT 8.1
+// interchange. The UIB and UIH are built from the RXH fields, and the
+// UIT and UIZ get the control reference and segment counts.
+//
+// Marshal modifies msg: if the ControlReference or Date are empty,
+// they're set on msg to a new reference and the current time, so a
+// response can be matched up with it. Marshal a copy to leave msg as
+// it is.
+func Marshal(msg interface{}) ([]byte, error) {
+	m, ok := msg.(message)
+	if !ok {
+		return nil, fmt.Errorf("ncpdp: cannot marshal %T", msg)
+	}
+
+	segments, err := m.segments()
+	if err != nil {
+		return nil, err
+	}
+
+	rxh := m.rxh()
+	if err := rxh.prepare(); err != nil {
+		return nil, err
+	}
+
+	uib, uih := rxh.header(m.messageType())
+	ic := &edifact.Interchange{
+		Header:   DefaultHeader,
+		Start:    uib,
+		Messages: []*edifact.Message{{Start: uih, Segments: segments}},
+	}
+	return ic.Marshal()
+}
@@ -25,6 +25,10 @@ var (
 	UM_OUT1 = &RXHRES{
 		RXHREX: &RXHREX{
 			RXH: &RXH{
+				ControlReference: "hJIAmKH0FGDSt",
+				Sender:           Party{"Sender1", "ZZZ", ""},
+				Recipient:        Party{"Recepient1", "ZZZ", "Recepient2"},
+				Date:             *timeParse("2013-01-13 12:16:25 +0000 UTC"),
 				UIB: edifact.Values{
 					"UIB",
 					edifact.Values{
@@ -150,7 +154,7 @@ var (
 			LastDemand:                    timeParse("2012-04-22 00:00:00 +0000 UTC"),
 			Substitution:                  "0",
 			Prescriber: &Provider{
-				ProviderCode:       "",
+				ProviderCode:       "PC",
 				ReferenceNumber:    "BW7412396",
 				ReferenceQualifier: "DH",
 				Name: &Name{
@@ -163,7 +167,7 @@ var (
 				PartyName: "",
 			},
 			Pharmacy: &Provider{
-				ProviderCode:       "",
+				ProviderCode:       "P2",
 				ReferenceNumber:    "1031232",
 				ReferenceQualifier: "D3",
 				Name:               nil,
@@ -224,3 +228,161 @@ func TestUnmarshal(t *testing.T) {
 func TestUnmarshalValues(t *testing.T) {
 
 }
+
+func newTestRXHREQ() *RXHREQ {
+	r := NewRXHREQ(
+		&Provider{
+			ProviderCode:       "PC",
+			ReferenceNumber:    "1234567890",
+			ReferenceQualifier: "HPI",
+			Name:               &Name{Last: "Betterton", First: "Jill"},
+		},
+		&Patient{
+			Relationship: "1",
+			Dob:          *timeParse("1985-06-30 00:00:00 +0000 UTC"),
+			Name:         &Name{Last: "Smith", First: "John"},
+			Gender:       "M",
+			Address:      &Address{Postal: "33165"},
+			Phones:       []*Phone{{"3053872415", "TE"}},
+		},
+	)
+	r.Sender = Party{"Sender1", "ZZZ", "pass"}
+	r.Recipient = Party{"Recepient1", "ZZZ", ""}
+	return r
+}
+
+const M_OUT1 = `UNA:+./*'UIB+UNOA:0++hJIAmKH0FGDSt+++Sender1:ZZZ:pass+Recepient1:ZZZ+20130113:121625'UIH+SCRIPT:008:001:RXHREQ+hJIAmKH0FGDSt'PVD+PC+1234567890:HPI+++Betterton:Jill'PTT+1+19850630+Smith:John+M++:::33165+3053872415:TE'UIT+hJIAmKH0FGDSt+4'UIZ++1'`
+
+func TestMarshal(t *testing.T) {
+	in := newTestRXHREQ()
+	in.ControlReference = "hJIAmKH0FGDSt"
+	in.Date = *timeParse("2013-01-13 12:16:25 +0000 UTC")
+
+	data, err := Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != M_OUT1 {
+		t.Fatalf("mismatch\nhave: %s\nwant: %s", data, M_OUT1)
+	}
+
+	// it reads back as the request it was made from
+	out, err := Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rxhreq, ok := out.(*RXHREQ)
+	if !ok {
+		t.Fatalf("expected an *RXHREQ, got %T", out)
+	}
+
+	for i, pair := range [][2]interface{}{
+		{rxhreq.ControlReference, in.ControlReference},
+		{rxhreq.Sender, in.Sender},
+		{rxhreq.Recipient, in.Recipient},
+		{rxhreq.Date, in.Date},
+		{rxhreq.Patient, in.Patient},
+		{rxhreq.RequestingPhysician, in.RequestingPhysician},
+	} {
+		have, want := spew.Sprintf("%#v", pair[0]), spew.Sprintf("%#v", pair[1])
+		if have != want {
+			t.Fatalf("%d. mismatch\nhave: %s\nwant: %s", i, have, want)
+		}
+	}
+
+	ic, err := edifact.NewInterchange(mustUnmarshal(t, data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if errs := ic.Validate(); len(errs) > 0 {
+		t.Fatalf("received control errors: %v", errs)
+	}
+}
+
+func TestMarshalControlReference(t *testing.T) {
+	in := newTestRXHREQ()
+	if _, err := Marshal(in); err != nil {
+		t.Fatal(err)
+	}
+	if len(in.ControlReference) != 13 {
+		t.Fatalf("expected a generated control reference, got %q", in.ControlReference)
+	}
+	if in.Date.IsZero() {
+		t.Fatal("expected the date to be filled in")
+	}
+
+	ref := in.ControlReference
+	in.ControlReference = ""
+	if _, err := Marshal(in); err != nil {
+		t.Fatal(err)
+	}
+	if in.ControlReference == ref {
+		t.Fatalf("expected a new control reference, got %q again", ref)
+	}
+}
+
+func TestMarshalCharset(t *testing.T) {
+	// UNOA, which the UIB declares, has no lower case letters
+	in := NewRXHREQ(
+		&Provider{ProviderCode: "PC", ReferenceNumber: "1234567890", ReferenceQualifier: "HPI", Name: &Name{Last: "BETTERTON", First: "JILL"}},
+		&Patient{Relationship: "1", Name: &Name{Last: "SMITH", First: "JOHN"}},
+	)
+	in.Sender = Party{"SENDER1", "ZZZ", ""}
+	in.Recipient = Party{"RECIPIENT1", "ZZZ", ""}
+
+	for i := 0; i < 20; i++ {
+		in.ControlReference = ""
+		data, err := Marshal(in)
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+		if _, err := edifact.UnmarshalOptions(data, edifact.DecodeOptions{CharsetMode: edifact.CharsetEnforce}); err != nil {
+			t.Fatalf("%d. received error: %s\n%s", i, err, data)
+		}
+	}
+}
+
+func TestMarshalErrors(t *testing.T) {
+	tests := []interface{}{
+		NewRXHREQ(nil, &Patient{}),
+		NewRXHREQ(&Provider{}, nil),
+		&RXHRES{},
+	}
+	for i, tt := range tests {
+		if _, err := Marshal(tt); err == nil {
+			t.Fatalf("%d. expected an error", i)
+		}
+	}
+}
+
+func mustUnmarshal(t *testing.T, data []byte) edifact.Values {
+	values, err := edifact.Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return values
+}
+
+func TestMarshalRXHRES(t *testing.T) {
+	data, err := Marshal(UM_OUT1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	rxhres := out.(*RXHRES)
+
+	for i, pair := range [][2]interface{}{
+		{rxhres.Response, UM_OUT1.Response},
+		{rxhres.Patient, UM_OUT1.Patient},
+		{rxhres.COO, UM_OUT1.COO},
+		{rxhres.Drugs, UM_OUT1.Drugs},
+	} {
+		have, want := spew.Sprintf("%#v", pair[0]), spew.Sprintf("%#v", pair[1])
+		if have != want {
+			t.Fatalf("%d. mismatch\nhave: %s\nwant: %s", i, have, want)
+		}
+	}
+}
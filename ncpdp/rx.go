@@ -0,0 +1,347 @@
+package ncpdp
+
+import (
+	"errors"
+	"fmt"
+	"github.com/kdar/health/edifact"
+)
+
+// base struct of the prescribing messages: NEWRX, REFREQ, REFRES,
+// RXCHG, CHGRES, CANRX and CANRES.
+type RX struct {
+	*RXH
+
+	// Segment: PVD, with the provider code PC
+	Prescriber *Provider
+	// Segment: PVD, with the provider code P2
+	Pharmacy *Provider
+	// Segment: PVD, with any other provider code, such as SU for the
+	// supervisor
+	Others []*Provider
+
+	// Segment: PTT
+	*Patient
+
+	// Segment: DRU. Some messages have both the prescribed and the
+	// dispensed drug, see Drug.Prescribed and Drug.Dispensed.
+	Drugs []*Drug
+}
+
+// creates a new RX
+func newRX() *RX {
+	return &RX{
+		RXH: newRXH(),
+	}
+}
+
+// fills in the values for RX from a PVD, PTT or DRU segment. Returns
+// false for any other segment.
+func (r *RX) fill(name string, values edifact.Values) bool {
+	switch name {
+	case "PVD":
+		provider := newProvider()
+		provider.fill(values)
+		switch provider.ProviderCode {
+		case "PC": // prescriber
+			r.Prescriber = provider
+		case "P2": // pharmacy
+			r.Pharmacy = provider
+		default:
+			r.Others = append(r.Others, provider)
+		}
+	case "PTT":
+		patient := newPatient()
+		patient.fill(values)
+		r.Patient = patient
+	case "DRU":
+		drug := newDrug()
+		drug.fill(values)
+		r.Drugs = append(r.Drugs, drug)
+	default:
+		return false
+	}
+
+	return true
+}
+
+// returns an error if the message of type msgType is missing
+// something it can't be sent without.
+func (r *RX) check(msgType string) error {
+	switch {
+	case r.Prescriber == nil:
+		return fmt.Errorf("ncpdp: %s has no prescriber", msgType)
+	case r.Patient == nil:
+		return fmt.Errorf("ncpdp: %s has no patient", msgType)
+	case len(r.Drugs) == 0:
+		return fmt.Errorf("ncpdp: %s has no drugs", msgType)
+	}
+
+	return nil
+}
+
+// returns the PVD, PTT and DRU segments. The pharmacy goes first in
+// the messages the pharmacy sends, and the other providers last.
+func (r *RX) segments(pharmacyFirst bool) []edifact.Values {
+	var providers []edifact.Values
+	if r.Prescriber != nil {
+		prescriber := *r.Prescriber
+		if prescriber.ProviderCode == "" {
+			prescriber.ProviderCode = "PC"
+		}
+		providers = append(providers, prescriber.values())
+	}
+	if r.Pharmacy != nil {
+		pharmacy := *r.Pharmacy
+		if pharmacy.ProviderCode == "" {
+			pharmacy.ProviderCode = "P2"
+		}
+		if pharmacyFirst {
+			providers = append([]edifact.Values{pharmacy.values()}, providers...)
+		} else {
+			providers = append(providers, pharmacy.values())
+		}
+	}
+	for _, other := range r.Others {
+		providers = append(providers, other.values())
+	}
+
+	segments := providers
+	if r.Patient != nil {
+		segments = append(segments, r.Patient.values())
+	}
+	for _, drug := range r.Drugs {
+		segments = append(segments, drug.values())
+	}
+	return segments
+}
+
+// Segment: REQ
+type Request struct {
+	// Values:
+	//   G = Generic substitution
+	//   T = Therapeutic interchange
+	//   P = Prior authorization
+	ChangeRequestType string // 010 M
+
+	// more fields are in this section, but unimplemented
+}
+
+// creates a new Request
+func newRequest() *Request {
+	return &Request{}
+}
+
+// fills in the values for Request
+func (r *Request) fill(values edifact.Values) {
+	r.ChangeRequestType = getString(values, 1)
+}
+
+// returns the REQ segment of the request
+func (r *Request) values() edifact.Values {
+	return edifact.Values{"REQ", r.ChangeRequestType}
+}
+
+// New prescription, sent by the prescriber to the pharmacy.
+type NEWRX struct {
+	*RX
+}
+
+// creates a new NEWRX
+func NewNEWRX() *NEWRX {
+	return &NEWRX{RX: newRX()}
+}
+
+func (r *NEWRX) messageType() string { return "NEWRX" }
+
+func (r *NEWRX) segments() ([]edifact.Values, error) {
+	if err := r.check("NEWRX"); err != nil {
+		return nil, err
+	}
+	return r.RX.segments(false), nil
+}
+
+// Refill request, sent by the pharmacy to the prescriber. Answered
+// with REFRES.
+type REFREQ struct {
+	*RX
+}
+
+// creates a new REFREQ
+func NewREFREQ() *REFREQ {
+	return &REFREQ{RX: newRX()}
+}
+
+func (r *REFREQ) messageType() string { return "REFREQ" }
+
+func (r *REFREQ) segments() ([]edifact.Values, error) {
+	if err := r.check("REFREQ"); err != nil {
+		return nil, err
+	}
+	return r.RX.segments(true), nil
+}
+
+// Refill response to REFREQ, sent by the prescriber to the pharmacy.
+type REFRES struct {
+	*RX
+	Response *Response
+}
+
+// creates a new REFRES
+func NewREFRES() *REFRES {
+	return &REFRES{RX: newRX(), Response: newResponse()}
+}
+
+func (r *REFRES) messageType() string { return "REFRES" }
+
+func (r *REFRES) segments() ([]edifact.Values, error) {
+	return responseSegments(r.RX, r.Response, "REFRES", true)
+}
+
+// Change request, sent by the pharmacy to the prescriber. Answered
+// with CHGRES.
+type RXCHG struct {
+	*RX
+	Request *Request
+}
+
+// creates a new RXCHG
+func NewRXCHG() *RXCHG {
+	return &RXCHG{RX: newRX(), Request: newRequest()}
+}
+
+func (r *RXCHG) messageType() string { return "RXCHG" }
+
+func (r *RXCHG) segments() ([]edifact.Values, error) {
+	if r.Request == nil {
+		return nil, errors.New("ncpdp: RXCHG has no request")
+	}
+	if err := r.check("RXCHG"); err != nil {
+		return nil, err
+	}
+	return append([]edifact.Values{r.Request.values()}, r.RX.segments(true)...), nil
+}
+
+// Change response to RXCHG, sent by the prescriber to the pharmacy.
+type CHGRES struct {
+	*RX
+	Response *Response
+}
+
+// creates a new CHGRES
+func NewCHGRES() *CHGRES {
+	return &CHGRES{RX: newRX(), Response: newResponse()}
+}
+
+func (r *CHGRES) messageType() string { return "CHGRES" }
+
+func (r *CHGRES) segments() ([]edifact.Values, error) {
+	return responseSegments(r.RX, r.Response, "CHGRES", true)
+}
+
+// Cancel prescription, sent by the prescriber to the pharmacy.
+// Answered with CANRES.
+type CANRX struct {
+	*RX
+}
+
+// creates a new CANRX
+func NewCANRX() *CANRX {
+	return &CANRX{RX: newRX()}
+}
+
+func (r *CANRX) messageType() string { return "CANRX" }
+
+func (r *CANRX) segments() ([]edifact.Values, error) {
+	if err := r.check("CANRX"); err != nil {
+		return nil, err
+	}
+	return r.RX.segments(false), nil
+}
+
+// Cancel response to CANRX, sent by the pharmacy to the prescriber.
+// Only the response is required.
+type CANRES struct {
+	*RX
+	Response *Response
+}
+
+// creates a new CANRES
+func NewCANRES() *CANRES {
+	return &CANRES{RX: newRX(), Response: newResponse()}
+}
+
+func (r *CANRES) messageType() string { return "CANRES" }
+
+func (r *CANRES) segments() ([]edifact.Values, error) {
+	return responseSegments(r.RX, r.Response, "CANRES", false)
+}
+
+// returns the RES segment followed by the segments of r. If check is
+// true, r must have everything a prescription has.
+func responseSegments(r *RX, response *Response, msgType string, check bool) ([]edifact.Values, error) {
+	if response == nil {
+		return nil, fmt.Errorf("ncpdp: %s has no response", msgType)
+	}
+	if check {
+		if err := r.check(msgType); err != nil {
+			return nil, err
+		}
+	}
+	return append([]edifact.Values{response.values()}, r.segments(false)...), nil
+}
+
+// unmarshals the values of a prescribing message of type msgType.
+func unmarshalRX(values edifact.Values, msgType string) (interface{}, error) {
+	rx := newRX()
+	var response **Response
+	var request **Request
+	var ret interface{}
+
+	switch msgType {
+	case "NEWRX":
+		ret = &NEWRX{RX: rx}
+	case "REFREQ":
+		ret = &REFREQ{RX: rx}
+	case "REFRES":
+		r := &REFRES{RX: rx}
+		response, ret = &r.Response, r
+	case "RXCHG":
+		r := &RXCHG{RX: rx}
+		request, ret = &r.Request, r
+	case "CHGRES":
+		r := &CHGRES{RX: rx}
+		response, ret = &r.Response, r
+	case "CANRX":
+		ret = &CANRX{RX: rx}
+	case "CANRES":
+		r := &CANRES{RX: rx}
+		response, ret = &r.Response, r
+	default:
+		return nil, fmt.Errorf("ncpdp: unknown message type %s", msgType)
+	}
+
+	for _, value := range values {
+		if vals, ok := value.(edifact.Values); ok {
+			if name, ok := vals[0].(string); ok {
+				if rx.fill(name, vals) || rx.RXH.fillEnvelope(name, vals) {
+					continue
+				}
+
+				switch name {
+				case "RES":
+					if response != nil {
+						*response = newResponse()
+						(*response).fill(vals)
+					}
+				case "REQ":
+					if request != nil {
+						*request = newRequest()
+						(*request).fill(vals)
+					}
+				}
+			}
+		}
+	}
+
+	return ret, nil
+}
@@ -0,0 +1,162 @@
+package ncpdp
+
+import (
+	"github.com/davecgh/go-spew/spew"
+	"testing"
+)
+
+func newTestRX() *RX {
+	rx := newRX()
+	rx.ControlReference = "hJIAmKH0FGDSt"
+	rx.Sender = Party{"Sender1", "ZZZ", "pass"}
+	rx.Recipient = Party{"Recepient1", "ZZZ", ""}
+	rx.Date = *timeParse("2013-01-13 12:16:25 +0000 UTC")
+
+	rx.Prescriber = &Provider{
+		ProviderCode:       "PC",
+		ReferenceNumber:    "BW7412396",
+		ReferenceQualifier: "DH",
+		Name:               &Name{Last: "Betterton", First: "Jill"},
+	}
+	rx.Pharmacy = &Provider{
+		ProviderCode:       "P2",
+		ReferenceNumber:    "1031232",
+		ReferenceQualifier: "D3",
+		PartyName:          "CVS PHARMACY",
+	}
+	rx.Patient = &Patient{
+		Relationship: "1",
+		Dob:          *timeParse("1985-06-30 00:00:00 +0000 UTC"),
+		Name:         &Name{Last: "Smith", First: "John"},
+		Gender:       "M",
+		Address:      &Address{Line1: "55596 SW 16TH ST", City: "ATLANTA", State: "GA", Postal: "12175"},
+		Phones:       []*Phone{{"3053872415", "TE"}, {"3053872416", "FX"}},
+	}
+	rx.Drugs = []*Drug{
+		{
+			ItemDescriptionIdentification: "P",
+			ItemDescription:               "XOLEGEL 2% GEL",
+			ItemNumber:                    "16110008045",
+			CodeListResponsibilityAgency:  "ND",
+			QuantityQualifier:             "ZZ",
+			Quantity:                      "45",
+			CodeListQualifier:             "38",
+			Dosage1:                       "APPLY TO AFFECTED AREA TWICE A DAY AS NEEDED",
+			DaysSupply:                    durationParse("720h0m0s"),
+			LastDemand:                    timeParse("2012-04-22 00:00:00 +0000 UTC"),
+			Substitution:                  "0",
+		},
+		{
+			ItemDescriptionIdentification: "D",
+			ItemDescription:               "XOLEGEL 2% GEL",
+			DateIssued:                    timeParse("2012-04-20 00:00:00 +0000 UTC"),
+		},
+	}
+	return rx
+}
+
+func newTestRXH() *RXH {
+	return newTestRX().RXH
+}
+
+// a message of every type, all of which can be marshaled
+func newTestMessages() []interface{} {
+	return []interface{}{
+		&NEWRX{RX: newTestRX()},
+		&REFREQ{RX: newTestRX()},
+		&REFRES{RX: newTestRX(), Response: &Response{ResponseType: "A", ReferenceNumber: "123"}},
+		&RXCHG{RX: newTestRX(), Request: &Request{ChangeRequestType: "G"}},
+		&CHGRES{RX: newTestRX(), Response: &Response{ResponseType: "D", Text: "NOT MY PATIENT"}},
+		&CANRX{RX: newTestRX()},
+		&CANRES{RX: &RX{RXH: newTestRXH()}, Response: &Response{ResponseType: "A"}},
+		&STATUS{RXH: newTestRXH(), Status: &Status{StatusTypeCode: "010"}},
+		&VERIFY{RXH: newTestRXH(), Status: &Status{StatusTypeCode: "010"}},
+		&ERROR{RXH: newTestRXH(), Status: &Status{StatusTypeCode: "900", CodeListQualifier: "007", Text: "Facility Not Found."}},
+	}
+}
+
+func TestMarshalRX(t *testing.T) {
+	for i, in := range newTestMessages() {
+		data, err := Marshal(in)
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+
+		out, err := Unmarshal(data)
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+
+		// only the segments that were unmarshaled differ
+		rxh := out.(message).rxh()
+		if rxh.UIB == nil || rxh.UIH == nil || rxh.UIT == nil || rxh.UIZ == nil {
+			t.Fatalf("%d. missing envelope segments in %s", i, data)
+		}
+		rxh.UIB, rxh.UIH, rxh.UIT, rxh.UIZ = nil, nil, nil, nil
+
+		have, want := spew.Sprintf("%#v", out), spew.Sprintf("%#v", in)
+		if have != want {
+			t.Fatalf("%d. mismatch\nhave: %s\nwant: %s", i, have, want)
+		}
+	}
+}
+
+const NEWRX_IN1 = `UNA:+./*'UIB+UNOA:0++ref+++Sender1:ZZZ+Recepient1:ZZZ+20130113:121625'UIH+SCRIPT:008:001:NEWRX+ref'PVD+PC+BW7412396:DH+++Betterton:Jill'PVD+P2+1031232:D3+++++CVS PHARMACY'PTT++19850630+Smith:John+M'DRU+P:XOLEGEL 2% GEL+ZZ:45:38+:APPLY TWICE A DAY+85:20120420:102*ZDS:30:804+0'UIT+ref+6'UIZ++1'`
+
+func TestUnmarshalNEWRX(t *testing.T) {
+	out, err := Unmarshal([]byte(NEWRX_IN1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newrx, ok := out.(*NEWRX)
+	if !ok {
+		t.Fatalf("expected a *NEWRX, got %T", out)
+	}
+
+	if newrx.Prescriber == nil || newrx.Prescriber.Name.Last != "Betterton" {
+		t.Fatalf("unexpected prescriber: %#+v", newrx.Prescriber)
+	}
+	if newrx.Pharmacy == nil || newrx.Pharmacy.PartyName != "CVS PHARMACY" {
+		t.Fatalf("unexpected pharmacy: %#+v", newrx.Pharmacy)
+	}
+	if newrx.Patient == nil || newrx.Patient.Name.First != "John" {
+		t.Fatalf("unexpected patient: %#+v", newrx.Patient)
+	}
+	if len(newrx.Drugs) != 1 || !newrx.Drugs[0].Prescribed() || newrx.Drugs[0].DaysSupply == nil || newrx.Drugs[0].DateIssued == nil {
+		t.Fatalf("unexpected drugs: %s", spew.Sdump(newrx.Drugs))
+	}
+}
+
+func TestMarshalRXOthers(t *testing.T) {
+	in := &NEWRX{RX: newTestRX()}
+	in.Others = []*Provider{{ProviderCode: "SU", ReferenceNumber: "AS1234567", ReferenceQualifier: "DH", Name: &Name{Last: "Adams", First: "Sam"}}}
+
+	data, err := Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, err := Unmarshal(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	have, want := spew.Sprintf("%#v", out.(*NEWRX).Others), spew.Sprintf("%#v", in.Others)
+	if have != want {
+		t.Fatalf("mismatch\nhave: %s\nwant: %s", have, want)
+	}
+}
+
+func TestMarshalRXErrors(t *testing.T) {
+	tests := []interface{}{
+		NewNEWRX(),
+		&REFREQ{RX: &RX{RXH: newRXH(), Prescriber: &Provider{}, Patient: &Patient{}}},
+		&REFRES{RX: newTestRX()},
+		&RXCHG{RX: newTestRX()},
+		&STATUS{RXH: newRXH()},
+	}
+	for i, tt := range tests {
+		if _, err := Marshal(tt); err == nil {
+			t.Fatalf("%d. expected an error", i)
+		}
+	}
+}
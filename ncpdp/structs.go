@@ -27,6 +27,37 @@ type Phone struct {
 	Qualifier string
 }
 
+// The sender or recipient of an interchange.
+type Party struct {
+	Id string // M
+
+	// Values:
+	//   ZZZ = Mutually defined
+	Qualifier string // C
+
+	// Level two identification, e.g. the password of a sender
+	// or the id of a sub-recipient.
+	SecondaryId string // C
+}
+
+func (p Party) values() edifact.Values {
+	return edifact.Values{p.Id, p.Qualifier, p.SecondaryId}
+}
+
+func (n *Name) values() interface{} {
+	if n == nil {
+		return ""
+	}
+	return edifact.Values{n.Last, n.First, n.Middle, n.Suffix, n.Prefix}
+}
+
+func (a *Address) values() interface{} {
+	if a == nil {
+		return ""
+	}
+	return edifact.Values{a.Line1, a.City, a.State, a.Postal, a.LocationQualifier, a.Location}
+}
+
 // segment: PTT
 type Patient struct {
 	Relationship string // 010 C
@@ -81,6 +112,30 @@ func (p *Patient) fill(values edifact.Values) {
 	p.Phones = getPhones(values, 7)
 }
 
+// returns the PTT segment of the patient
+func (p *Patient) values() edifact.Values {
+	dob := ""
+	if !p.Dob.IsZero() {
+		dob = p.Dob.Format("20060102")
+	}
+
+	phones := edifact.Values{}
+	for _, phone := range p.Phones {
+		phones = append(phones, edifact.Values{phone.Number, phone.Qualifier})
+	}
+
+	return trimValues(edifact.Values{
+		"PTT",
+		p.Relationship,
+		dob,
+		p.Name.values(),
+		p.Gender,
+		edifact.Values{p.ReferenceNumber, p.ReferenceQualifier},
+		p.Address.values(),
+		phones,
+	})
+}
+
 // creates a new patient
 func newPatient() *Patient {
 	return &Patient{}
@@ -128,6 +183,8 @@ func newProvider() *Provider {
 }
 
 func (p *Provider) fill(values edifact.Values) {
+	p.ProviderCode = getString(values, 1)
+
 	subValues := getValues(values, 2)
 	p.ReferenceNumber = getString(subValues, 0)
 	p.ReferenceQualifier = getString(subValues, 1)
@@ -137,6 +194,20 @@ func (p *Provider) fill(values edifact.Values) {
 	p.PartyName = getString(values, 7)
 }
 
+// returns the PVD segment of the provider
+func (p *Provider) values() edifact.Values {
+	return trimValues(edifact.Values{
+		"PVD",
+		p.ProviderCode,
+		edifact.Values{p.ReferenceNumber, p.ReferenceQualifier},
+		"",
+		"",
+		p.Name.values(),
+		"",
+		p.PartyName,
+	})
+}
+
 // // returns the value and qualifier of the provider id
 // func (p *Provider) ProviderID() *ProviderID {
 //   return &ProviderID{p.ReferenceNumber, p.ReferenceQualifier}
@@ -0,0 +1,732 @@
+package ncpdp
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// SCRIPT 10.6 is the XML version of the standard. Its messages decode
+// into the same types as 8.1 messages, so either can be converted into
+// the other. A few things 8.1 has don't exist in 10.6 and are lost in
+// the conversion: the COO segment, the quantity qualifier and dosage id
+// of drugs, and reference qualifiers that aren't in
+// xmlIdentifications, which become MutuallyDefined.
+
+const scriptNamespace = "http://www.ncpdp.org/schema/SCRIPT"
+
+// the body element of each message type
+var xmlMessageTypes = map[string]string{
+	"RXHREQ": "RxHistoryRequest",
+	"RXHRES": "RxHistoryResponse",
+	"NEWRX":  "NewRx",
+	"REFREQ": "RefillRequest",
+	"REFRES": "RefillResponse",
+	"RXCHG":  "RxChangeRequest",
+	"CHGRES": "RxChangeResponse",
+	"CANRX":  "CancelRx",
+	"CANRES": "CancelRxResponse",
+	"STATUS": "Status",
+	"VERIFY": "Verify",
+	"ERROR":  "Error",
+}
+
+// the identification elements of the reference qualifiers. The first
+// one with an element is the qualifier it decodes to.
+var xmlIdentifications = []struct {
+	qualifier string
+	name      string
+}{
+	{"HPI", "NPI"},
+	{"DH", "DEANumber"},
+	{"DE", "DEANumber"},
+	{"D3", "NCPDPID"},
+	{"0B", "StateLicenseNumber"},
+	{"SY", "SocialSecurity"},
+	{"ZZ", "MutuallyDefined"},
+}
+
+type xmlMessage struct {
+	XMLName xml.Name  `xml:"Message"`
+	Xmlns   string    `xml:"xmlns,attr,omitempty"`
+	Version string    `xml:"version,attr,omitempty"`
+	Release string    `xml:"release,attr,omitempty"`
+	Header  xmlHeader `xml:"Header"`
+	Body    xmlBody   `xml:"Body"`
+}
+
+type xmlHeader struct {
+	To        xmlParty
+	From      xmlParty
+	MessageID string
+	SentTime  string
+	Security  *xmlSecurity `xml:",omitempty"`
+}
+
+type xmlParty struct {
+	Qualifier string `xml:",attr,omitempty"`
+	Id        string `xml:",chardata"`
+}
+
+// where the secondary ids of the sender and recipient go
+type xmlSecurity struct {
+	Sender   *xmlSecondary `xml:",omitempty"`
+	Receiver *xmlSecondary `xml:",omitempty"`
+}
+
+type xmlSecondary struct {
+	SecondaryIdentification string
+}
+
+type xmlBody struct {
+	Content xmlContent `xml:",any"`
+}
+
+// the body element of any message type. Only the elements of its
+// message type are set.
+type xmlContent struct {
+	XMLName xml.Name
+
+	Request  *xmlRequest  `xml:",omitempty"`
+	Response *xmlResponse `xml:",omitempty"`
+
+	// Status and Error
+	Code            string `xml:",omitempty"`
+	DescriptionCode string `xml:",omitempty"`
+	Description     string `xml:",omitempty"`
+	// Verify
+	VerifyStatus *xmlStatus `xml:",omitempty"`
+
+	Pharmacy             *xmlProvider `xml:",omitempty"`
+	Prescriber           *xmlProvider `xml:",omitempty"`
+	Patient              *xmlPatient  `xml:",omitempty"`
+	MedicationPrescribed []*xmlDrug
+	MedicationDispensed  []*xmlDrug
+	MedicationRequested  []*xmlDrug
+}
+
+type xmlRequest struct {
+	ChangeRequestType string
+}
+
+type xmlResponse struct {
+	Approved                      *xmlResponseDetail `xml:",omitempty"`
+	Denied                        *xmlResponseDetail `xml:",omitempty"`
+	ApprovedWithChanges           *xmlResponseDetail `xml:",omitempty"`
+	DeniedNewPrescriptionToFollow *xmlResponseDetail `xml:",omitempty"`
+}
+
+// returns the element of the response type
+func (r *xmlResponse) detail(responseType string) **xmlResponseDetail {
+	switch responseType {
+	case "A":
+		return &r.Approved
+	case "D":
+		return &r.Denied
+	case "C":
+		return &r.ApprovedWithChanges
+	case "N":
+		return &r.DeniedNewPrescriptionToFollow
+	}
+	return nil
+}
+
+type xmlResponseDetail struct {
+	ReasonCode      string `xml:",omitempty"`
+	ReferenceNumber string `xml:",omitempty"`
+	Note            string `xml:",omitempty"`
+	DenialReason    string `xml:",omitempty"`
+}
+
+type xmlStatus struct {
+	Code            string
+	DescriptionCode string `xml:",omitempty"`
+	Description     string `xml:",omitempty"`
+}
+
+type xmlProvider struct {
+	Identification *xmlIdentification `xml:",omitempty"`
+	ClinicName     string             `xml:",omitempty"` // prescriber
+	StoreName      string             `xml:",omitempty"` // pharmacy
+	Name           *xmlName           `xml:",omitempty"`
+}
+
+// an element named after the qualifier of the id, e.g. NPI
+type xmlIdentification struct {
+	Ids []xmlId `xml:",any"`
+}
+
+type xmlId struct {
+	XMLName xml.Name
+	Value   string `xml:",chardata"`
+}
+
+type xmlName struct {
+	LastName   string
+	FirstName  string `xml:",omitempty"`
+	MiddleName string `xml:",omitempty"`
+	Suffix     string `xml:",omitempty"`
+	Prefix     string `xml:",omitempty"`
+}
+
+type xmlPatient struct {
+	PatientRelationship  string             `xml:",omitempty"`
+	Identification       *xmlIdentification `xml:",omitempty"`
+	Name                 *xmlName           `xml:",omitempty"`
+	Gender               string             `xml:",omitempty"`
+	DateOfBirth          *xmlDate           `xml:",omitempty"`
+	Address              *xmlAddress        `xml:",omitempty"`
+	CommunicationNumbers *xmlCommunications `xml:",omitempty"`
+}
+
+type xmlDate struct {
+	Date string
+}
+
+type xmlAddress struct {
+	AddressLine1           string `xml:",omitempty"`
+	AddressLine2           string `xml:",omitempty"`
+	City                   string `xml:",omitempty"`
+	State                  string `xml:",omitempty"`
+	ZipCode                string `xml:",omitempty"`
+	PlaceLocationQualifier string `xml:",omitempty"`
+}
+
+type xmlCommunications struct {
+	Communication []xmlCommunication
+}
+
+type xmlCommunication struct {
+	Number    string
+	Qualifier string `xml:",omitempty"`
+}
+
+type xmlDrug struct {
+	DrugDescription string        `xml:",omitempty"`
+	DrugCoded       *xmlDrugCoded `xml:",omitempty"`
+	Quantity        *xmlQuantity  `xml:",omitempty"`
+	DaysSupply      string        `xml:",omitempty"`
+	Directions      string        `xml:",omitempty"`
+	Note            string        `xml:",omitempty"`
+	Substitutions   string        `xml:",omitempty"`
+	WrittenDate     *xmlDate      `xml:",omitempty"`
+	LastFillDate    *xmlDate      `xml:",omitempty"`
+	Pharmacy        *xmlProvider  `xml:",omitempty"`
+	Prescriber      *xmlProvider  `xml:",omitempty"`
+}
+
+type xmlDrugCoded struct {
+	ProductCode          string `xml:",omitempty"`
+	ProductCodeQualifier string `xml:",omitempty"`
+}
+
+type xmlQuantity struct {
+	Value             string
+	CodeListQualifier string `xml:",omitempty"`
+}
+
+// marshals a message into SCRIPT 10.6 XML, the way Marshal does into
+// SCRIPT 8.1.
+func MarshalXML(msg interface{}) ([]byte, error) {
+	m, ok := msg.(message)
+	if !ok {
+		return nil, fmt.Errorf("ncpdp: cannot marshal %T", msg)
+	}
+	// the same message can't be sent as 8.1 either
+	if _, err := m.segments(); err != nil {
+		return nil, err
+	}
+
+	rxh := m.rxh()
+	if err := rxh.prepare(); err != nil {
+		return nil, err
+	}
+
+	x := &xmlMessage{
+		Xmlns:   scriptNamespace,
+		Version: "010",
+		Release: "006",
+		Header: xmlHeader{
+			To:        xmlParty{rxh.Recipient.Qualifier, rxh.Recipient.Id},
+			From:      xmlParty{rxh.Sender.Qualifier, rxh.Sender.Id},
+			MessageID: rxh.ControlReference,
+			SentTime:  rxh.Date.UTC().Format(time.RFC3339),
+		},
+	}
+	if rxh.Sender.SecondaryId != "" || rxh.Recipient.SecondaryId != "" {
+		x.Header.Security = &xmlSecurity{}
+		if rxh.Sender.SecondaryId != "" {
+			x.Header.Security.Sender = &xmlSecondary{rxh.Sender.SecondaryId}
+		}
+		if rxh.Recipient.SecondaryId != "" {
+			x.Header.Security.Receiver = &xmlSecondary{rxh.Recipient.SecondaryId}
+		}
+	}
+
+	c := &x.Body.Content
+	c.XMLName.Local = xmlMessageTypes[m.messageType()]
+
+	var err error
+	switch msg := msg.(type) {
+	case *RXHREQ:
+		c.Prescriber = xmlProviderOf(msg.RequestingPhysician, false)
+		c.Patient = xmlPatientOf(msg.Patient)
+	case *RXHRES:
+		c.Response, err = xmlResponseOf(msg.Response)
+		c.Prescriber = xmlProviderOf(msg.RequestingPhysician, false)
+		c.Patient = xmlPatientOf(msg.Patient)
+		c.setDrugs(msg.Drugs)
+	case *NEWRX:
+		c.setRX(msg.RX)
+	case *REFREQ:
+		c.setRX(msg.RX)
+	case *REFRES:
+		c.Response, err = xmlResponseOf(msg.Response)
+		c.setRX(msg.RX)
+	case *RXCHG:
+		c.Request = &xmlRequest{msg.Request.ChangeRequestType}
+		c.setRX(msg.RX)
+	case *CHGRES:
+		c.Response, err = xmlResponseOf(msg.Response)
+		c.setRX(msg.RX)
+	case *CANRX:
+		c.setRX(msg.RX)
+	case *CANRES:
+		c.Response, err = xmlResponseOf(msg.Response)
+		c.setRX(msg.RX)
+	case *STATUS:
+		c.setStatus(msg.Status)
+	case *VERIFY:
+		c.VerifyStatus = &xmlStatus{msg.Status.StatusTypeCode, msg.Status.CodeListQualifier, msg.Status.Text}
+	case *ERROR:
+		c.setStatus(msg.Status)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	out, err := xml.MarshalIndent(x, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	return append([]byte(xml.Header), out...), nil
+}
+
+func (c *xmlContent) setStatus(s *Status) {
+	c.Code, c.DescriptionCode, c.Description = s.StatusTypeCode, s.CodeListQualifier, s.Text
+}
+
+// sets the providers, patient and drugs of a prescribing message
+func (c *xmlContent) setRX(r *RX) {
+	c.Pharmacy = xmlProviderOf(r.Pharmacy, true)
+	c.Prescriber = xmlProviderOf(r.Prescriber, false)
+	c.Patient = xmlPatientOf(r.Patient)
+	c.setDrugs(r.Drugs)
+}
+
+// puts each drug in the element of its ItemDescriptionIdentification
+func (c *xmlContent) setDrugs(drugs []*Drug) {
+	for _, drug := range drugs {
+		x := xmlDrugOf(drug)
+		switch {
+		case drug.Prescribed():
+			c.MedicationPrescribed = append(c.MedicationPrescribed, x)
+		case drug.Requested():
+			c.MedicationRequested = append(c.MedicationRequested, x)
+		default:
+			c.MedicationDispensed = append(c.MedicationDispensed, x)
+		}
+	}
+}
+
+func xmlResponseOf(r *Response) (*xmlResponse, error) {
+	x := &xmlResponse{}
+	detail := x.detail(r.ResponseType)
+	if detail == nil {
+		return nil, fmt.Errorf("ncpdp: unknown response type %q", r.ResponseType)
+	}
+
+	*detail = &xmlResponseDetail{ReasonCode: r.CodeListQualifier, ReferenceNumber: r.ReferenceNumber}
+	if r.ResponseType == "D" || r.ResponseType == "N" {
+		(*detail).DenialReason = r.Text
+	} else {
+		(*detail).Note = r.Text
+	}
+	return x, nil
+}
+
+func xmlIdentificationOf(number, qualifier string) *xmlIdentification {
+	if number == "" {
+		return nil
+	}
+
+	name := "MutuallyDefined"
+	for _, id := range xmlIdentifications {
+		if id.qualifier == qualifier {
+			name = id.name
+			break
+		}
+	}
+	return &xmlIdentification{[]xmlId{{xml.Name{Local: name}, number}}}
+}
+
+func xmlNameOf(n *Name) *xmlName {
+	if n == nil {
+		return nil
+	}
+	return &xmlName{n.Last, n.First, n.Middle, n.Suffix, n.Prefix}
+}
+
+func xmlDateOf(t time.Time) *xmlDate {
+	if t.IsZero() {
+		return nil
+	}
+	return &xmlDate{t.Format("2006-01-02")}
+}
+
+func xmlProviderOf(p *Provider, pharmacy bool) *xmlProvider {
+	if p == nil {
+		return nil
+	}
+
+	x := &xmlProvider{
+		Identification: xmlIdentificationOf(p.ReferenceNumber, p.ReferenceQualifier),
+		Name:           xmlNameOf(p.Name),
+	}
+	if pharmacy {
+		x.StoreName = p.PartyName
+	} else {
+		x.ClinicName = p.PartyName
+	}
+	return x
+}
+
+func xmlPatientOf(p *Patient) *xmlPatient {
+	if p == nil {
+		return nil
+	}
+
+	x := &xmlPatient{
+		PatientRelationship: p.Relationship,
+		Identification:      xmlIdentificationOf(p.ReferenceNumber, p.ReferenceQualifier),
+		Name:                xmlNameOf(p.Name),
+		Gender:              p.Gender,
+		DateOfBirth:         xmlDateOf(p.Dob),
+	}
+	if a := p.Address; a != nil && *a != (Address{}) {
+		x.Address = &xmlAddress{a.Line1, a.Location, a.City, a.State, a.Postal, a.LocationQualifier}
+	}
+	if len(p.Phones) > 0 {
+		x.CommunicationNumbers = &xmlCommunications{}
+		for _, phone := range p.Phones {
+			x.CommunicationNumbers.Communication = append(x.CommunicationNumbers.Communication,
+				xmlCommunication{phone.Number, phone.Qualifier})
+		}
+	}
+	return x
+}
+
+func xmlDrugOf(d *Drug) *xmlDrug {
+	x := &xmlDrug{
+		DrugDescription: d.ItemDescription,
+		Directions:      d.Dosage1,
+		Note:            d.Dosage2,
+		Substitutions:   d.Substitution,
+		Pharmacy:        xmlProviderOf(d.Pharmacy, true),
+		Prescriber:      xmlProviderOf(d.Prescriber, false),
+	}
+	if d.ItemNumber != "" || d.CodeListResponsibilityAgency != "" {
+		x.DrugCoded = &xmlDrugCoded{d.ItemNumber, d.CodeListResponsibilityAgency}
+	}
+	if d.Quantity != "" || d.CodeListQualifier != "" {
+		x.Quantity = &xmlQuantity{d.Quantity, d.CodeListQualifier}
+	}
+	if d.DaysSupply != nil {
+		x.DaysSupply = strconv.FormatInt(int64(*d.DaysSupply/(time.Hour*24)), 10)
+	}
+	if d.DateIssued != nil {
+		x.WrittenDate = xmlDateOf(*d.DateIssued)
+	}
+	if d.LastDemand != nil {
+		x.LastFillDate = xmlDateOf(*d.LastDemand)
+	}
+	return x
+}
+
+// unmarshals SCRIPT 10.6 XML into the same types Unmarshal does for
+// SCRIPT 8.1.
+func UnmarshalXML(data []byte) (interface{}, error) {
+	x := &xmlMessage{}
+	if err := xml.Unmarshal(data, x); err != nil {
+		return nil, err
+	}
+
+	c := &x.Body.Content
+	msgType := ""
+	for typ, name := range xmlMessageTypes {
+		if name == c.XMLName.Local {
+			msgType = typ
+		}
+	}
+
+	var rxh *RXH
+	var ret interface{}
+	var err error
+
+	switch msgType {
+	case "RXHREQ":
+		r := newRXHREQ()
+		r.RequestingPhysician = c.Prescriber.provider("PC")
+		if c.Patient != nil {
+			r.Patient = c.Patient.patient()
+		}
+		rxh, ret = r.RXH, r
+	case "RXHRES":
+		r := newRXHRES()
+		if c.Response != nil {
+			r.Response, err = c.Response.response()
+		}
+		r.RequestingPhysician = c.Prescriber.provider("PC")
+		if c.Patient != nil {
+			r.Patient = c.Patient.patient()
+		}
+		r.Drugs = c.drugs()
+		rxh, ret = r.RXH, r
+	case "NEWRX":
+		r := &NEWRX{RX: c.rx()}
+		rxh, ret = r.RXH, r
+	case "REFREQ":
+		r := &REFREQ{RX: c.rx()}
+		rxh, ret = r.RXH, r
+	case "REFRES":
+		r := &REFRES{RX: c.rx()}
+		r.Response, err = c.Response.response()
+		rxh, ret = r.RXH, r
+	case "RXCHG":
+		r := &RXCHG{RX: c.rx()}
+		if c.Request != nil {
+			r.Request = &Request{ChangeRequestType: c.Request.ChangeRequestType}
+		}
+		rxh, ret = r.RXH, r
+	case "CHGRES":
+		r := &CHGRES{RX: c.rx()}
+		r.Response, err = c.Response.response()
+		rxh, ret = r.RXH, r
+	case "CANRX":
+		r := &CANRX{RX: c.rx()}
+		rxh, ret = r.RXH, r
+	case "CANRES":
+		r := &CANRES{RX: c.rx()}
+		r.Response, err = c.Response.response()
+		rxh, ret = r.RXH, r
+	case "STATUS":
+		r := NewSTATUS()
+		r.Status = &Status{c.Code, c.DescriptionCode, c.Description}
+		rxh, ret = r.RXH, r
+	case "VERIFY":
+		r := NewVERIFY()
+		if s := c.VerifyStatus; s != nil {
+			r.Status = &Status{s.Code, s.DescriptionCode, s.Description}
+		}
+		rxh, ret = r.RXH, r
+	case "ERROR":
+		r := NewERROR()
+		r.Status = &Status{c.Code, c.DescriptionCode, c.Description}
+		rxh, ret = r.RXH, r
+	default:
+		return nil, fmt.Errorf("ncpdp: unknown message %s", c.XMLName.Local)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	rxh.ControlReference = x.Header.MessageID
+	rxh.Sender = Party{Id: x.Header.From.Id, Qualifier: x.Header.From.Qualifier}
+	rxh.Recipient = Party{Id: x.Header.To.Id, Qualifier: x.Header.To.Qualifier}
+	if security := x.Header.Security; security != nil {
+		if security.Sender != nil {
+			rxh.Sender.SecondaryId = security.Sender.SecondaryIdentification
+		}
+		if security.Receiver != nil {
+			rxh.Recipient.SecondaryId = security.Receiver.SecondaryIdentification
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, x.Header.SentTime); err == nil {
+		rxh.Date = t.UTC()
+	}
+
+	return ret, nil
+}
+
+// returns the RX of a prescribing message
+func (c *xmlContent) rx() *RX {
+	r := newRX()
+	r.Pharmacy = c.Pharmacy.provider("P2")
+	r.Prescriber = c.Prescriber.provider("PC")
+	if c.Patient != nil {
+		r.Patient = c.Patient.patient()
+	}
+	r.Drugs = c.drugs()
+	return r
+}
+
+// returns the drugs of all the medication elements
+func (c *xmlContent) drugs() []*Drug {
+	var drugs []*Drug
+	for _, m := range []struct {
+		identification string
+		drugs          []*xmlDrug
+	}{
+		{"P", c.MedicationPrescribed},
+		{"D", c.MedicationDispensed},
+		{"R", c.MedicationRequested},
+	} {
+		for _, x := range m.drugs {
+			drugs = append(drugs, x.drug(m.identification))
+		}
+	}
+	return drugs
+}
+
+func (r *xmlResponse) response() (*Response, error) {
+	if r == nil {
+		return nil, nil
+	}
+
+	for _, responseType := range []string{"A", "D", "C", "N"} {
+		if detail := *r.detail(responseType); detail != nil {
+			text := detail.Note
+			if detail.DenialReason != "" {
+				text = detail.DenialReason
+			}
+			return &Response{
+				ResponseType:      responseType,
+				CodeListQualifier: detail.ReasonCode,
+				ReferenceNumber:   detail.ReferenceNumber,
+				Text:              text,
+			}, nil
+		}
+	}
+	return nil, fmt.Errorf("ncpdp: response without a response type")
+}
+
+// returns the number and qualifier of the first id
+func (x *xmlIdentification) reference() (number, qualifier string) {
+	if x == nil || len(x.Ids) == 0 {
+		return "", ""
+	}
+
+	id := x.Ids[0]
+	qualifier = "ZZ"
+	for _, i := range xmlIdentifications {
+		if i.name == id.XMLName.Local {
+			qualifier = i.qualifier
+			break
+		}
+	}
+	return id.Value, qualifier
+}
+
+func (x *xmlName) name() *Name {
+	if x == nil {
+		return nil
+	}
+	return &Name{x.LastName, x.FirstName, x.MiddleName, x.Suffix, x.Prefix}
+}
+
+func (x *xmlDate) time() *time.Time {
+	if x == nil {
+		return nil
+	}
+	t, err := time.Parse("2006-01-02", x.Date)
+	if err != nil {
+		return nil
+	}
+	return &t
+}
+
+func (x *xmlProvider) provider(code string) *Provider {
+	if x == nil {
+		return nil
+	}
+
+	p := newProvider()
+	p.ProviderCode = code
+	p.ReferenceNumber, p.ReferenceQualifier = x.Identification.reference()
+	p.Name = x.Name.name()
+	p.PartyName = x.ClinicName
+	if x.StoreName != "" {
+		p.PartyName = x.StoreName
+	}
+	return p
+}
+
+func (x *xmlPatient) patient() *Patient {
+	p := newPatient()
+	p.Relationship = x.PatientRelationship
+	p.ReferenceNumber, p.ReferenceQualifier = x.Identification.reference()
+	p.Name = x.Name.name()
+	p.Gender = x.Gender
+	if dob := x.DateOfBirth.time(); dob != nil {
+		p.Dob = *dob
+	}
+
+	// like getAddress, there's always an address
+	p.Address = &Address{}
+	if a := x.Address; a != nil {
+		p.Address = &Address{a.AddressLine1, a.City, a.State, a.ZipCode, a.PlaceLocationQualifier, a.AddressLine2}
+	}
+	if x.CommunicationNumbers != nil {
+		for _, c := range x.CommunicationNumbers.Communication {
+			p.Phones = append(p.Phones, &Phone{c.Number, c.Qualifier})
+		}
+	}
+	return p
+}
+
+func (x *xmlDrug) drug(identification string) *Drug {
+	d := newDrug()
+	d.ItemDescriptionIdentification = identification
+	d.ItemDescription = x.DrugDescription
+	if x.DrugCoded != nil {
+		d.ItemNumber, d.CodeListResponsibilityAgency = x.DrugCoded.ProductCode, x.DrugCoded.ProductCodeQualifier
+	}
+	if x.Quantity != nil {
+		// 10.6 has no quantity qualifier, so it's ZZ, mutually
+		// defined, like in every 8.1 message
+		d.QuantityQualifier = "ZZ"
+		d.Quantity, d.CodeListQualifier = x.Quantity.Value, x.Quantity.CodeListQualifier
+	}
+	d.Dosage1, d.Dosage2 = x.Directions, x.Note
+	if x.DaysSupply != "" {
+		if days, err := strconv.ParseInt(x.DaysSupply, 10, 64); err == nil {
+			duration := time.Duration(days) * time.Hour * 24
+			d.DaysSupply = &duration
+		}
+	}
+	d.DateIssued = x.WrittenDate.time()
+	d.LastDemand = x.LastFillDate.time()
+	d.Substitution = x.Substitutions
+	d.Pharmacy = x.Pharmacy.provider("P2")
+	d.Prescriber = x.Prescriber.provider("PC")
+	return d
+}
+
+// converts SCRIPT 8.1 EDIFACT into SCRIPT 10.6 XML
+func ConvertToXML(data []byte) ([]byte, error) {
+	msg, err := Unmarshal(data)
+	if err != nil {
+		return nil, err
+	}
+	return MarshalXML(msg)
+}
+
+// converts SCRIPT 10.6 XML into SCRIPT 8.1 EDIFACT
+func ConvertToEDIFACT(data []byte) ([]byte, error) {
+	msg, err := UnmarshalXML(data)
+	if err != nil {
+		return nil, err
+	}
+	return Marshal(msg)
+}
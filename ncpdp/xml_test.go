@@ -0,0 +1,138 @@
+package ncpdp
+
+import (
+	"github.com/davecgh/go-spew/spew"
+	"strings"
+	"testing"
+)
+
+func TestMarshalXML(t *testing.T) {
+	rxhreq := newTestRXHREQ()
+	rxhreq.ControlReference = "hJIAmKH0FGDSt"
+	rxhreq.Date = *timeParse("2013-01-13 12:16:25 +0000 UTC")
+
+	for i, in := range append(newTestMessages(), rxhreq) {
+		data, err := MarshalXML(in)
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+
+		out, err := UnmarshalXML(data)
+		if err != nil {
+			t.Fatalf("%d. received error: %s", i, err)
+		}
+
+		have, want := spew.Sprintf("%#v", out), spew.Sprintf("%#v", in)
+		if have != want {
+			t.Fatalf("%d. mismatch\nhave: %s\nwant: %s\nxml: %s", i, have, want, data)
+		}
+	}
+}
+
+const XML_IN1 = `<?xml version="1.0" encoding="UTF-8"?>
+<Message xmlns="http://www.ncpdp.org/schema/SCRIPT" version="010" release="006">
+  <Header>
+    <To Qualifier="P">7701630</To>
+    <From Qualifier="D">6666666</From>
+    <MessageID>1234567</MessageID>
+    <SentTime>2013-01-13T12:16:25.1Z</SentTime>
+  </Header>
+  <Body>
+    <NewRx>
+      <Pharmacy>
+        <Identification><NCPDPID>7701630</NCPDPID></Identification>
+        <StoreName>MAIN STREET PHARMACY</StoreName>
+      </Pharmacy>
+      <Prescriber>
+        <Identification><NPI>1234567890</NPI></Identification>
+        <ClinicName>FAMILY CLINIC</ClinicName>
+        <Name><LastName>Betterton</LastName><FirstName>Jill</FirstName></Name>
+      </Prescriber>
+      <Patient>
+        <Name><LastName>Smith</LastName><FirstName>John</FirstName></Name>
+        <Gender>M</Gender>
+        <DateOfBirth><Date>1985-06-30</Date></DateOfBirth>
+        <CommunicationNumbers>
+          <Communication><Number>3053872415</Number><Qualifier>TE</Qualifier></Communication>
+        </CommunicationNumbers>
+      </Patient>
+      <MedicationPrescribed>
+        <DrugDescription>XOLEGEL 2% GEL</DrugDescription>
+        <Quantity><Value>45</Value><CodeListQualifier>38</CodeListQualifier></Quantity>
+        <DaysSupply>30</DaysSupply>
+        <Directions>APPLY TWICE A DAY</Directions>
+        <Substitutions>0</Substitutions>
+        <WrittenDate><Date>2012-04-20</Date></WrittenDate>
+      </MedicationPrescribed>
+    </NewRx>
+  </Body>
+</Message>`
+
+const XML_OUT1 = `UNA:+./*'UIB+UNOA:0++1234567+++6666666:D+7701630:P+20130113:121625'UIH+SCRIPT:008:001:NEWRX+1234567'PVD+PC+1234567890:HPI+++Betterton:Jill++FAMILY CLINIC'PVD+P2+7701630:D3+++++MAIN STREET PHARMACY'PTT++19850630+Smith:John+M+++3053872415:TE'DRU+P:XOLEGEL 2% GEL+ZZ:45:38+:APPLY TWICE A DAY+85:20120420:102*ZDS:30:804+0'UIT+1234567+6'UIZ++1'`
+
+func TestConvertToEDIFACT(t *testing.T) {
+	out, err := ConvertToEDIFACT([]byte(XML_IN1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != XML_OUT1 {
+		t.Fatalf("mismatch\nhave: %s\nwant: %s", out, XML_OUT1)
+	}
+}
+
+func TestConvertToXML(t *testing.T) {
+	data, err := ConvertToXML([]byte(UM_IN1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{
+		`<RxHistoryResponse>`,
+		`<To Qualifier="ZZZ">Recepient1</To>`,
+		`<SecondaryIdentification>Recepient2</SecondaryIdentification>`,
+		`<SentTime>2013-01-13T12:16:25Z</SentTime>`,
+		`<DEANumber>BW7412396</DEANumber>`,
+	} {
+		if !strings.Contains(string(data), s) {
+			t.Fatalf("expected %s in\n%s", s, data)
+		}
+	}
+
+	// and back again, losing only what 10.6 doesn't have
+	msg, err := UnmarshalXML(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	out, ok := msg.(*RXHRES)
+	if !ok {
+		t.Fatalf("expected an *RXHRES, got %T", msg)
+	}
+
+	for i, pair := range [][2]interface{}{
+		{out.ControlReference, UM_OUT1.ControlReference},
+		{out.Sender, UM_OUT1.Sender},
+		{out.Recipient, UM_OUT1.Recipient},
+		{out.Date, UM_OUT1.Date},
+		{out.Response, UM_OUT1.Response},
+		{out.RequestingPhysician, UM_OUT1.RequestingPhysician},
+		{out.Patient, UM_OUT1.Patient},
+		{out.Drugs, UM_OUT1.Drugs},
+	} {
+		have, want := spew.Sprintf("%#v", pair[0]), spew.Sprintf("%#v", pair[1])
+		if have != want {
+			t.Fatalf("%d. mismatch\nhave: %s\nwant: %s", i, have, want)
+		}
+	}
+}
+
+func TestUnmarshalXMLErrors(t *testing.T) {
+	tests := []string{
+		`<Message><Body><Unknown></Unknown></Body></Message>`,
+		`<Message><Body><RefillResponse><Response></Response></RefillResponse></Body></Message>`,
+		`<Message`,
+	}
+	for i, tt := range tests {
+		if _, err := UnmarshalXML([]byte(tt)); err == nil {
+			t.Fatalf("%d. expected an error", i)
+		}
+	}
+}